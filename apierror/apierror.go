@@ -0,0 +1,196 @@
+// Package apierror is the error type every API handler reports, and the one
+// place where storage and database errors are mapped to HTTP statuses.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+)
+
+// FieldError describes one invalid input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is rendered as {"error": {...}}. Code is stable and meant for machines;
+// Message is for humans and may change.
+type Error struct {
+	Status    int          `json:"-"`
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	Details   interface{}  `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	// Err is the underlying cause; it is logged, never sent to the client.
+	Err error `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// New creates an error with an explicit status and code.
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Wrap attaches the cause.
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// WithDetails attaches extra structured data for the client.
+func (e *Error) WithDetails(d interface{}) *Error {
+	e.Details = d
+	return e
+}
+
+// WithField adds a field error.
+func (e *Error) WithField(field, message string) *Error {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+	return e
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, "bad_request", message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, "unauthorized", message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, "forbidden", message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, "not_found", message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, "conflict", message)
+}
+
+func TooManyRequests(message string) *Error {
+	return New(http.StatusTooManyRequests, "rate_limited", message)
+}
+
+func Unavailable(message string) *Error {
+	return New(http.StatusServiceUnavailable, "unavailable", message)
+}
+
+func Internal(err error) *Error {
+	return New(http.StatusInternalServerError, "internal", "internal server error").Wrap(err)
+}
+
+type mapping struct {
+	target  error
+	status  int
+	code    string
+	message string
+}
+
+var (
+	mu       sync.RWMutex
+	mappings []mapping
+)
+
+// Register maps a sentinel error (matched with errors.Is) to a status and code.
+// An empty message keeps the error's own text.
+func Register(target error, status int, code, message string) {
+	mu.Lock()
+	defer mu.Unlock()
+	mappings = append(mappings, mapping{target, status, code, message})
+}
+
+// From converts any error into an *Error. Unknown errors become a 500 whose
+// message does not leak the cause.
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	mu.RLock()
+	for _, m := range mappings {
+		if errors.Is(err, m.target) {
+			mu.RUnlock()
+			msg := m.message
+			if msg == "" {
+				msg = err.Error()
+			}
+			return New(m.status, m.code, msg).Wrap(err)
+		}
+	}
+	mu.RUnlock()
+
+	var ve validator.ValidationErrors
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound("resource not found").Wrap(err)
+	case IsDuplicateKey(err):
+		return Conflict("resource already exists").Wrap(err)
+	case IsMissingObject(err):
+		return New(http.StatusNotFound, "object_not_found", "object not found in storage").Wrap(err)
+	case errors.As(err, &ve):
+		out := New(http.StatusBadRequest, "validation_failed", "invalid payload").Wrap(err)
+		for _, fe := range ve {
+			out.WithField(strings.ToLower(fe.Field()), "failed on '"+fe.Tag()+"'")
+		}
+		return out
+	}
+	return Internal(err)
+}
+
+// IsDuplicateKey matches unique-constraint violations. The database is opened
+// with TranslateError, so each dialector turns its driver's code (MySQL 1062,
+// Postgres 23505, SQLite's unique and primary key constraints) into
+// gorm.ErrDuplicatedKey; messages are never inspected.
+func IsDuplicateKey(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// IsMissingObject reports a MinIO/S3 "no such key" error.
+func IsMissingObject(err error) bool {
+	if err == nil {
+		return false
+	}
+	code := minio.ToErrorResponse(err).Code
+	return code == "NoSuchKey" || code == "NoSuchObject"
+}
+
+// Lookup converts the error of a single-record lookup: "record not found"
+// becomes a 404 with message, anything else goes through From.
+func Lookup(err error, message string) *Error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NotFound(message).Wrap(err)
+	}
+	return From(err)
+}
+
+// Binding converts a request binding error into a 400, listing the failing
+// fields when the payload failed validation.
+func Binding(err error) *Error {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		return From(err)
+	}
+	return New(http.StatusBadRequest, "invalid_payload", "invalid payload: "+err.Error()).Wrap(err)
+}
@@ -0,0 +1,30 @@
+package apierror
+
+import (
+	"net/http"
+
+	"open-illustrations-go/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDKey is the gin context key holding the request ID.
+const RequestIDKey = "request_id"
+
+// Abort records err on the context and stops the handler chain; the
+// middleware renders it once the handlers return.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Write renders err immediately as {"error": {...}}. Server errors are logged,
+// except 503s, which report load shedding rather than a fault.
+func Write(c *gin.Context, err error) {
+	e := From(err)
+	e.RequestID = c.GetString(RequestIDKey)
+	if e.Status >= 500 && e.Status != http.StatusServiceUnavailable {
+		logging.FromContext(c.Request.Context()).Error("request failed", "status", e.Status, "error", e)
+	}
+	c.AbortWithStatusJSON(e.Status, gin.H{"error": e})
+}
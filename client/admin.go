@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Admin endpoints need WithAPIKey.
+
+func (c *Client) ListSigningKeys(ctx context.Context) ([]SigningKey, error) {
+	var out []SigningKey
+	err := c.do(ctx, http.MethodGet, "/api/v1/admin/signing-keys", nil, nil, &out)
+	return out, err
+}
+
+// RotateSigningKey activates a new signing key; the previous one keeps
+// verifying tokens for the server's retention period.
+func (c *Client) RotateSigningKey(ctx context.Context) (*SigningKey, error) {
+	var out SigningKey
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/signing-keys/rotate", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) ListRevocations(ctx context.Context) ([]Revocation, error) {
+	var out []Revocation
+	err := c.do(ctx, http.MethodGet, "/api/v1/admin/revocations", nil, nil, &out)
+	return out, err
+}
+
+func (c *Client) Revoke(ctx context.Context, in RevokeRequest) (*Revocation, error) {
+	var out Revocation
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/revocations", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) DeleteRevocation(ctx context.Context, revocationID uint) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/admin/revocations/"+id(revocationID), nil, nil, nil)
+}
+
+// License returns the license summary.
+func (c *Client) License(ctx context.Context) (string, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/v1/info/license"})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		License string `json:"license"`
+	}
+	err = decodeJSON(resp.Body, &out)
+	return out.License, err
+}
+
+// About returns the service description.
+func (c *Client) About(ctx context.Context) (string, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/v1/info/about"})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		About string `json:"about"`
+	}
+	err = decodeJSON(resp.Body, &out)
+	return out.About, err
+}
@@ -0,0 +1,282 @@
+// Package client is a Go client for the Open Illustrations API.
+//
+//	c := client.New("https://illustrations.example.com", client.WithAPIKey(os.Getenv("ADMIN_API_TOKEN")))
+//	for ill, err := range c.Illustrations(ctx, nil) {
+//		...
+//	}
+//
+// Every method takes a context; cancelling it aborts the request and any
+// pending retry. Idempotent requests are retried on network errors, 429 and
+// 5xx responses, honouring Retry-After.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client talks to one API server. It is safe for concurrent use.
+type Client struct {
+	baseURL        string
+	http           *http.Client
+	apiKey         string
+	internalSecret string
+	userAgent      string
+	maxRetries     int
+	backoff        time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey sends "Authorization: Bearer <key>": ADMIN_API_TOKEN, or a client
+// key from API_KEYS on the server.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithInternalSecret sends X-Internal-Request, unlocking the internal audience
+// (storage keys, presigned URLs). Must match INTERNAL_PRESIGN_SECRET.
+func WithInternalSecret(secret string) Option {
+	return func(c *Client) { c.internalSecret = secret }
+}
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) { c.http = h }
+}
+
+// WithRetries sets how many times a failed idempotent request is retried and
+// the initial backoff, which doubles on every attempt. Defaults: 3, 200ms.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) { c.maxRetries, c.backoff = n, backoff }
+}
+
+// WithUserAgent sets the User-Agent header.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a client for the server at baseURL (scheme and host, without /api/v1).
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		http:       http.DefaultClient,
+		userAgent:  "open-illustrations-go-client",
+		maxRetries: 3,
+		backoff:    200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error is an error response from the API.
+type Error struct {
+	Status    int             `json:"-"`
+	Code      string          `json:"code"`
+	Message   string          `json:"message"`
+	Fields    []FieldError    `json:"fields,omitempty"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+// FieldError is one failed validation rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
+}
+
+// request describes one call. body is either a []byte (replayed on retry) or
+// an io.Reader, which disables retries.
+type request struct {
+	method  string
+	path    string
+	query   url.Values
+	header  http.Header
+	body    interface{}
+	ctype   string
+	noRetry bool
+}
+
+func (c *Client) url(path string, q url.Values) string {
+	u := c.baseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
+
+// send performs r and returns the response for any 2xx or 3xx status; other
+// statuses are decoded into *Error. The caller closes the body.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	retries := c.maxRetries
+	if r.noRetry || !idempotent(r.method) {
+		retries = 0
+	}
+	if _, streaming := r.body.(io.Reader); streaming {
+		retries = 0
+	}
+	wait := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.sendOnce(ctx, r)
+		if err == nil && resp.StatusCode < 400 {
+			return resp, nil
+		}
+		retryable := err != nil && ctx.Err() == nil
+		if err == nil {
+			retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+			if d := retryAfter(resp); d > 0 {
+				wait = d
+			}
+			if !retryable || attempt >= retries {
+				return nil, decodeError(resp)
+			}
+			resp.Body.Close()
+		}
+		if !retryable || attempt >= retries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) sendOnce(ctx context.Context, r request) (*http.Response, error) {
+	var body io.Reader
+	switch b := r.body.(type) {
+	case nil:
+	case []byte:
+		body = bytes.NewReader(b)
+	case io.Reader:
+		body = b
+	default:
+		return nil, fmt.Errorf("client: unsupported body %T", r.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, c.url(r.path, r.query), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if r.ctype != "" {
+		req.Header.Set("Content-Type", r.ctype)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if c.internalSecret != "" {
+		req.Header.Set("X-Internal-Request", c.internalSecret)
+	}
+	return c.http.Do(req)
+}
+
+// do sends r with an optional JSON body and decodes the "data" member of the
+// response into out (when out is non-nil).
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, in, out interface{}) error {
+	r := request{method: method, path: path, query: q}
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		r.body, r.ctype = b, "application/json"
+	}
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeData(resp.Body, out)
+}
+
+func decodeData(r io.Reader, out interface{}) error {
+	if out == nil {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}
+	var env struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return fmt.Errorf("client: decode response: %w", err)
+	}
+	return json.Unmarshal(env.Data, out)
+}
+
+func decodeError(resp *http.Response) error {
+	defer resp.Body.Close()
+	e := &Error{Status: resp.StatusCode}
+	var env struct {
+		Error *Error `json:"error"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(b, &env) == nil && env.Error != nil {
+		env.Error.Status = resp.StatusCode
+		return env.Error
+	}
+	e.Code = strings.ToLower(strings.ReplaceAll(http.StatusText(resp.StatusCode), " ", "_"))
+	e.Message = strings.TrimSpace(string(b))
+	return e
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return time.Duration(n) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func id(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
+
+// decodeJSON decodes a response that is not wrapped in {"data": ...}.
+func decodeJSON(r io.Reader, out interface{}) error {
+	if err := json.NewDecoder(r).Decode(out); err != nil {
+		return fmt.Errorf("client: decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ListOptions shapes list and detail responses.
+type ListOptions struct {
+	// Fields keeps only these JSON fields (?fields=).
+	Fields []string
+	// Include embeds relations (?include=): category, pack, style for
+	// illustrations, illustrations for taxonomies.
+	Include []string
+	// Presign asks for presigned storage URLs; needs WithInternalSecret.
+	Presign bool
+	// Limit > 0 returns one page of at most Limit items (1-500), ordered by
+	// id, after skipping Offset. The iterators use Limit as their page size.
+	Limit  int
+	Offset int
+}
+
+func (o *ListOptions) query() url.Values {
+	q := url.Values{}
+	if o == nil {
+		return q
+	}
+	if len(o.Fields) > 0 {
+		q.Set("fields", strings.Join(o.Fields, ","))
+	}
+	if len(o.Include) > 0 {
+		q.Set("include", strings.Join(o.Include, ","))
+	}
+	if o.Presign {
+		q.Set("include_presign", "1")
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+		if o.Offset > 0 {
+			q.Set("offset", strconv.Itoa(o.Offset))
+		}
+	}
+	return q
+}
+
+// defaultPageSize is the page size of the iterators when none is given.
+const defaultPageSize = 100
+
+// pages adapts a paginated list call to an iterator. It requests limit items
+// at a time, starting at offset, and stops after the first short page.
+func pages[T any](limit, offset int, list func(offset int) ([]T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			items, err := list(offset)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(items) < limit {
+				return
+			}
+			offset += len(items)
+		}
+	}
+}
+
+// eachPage iterates over a list endpoint with opts, using opts.Limit as the
+// page size (defaultPageSize when unset) and starting at opts.Offset.
+func eachPage[T any](opts *ListOptions, list func(*ListOptions) ([]T, error)) iter.Seq2[T, error] {
+	p := ListOptions{}
+	if opts != nil {
+		p = *opts
+	}
+	if p.Limit <= 0 {
+		p.Limit = defaultPageSize
+	}
+	return pages(p.Limit, p.Offset, func(offset int) ([]T, error) {
+		page := p
+		page.Offset = offset
+		return list(&page)
+	})
+}
+
+// ListIllustrations returns every illustration, or one page when opts.Limit is set.
+func (c *Client) ListIllustrations(ctx context.Context, opts *ListOptions) ([]Illustration, error) {
+	var out []Illustration
+	err := c.do(ctx, http.MethodGet, "/api/v1/illustrations", opts.query(), nil, &out)
+	return out, err
+}
+
+// Illustrations iterates over every illustration, fetching a page at a time.
+// Iteration stops at the first error.
+func (c *Client) Illustrations(ctx context.Context, opts *ListOptions) iter.Seq2[Illustration, error] {
+	return eachPage(opts, func(o *ListOptions) ([]Illustration, error) { return c.ListIllustrations(ctx, o) })
+}
+
+// GetIllustration fetches one illustration.
+func (c *Client) GetIllustration(ctx context.Context, illustrationID uint, opts *ListOptions) (*Illustration, error) {
+	var out Illustration
+	if err := c.do(ctx, http.MethodGet, "/api/v1/illustrations/"+id(illustrationID), opts.query(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateIllustration registers an object that is already in the bucket.
+func (c *Client) CreateIllustration(ctx context.Context, in NewIllustration) (*Illustration, error) {
+	var out Illustration
+	if err := c.do(ctx, http.MethodPost, "/api/v1/illustrations", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadInput describes an SVG upload. FileName must end in .svg.
+type UploadInput struct {
+	Title      string
+	FileName   string
+	CategoryID *uint
+	StyleID    *uint
+	PackID     *uint
+}
+
+// UploadIllustration uploads an SVG in one multipart request, streaming r.
+// Uploads are not retried; use UploadResumable for large or flaky transfers.
+func (c *Client) UploadIllustration(ctx context.Context, in UploadInput, r io.Reader) (*Illustration, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		err := writeUploadForm(mw, in, r)
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	resp, err := c.send(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/v1/illustrations",
+		body:   pr,
+		ctype:  mw.FormDataContentType(),
+	})
+	pr.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out Illustration
+	if err := decodeData(resp.Body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func writeUploadForm(mw *multipart.Writer, in UploadInput, r io.Reader) error {
+	fields := map[string]string{"title": in.Title, "file_name": in.FileName}
+	for name, v := range map[string]*uint{"category_id": in.CategoryID, "style_id": in.StyleID, "pack_id": in.PackID} {
+		if v != nil {
+			fields[name] = id(*v)
+		}
+	}
+	for name, v := range fields {
+		if err := mw.WriteField(name, v); err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile("file", in.FileName)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, r)
+	return err
+}
+
+// DeleteIllustration soft-deletes an illustration.
+func (c *Client) DeleteIllustration(ctx context.Context, illustrationID uint) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/illustrations/"+id(illustrationID), nil, nil, nil)
+}
+
+// FileURLOptions narrows the signed URL returned by FileURL.
+type FileURLOptions struct {
+	// PNGSize > 0 limits the token to the PNG rendition at that width.
+	PNGSize    int
+	Attachment bool
+	BindIP     bool
+	// BindUser limits the token to this client's API key (see WithAPIKey).
+	BindUser  bool
+	SingleUse bool
+}
+
+// FileURL returns a presigned storage URL and a signed proxy URL for an illustration.
+func (c *Client) FileURL(ctx context.Context, illustrationID uint, opts FileURLOptions) (*FileURL, error) {
+	q := url.Values{}
+	if opts.PNGSize > 0 {
+		q.Set("variant", "png")
+		q.Set("size", strconv.Itoa(opts.PNGSize))
+	}
+	if opts.Attachment {
+		q.Set("disposition", "attachment")
+	}
+	if opts.BindIP {
+		q.Set("bind_ip", "1")
+	}
+	if opts.BindUser {
+		q.Set("bind_user", "1")
+	}
+	if opts.SingleUse {
+		q.Set("single_use", "1")
+	}
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/v1/illustrations/" + id(illustrationID) + "/file", query: q})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out FileURL
+	// this endpoint is not wrapped in {"data": ...}
+	if err := decodeJSON(resp.Body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DownloadURL returns a presigned URL valid for one hour.
+func (c *Client) DownloadURL(ctx context.Context, illustrationID uint) (string, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/v1/illustrations/" + id(illustrationID) + "/download"})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		DownloadURL string `json:"download_url"`
+	}
+	err = decodeJSON(resp.Body, &out)
+	return out.DownloadURL, err
+}
+
+// StreamOptions selects the rendition streamed by StreamPublic.
+type StreamOptions struct {
+	// PNGSize > 0 streams a PNG of that width instead of the SVG.
+	PNGSize int
+}
+
+// StreamPublic copies a free illustration to w and returns its content type.
+func (c *Client) StreamPublic(ctx context.Context, illustrationID uint, w io.Writer, opts StreamOptions) (string, error) {
+	q := url.Values{}
+	if opts.PNGSize > 0 {
+		q.Set("format", "png")
+		q.Set("size", strconv.Itoa(opts.PNGSize))
+	}
+	return c.stream(ctx, request{method: http.MethodGet, path: "/api/v1/illustrations/" + id(illustrationID) + "/public", query: q}, w)
+}
+
+// StreamSigned copies the asset behind a signed URL (as returned in image_url,
+// download_url or FileURL.SignedURL) to w and returns its content type.
+func (c *Client) StreamSigned(ctx context.Context, signedURL string, w io.Writer) (string, error) {
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(u.Path, "/api/v1/i/") {
+		return "", fmt.Errorf("client: %q is not a signed asset URL", signedURL)
+	}
+	// single-use tokens must not be replayed
+	return c.stream(ctx, request{method: http.MethodGet, path: u.Path, query: u.Query(), noRetry: true}, w)
+}
+
+func (c *Client) stream(ctx context.Context, r request, w io.Writer) (string, error) {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return "", err
+	}
+	return resp.Header.Get("Content-Type"), nil
+}
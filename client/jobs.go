@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ImportInput holds the defaults applied to files the manifest doesn't describe.
+type ImportInput struct {
+	Category  string
+	Style     string
+	Pack      string
+	IsPremium bool
+	// ManifestName (.json or .csv) and Manifest are optional; a manifest at
+	// the root of the ZIP is used otherwise.
+	ManifestName string
+	Manifest     io.Reader
+}
+
+// CreateImport uploads a ZIP of SVGs for background import and returns the
+// queued import; poll GetImport for per-file results.
+func (c *Client) CreateImport(ctx context.Context, archiveName string, archive io.Reader, in ImportInput) (*ImportJob, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		err := writeImportForm(mw, archiveName, archive, in)
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	resp, err := c.send(ctx, request{method: http.MethodPost, path: "/api/v1/imports", body: pr, ctype: mw.FormDataContentType()})
+	pr.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out ImportJob
+	if err := decodeData(resp.Body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func writeImportForm(mw *multipart.Writer, archiveName string, archive io.Reader, in ImportInput) error {
+	for name, v := range map[string]string{"category": in.Category, "style": in.Style, "pack": in.Pack, "is_premium": strconv.FormatBool(in.IsPremium)} {
+		if err := mw.WriteField(name, v); err != nil {
+			return err
+		}
+	}
+	if in.Manifest != nil {
+		part, err := mw.CreateFormFile("manifest", in.ManifestName)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, in.Manifest); err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile("file", archiveName)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, archive)
+	return err
+}
+
+// GetImport returns an import with its per-file results.
+func (c *Client) GetImport(ctx context.Context, importID uint) (*ImportJob, error) {
+	var out ImportJob
+	if err := c.do(ctx, http.MethodGet, "/api/v1/imports/"+id(importID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// JobFilter narrows ListJobs; Limit is 1-500 (default 50). Jobs are listed
+// newest first and Offset skips that many of them. The job endpoints are admin
+// endpoints and need WithAPIKey with the admin token.
+type JobFilter struct {
+	Type   string
+	Status string
+	Limit  int
+	Offset int
+}
+
+func (c *Client) ListJobs(ctx context.Context, f JobFilter) ([]Job, error) {
+	q := url.Values{}
+	if f.Type != "" {
+		q.Set("type", f.Type)
+	}
+	if f.Status != "" {
+		q.Set("status", f.Status)
+	}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if f.Offset > 0 {
+		q.Set("offset", strconv.Itoa(f.Offset))
+	}
+	var out []Job
+	err := c.do(ctx, http.MethodGet, "/api/v1/admin/jobs", q, nil, &out)
+	return out, err
+}
+
+// Jobs iterates over every matching job, f.Limit at a time. Jobs enqueued
+// while iterating shift the pages, so a job may be seen twice.
+func (c *Client) Jobs(ctx context.Context, f JobFilter) iter.Seq2[Job, error] {
+	if f.Limit <= 0 {
+		f.Limit = defaultPageSize
+	}
+	return pages(f.Limit, f.Offset, func(offset int) ([]Job, error) {
+		f.Offset = offset
+		return c.ListJobs(ctx, f)
+	})
+}
+
+func (c *Client) GetJob(ctx context.Context, jobID uint) (*Job, error) {
+	var out Job
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/jobs/"+id(jobID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RetryJob requeues a failed job.
+func (c *Client) RetryJob(ctx context.Context, jobID uint) (*Job, error) {
+	var out Job
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/jobs/"+id(jobID)+"/retry", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PackDownloadOptions selects the archive variant; zero values use the server defaults.
+type PackDownloadOptions struct {
+	Format string // svg, png or both
+	Size   int    // PNG width, 16-1024
+	IDs    []uint // subset of the pack, at most 50
+	Layout string // flat, category or style
+}
+
+func (o PackDownloadOptions) query() url.Values {
+	q := url.Values{}
+	if o.Format != "" {
+		q.Set("format", o.Format)
+	}
+	if o.Size > 0 {
+		q.Set("size", strconv.Itoa(o.Size))
+	}
+	if len(o.IDs) > 0 {
+		ids := make([]string, len(o.IDs))
+		for i, n := range o.IDs {
+			ids[i] = id(n)
+		}
+		q.Set("ids", strings.Join(ids, ","))
+	}
+	if o.Layout != "" {
+		q.Set("layout", o.Layout)
+	}
+	return q
+}
+
+// DownloadPack writes the ZIP archive of a pack to w. While the server is still
+// building the archive (202) it waits for Retry-After and asks again, until
+// ctx is done.
+func (c *Client) DownloadPack(ctx context.Context, packID uint, opts PackDownloadOptions, w io.Writer) error {
+	r := request{method: http.MethodGet, path: "/api/v1/packs/" + id(packID) + "/download", query: opts.query()}
+	for {
+		resp, err := c.send(ctx, r)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusAccepted {
+			_, err = io.Copy(w, resp.Body)
+			resp.Body.Close()
+			return err
+		}
+		wait := retryAfter(resp)
+		resp.Body.Close()
+		if wait <= 0 {
+			wait = 5 * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+)
+
+// The server's category and pack routes have no rename: PUT soft-deletes
+// them. Styles support PUT as a rename and DELETE as a soft delete.
+
+type named struct {
+	Name string `json:"name"`
+}
+
+func (c *Client) createTaxonomy(ctx context.Context, path, name string) (*Taxonomy, error) {
+	var out Taxonomy
+	if err := c.do(ctx, http.MethodPost, path, nil, named{name}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) listTaxonomy(ctx context.Context, path string, opts *ListOptions) ([]Taxonomy, error) {
+	var out []Taxonomy
+	err := c.do(ctx, http.MethodGet, path, opts.query(), nil, &out)
+	return out, err
+}
+
+func (c *Client) getTaxonomy(ctx context.Context, path string, opts *ListOptions) (*Taxonomy, error) {
+	var out Taxonomy
+	if err := c.do(ctx, http.MethodGet, path, opts.query(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) listIllustrationsOf(ctx context.Context, path string, opts *ListOptions) ([]Illustration, error) {
+	var out []Illustration
+	err := c.do(ctx, http.MethodGet, path+"/illustrations", opts.query(), nil, &out)
+	return out, err
+}
+
+// ---- Categories ----
+
+func (c *Client) CreateCategory(ctx context.Context, name string) (*Taxonomy, error) {
+	return c.createTaxonomy(ctx, "/api/v1/category", name)
+}
+
+func (c *Client) ListCategories(ctx context.Context, opts *ListOptions) ([]Taxonomy, error) {
+	return c.listTaxonomy(ctx, "/api/v1/categories", opts)
+}
+
+func (c *Client) Categories(ctx context.Context, opts *ListOptions) iter.Seq2[Taxonomy, error] {
+	return eachPage(opts, func(o *ListOptions) ([]Taxonomy, error) { return c.ListCategories(ctx, o) })
+}
+
+func (c *Client) GetCategory(ctx context.Context, categoryID uint, opts *ListOptions) (*Taxonomy, error) {
+	return c.getTaxonomy(ctx, "/api/v1/categories/"+id(categoryID), opts)
+}
+
+func (c *Client) CategoryIllustrations(ctx context.Context, categoryID uint, opts *ListOptions) iter.Seq2[Illustration, error] {
+	return eachPage(opts, func(o *ListOptions) ([]Illustration, error) {
+		return c.listIllustrationsOf(ctx, "/api/v1/categories/"+id(categoryID), o)
+	})
+}
+
+func (c *Client) DeleteCategory(ctx context.Context, categoryID uint) error {
+	return c.do(ctx, http.MethodPut, "/api/v1/categories/"+id(categoryID), nil, nil, nil)
+}
+
+// ---- Packs ----
+
+func (c *Client) CreatePack(ctx context.Context, name string) (*Taxonomy, error) {
+	return c.createTaxonomy(ctx, "/api/v1/pack", name)
+}
+
+func (c *Client) ListPacks(ctx context.Context, opts *ListOptions) ([]Taxonomy, error) {
+	return c.listTaxonomy(ctx, "/api/v1/packs", opts)
+}
+
+func (c *Client) Packs(ctx context.Context, opts *ListOptions) iter.Seq2[Taxonomy, error] {
+	return eachPage(opts, func(o *ListOptions) ([]Taxonomy, error) { return c.ListPacks(ctx, o) })
+}
+
+func (c *Client) GetPack(ctx context.Context, packID uint, opts *ListOptions) (*Taxonomy, error) {
+	return c.getTaxonomy(ctx, "/api/v1/packs/"+id(packID), opts)
+}
+
+func (c *Client) PackIllustrations(ctx context.Context, packID uint, opts *ListOptions) iter.Seq2[Illustration, error] {
+	return eachPage(opts, func(o *ListOptions) ([]Illustration, error) {
+		return c.listIllustrationsOf(ctx, "/api/v1/packs/"+id(packID), o)
+	})
+}
+
+func (c *Client) DeletePack(ctx context.Context, packID uint) error {
+	return c.do(ctx, http.MethodPut, "/api/v1/packs/"+id(packID), nil, nil, nil)
+}
+
+// ---- Styles ----
+
+func (c *Client) CreateStyle(ctx context.Context, name string) (*Taxonomy, error) {
+	return c.createTaxonomy(ctx, "/api/v1/styles", name)
+}
+
+func (c *Client) ListStyles(ctx context.Context, opts *ListOptions) ([]Taxonomy, error) {
+	return c.listTaxonomy(ctx, "/api/v1/styles", opts)
+}
+
+func (c *Client) Styles(ctx context.Context, opts *ListOptions) iter.Seq2[Taxonomy, error] {
+	return eachPage(opts, func(o *ListOptions) ([]Taxonomy, error) { return c.ListStyles(ctx, o) })
+}
+
+func (c *Client) GetStyle(ctx context.Context, styleID uint, opts *ListOptions) (*Taxonomy, error) {
+	return c.getTaxonomy(ctx, "/api/v1/styles/"+id(styleID), opts)
+}
+
+func (c *Client) StyleIllustrations(ctx context.Context, styleID uint, opts *ListOptions) iter.Seq2[Illustration, error] {
+	return eachPage(opts, func(o *ListOptions) ([]Illustration, error) {
+		return c.listIllustrationsOf(ctx, "/api/v1/styles/"+id(styleID), o)
+	})
+}
+
+func (c *Client) RenameStyle(ctx context.Context, styleID uint, name string) (*Taxonomy, error) {
+	var out Taxonomy
+	if err := c.do(ctx, http.MethodPut, "/api/v1/styles/"+id(styleID), nil, named{name}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) DeleteStyle(ctx context.Context, styleID uint) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/styles/"+id(styleID), nil, nil, nil)
+}
@@ -0,0 +1,132 @@
+package client
+
+import "time"
+
+// Illustration is an illustration as returned by the API. Fields that were not
+// requested (see ListOptions.Fields) are left zero.
+type Illustration struct {
+	ID         uint      `json:"id"`
+	Title      string    `json:"title"`
+	StyleID    *uint     `json:"style_id"`
+	CategoryID *uint     `json:"category_id"`
+	PackID     *uint     `json:"pack_id"`
+	FileName   string    `json:"file_name"`
+	IsPremium  bool      `json:"is_premium"`
+	Tags       []string  `json:"tags,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	ImageURL              string     `json:"image_url"`
+	ImageURLExpiresAt     *time.Time `json:"image_url_expires_at,omitempty"`
+	DownloadURL           string     `json:"download_url"`
+	DownloadURLExpiresAt  *time.Time `json:"download_url_expires_at,omitempty"`
+	ThumbnailURL          string     `json:"thumbnail_url"`
+	ThumbnailURLExpiresAt *time.Time `json:"thumbnail_url_expires_at,omitempty"`
+
+	// internal and admin callers only
+	StorageKey string     `json:"storage_key,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+
+	Category *Taxonomy `json:"category,omitempty"`
+	Pack     *Taxonomy `json:"pack,omitempty"`
+	Style    *Taxonomy `json:"style,omitempty"`
+}
+
+// Taxonomy is a category, pack or style.
+type Taxonomy struct {
+	ID            uint           `json:"id"`
+	Name          string         `json:"name"`
+	Slug          string         `json:"slug,omitempty"`
+	CreatedAt     *time.Time     `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time     `json:"updated_at,omitempty"`
+	Illustrations []Illustration `json:"illustrations,omitempty"`
+}
+
+// NewIllustration creates an illustration record for an object that is
+// already in the bucket.
+type NewIllustration struct {
+	Title      string `json:"title"`
+	StyleID    *uint  `json:"style_id,omitempty"`
+	CategoryID *uint  `json:"category_id,omitempty"`
+	PackID     *uint  `json:"pack_id,omitempty"`
+	FileName   string `json:"file_name"`
+	StorageKey string `json:"storage_key"`
+}
+
+// FileURL is the response of GET /illustrations/:id/file.
+type FileURL struct {
+	// URL and ExpiresIn are empty when the signed URL was narrowed.
+	URL                string    `json:"url,omitempty"`
+	ExpiresIn          int       `json:"expires_in,omitempty"`
+	SignedURL          string    `json:"signed_url"`
+	SignedURLExpiresAt time.Time `json:"signed_url_expires_at"`
+}
+
+// Job is a background job.
+type Job struct {
+	ID          uint       `json:"id"`
+	Type        string     `json:"type"`
+	UniqueKey   string     `json:"unique_key,omitempty"`
+	Payload     string     `json:"payload"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	RunAt       time.Time  `json:"run_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	Result      string     `json:"result,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ImportJob is a bulk import and, once fetched by ID, its per-file results.
+type ImportJob struct {
+	ID         uint         `json:"id"`
+	Status     string       `json:"status"`
+	JobID      *uint        `json:"job_id,omitempty"`
+	Total      int          `json:"total"`
+	Succeeded  int          `json:"succeeded"`
+	Failed     int          `json:"failed"`
+	Error      string       `json:"error,omitempty"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Items      []ImportItem `json:"items,omitempty"`
+}
+
+// ImportItem is the result for one file of an import.
+type ImportItem struct {
+	Path           string `json:"path"`
+	Title          string `json:"title"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	IllustrationID *uint  `json:"illustration_id,omitempty"`
+}
+
+// SigningKey is an asset signing key; secrets are never returned.
+type SigningKey struct {
+	ID          uint       `json:"id"`
+	KID         string     `json:"kid"`
+	Status      string     `json:"status"`
+	VerifyUntil *time.Time `json:"verify_until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Revocation is a revoked token, nonce, storage key or issue-time cutoff.
+type Revocation struct {
+	ID         uint      `json:"id"`
+	Kind       string    `json:"kind"`
+	Nonce      string    `json:"nonce,omitempty"`
+	StorageKey string    `json:"storage_key,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// RevokeRequest sets exactly one of Token, Nonce, StorageKey or IssuedBefore.
+type RevokeRequest struct {
+	Token        string     `json:"token,omitempty"`
+	Nonce        string     `json:"nonce,omitempty"`
+	StorageKey   string     `json:"storage_key,omitempty"`
+	IssuedBefore *time.Time `json:"issued_before,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const tusVersion = "1.0.0"
+
+// ResumableInput describes a tus upload. Size is the exact number of bytes r yields.
+type ResumableInput struct {
+	UploadInput
+	IsPremium bool
+	Size      int64
+	// ChunkSize defaults to 5 MiB.
+	ChunkSize int
+}
+
+// UploadResumable uploads r with the tus protocol, one chunk per PATCH. A
+// failed chunk is retried from the offset the server reports, so only that
+// chunk is buffered. It returns the created illustration's ID.
+func (c *Client) UploadResumable(ctx context.Context, in ResumableInput, r io.Reader) (uint, error) {
+	location, err := c.createUpload(ctx, in)
+	if err != nil {
+		return 0, err
+	}
+	chunkSize := in.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 5 << 20
+	}
+	buf := make([]byte, chunkSize)
+	var offset int64
+	for offset < in.Size {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, fmt.Errorf("client: read upload at offset %d: %w", offset, err)
+		}
+		chunk, failures := buf[:n], 0
+		for len(chunk) > 0 {
+			resp, err := c.send(ctx, request{
+				method:  http.MethodPatch,
+				path:    location,
+				header:  http.Header{"Tus-Resumable": {tusVersion}, "Upload-Offset": {strconv.FormatInt(offset, 10)}},
+				body:    chunk,
+				ctype:   "application/offset+octet-stream",
+				noRetry: true,
+			})
+			var next int64
+			if err != nil {
+				if failures++; failures > c.maxRetries || ctx.Err() != nil {
+					return 0, err
+				}
+				// the server may have stored part of the chunk
+				if next, err = c.uploadOffset(ctx, location); err != nil {
+					return 0, err
+				}
+			} else {
+				next, _ = strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+				illustration := resp.Header.Get("X-Illustration-Id")
+				resp.Body.Close()
+				if illustration != "" {
+					n, err := strconv.ParseUint(illustration, 10, 64)
+					return uint(n), err
+				}
+			}
+			if next < offset || next > offset+int64(len(chunk)) {
+				return 0, fmt.Errorf("client: server reported offset %d outside chunk at %d", next, offset)
+			}
+			chunk, offset = chunk[next-offset:], next
+		}
+	}
+	return 0, errors.New("client: upload finished without an illustration")
+}
+
+func (c *Client) createUpload(ctx context.Context, in ResumableInput) (string, error) {
+	meta := map[string]string{"title": in.Title, "filename": in.FileName, "is_premium": strconv.FormatBool(in.IsPremium)}
+	for name, v := range map[string]*uint{"category_id": in.CategoryID, "style_id": in.StyleID, "pack_id": in.PackID} {
+		if v != nil {
+			meta[name] = id(*v)
+		}
+	}
+	pairs := make([]string, 0, len(meta))
+	for k, v := range meta {
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(v)))
+	}
+	resp, err := c.send(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/v1/uploads",
+		header: http.Header{
+			"Tus-Resumable":   {tusVersion},
+			"Upload-Length":   {strconv.FormatInt(in.Size, 10)},
+			"Upload-Metadata": {strings.Join(pairs, ",")},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	u, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || u.Path == "" {
+		return "", fmt.Errorf("client: upload created without a Location")
+	}
+	return u.Path, nil
+}
+
+func (c *Client) uploadOffset(ctx context.Context, location string) (int64, error) {
+	resp, err := c.send(ctx, request{method: http.MethodHead, path: location, header: http.Header{"Tus-Resumable": {tusVersion}}})
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+}
+
+// CancelUpload terminates an unfinished upload by its Location path.
+func (c *Client) CancelUpload(ctx context.Context, location string) error {
+	resp, err := c.send(ctx, request{method: http.MethodDelete, path: location, header: http.Header{"Tus-Resumable": {tusVersion}}})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"open-illustrations-go/services"
+)
+
+// runImport zips the directory and runs it through the same import pipeline as
+// POST /api/v1/imports, but synchronously instead of on the job queue.
+func runImport(args []string) error {
+	var defaults services.ImportDefaults
+	var manifestPath string
+	rest, err := subcommand("import", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&defaults.Category, "category", "", "default category")
+		fs.StringVar(&defaults.Style, "style", "", "default style")
+		fs.StringVar(&defaults.Pack, "pack", "", "default pack")
+		fs.BoolVar(&defaults.IsPremium, "premium", false, "mark files as premium")
+		fs.StringVar(&manifestPath, "manifest", "", "manifest .json or .csv (default: manifest.json/.csv in the directory)")
+	})
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errors.New("expected exactly one directory")
+	}
+
+	archive, size, err := zipDirectory(rest[0])
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	var manifest io.Reader
+	if manifestPath != "" {
+		f, err := os.Open(manifestPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		manifest = f
+	}
+
+	job, err := services.CreateImport(archive, size, filepath.Base(manifestPath), manifest, defaults)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "importing %d files (import #%d)\n", job.Total, job.ID)
+	if err := services.RunImport(context.Background(), job.ID, true); err != nil {
+		return err
+	}
+	done, err := services.GetImport(job.ID)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"PATH", "STATUS", "ILLUSTRATION", "ERROR"}}
+	for _, item := range done.Items {
+		t.add(item.Path, item.Status, item.IllustrationID, item.Error)
+	}
+	if err := render(done, t); err != nil {
+		return err
+	}
+	if *output == "table" {
+		fmt.Fprintf(os.Stderr, "%d imported, %d failed\n", done.Succeeded, done.Failed)
+	}
+	if done.Failed > 0 {
+		return fmt.Errorf("%d files failed", done.Failed)
+	}
+	return nil
+}
+
+// zipDirectory writes the SVGs and root manifest of dir to a temporary ZIP.
+func zipDirectory(dir string) (*os.File, int64, error) {
+	tmp, err := os.CreateTemp("", "oictl-import-*.zip")
+	if err != nil {
+		return nil, 0, err
+	}
+	zw := zip.NewWriter(tmp)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		lower := strings.ToLower(rel)
+		if !strings.HasSuffix(lower, ".svg") && lower != "manifest.json" && lower != "manifest.csv" {
+			return nil
+		}
+		w, err := zw.Create(rel)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err == nil {
+		err = zw.Close()
+	}
+	var size int64
+	if err == nil {
+		size, err = tmp.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, 0, err
+	}
+	return tmp, size, nil
+}
+
+// exportedIllustration is one row of the catalogue export, with taxonomy names
+// instead of IDs so it can be read (or fed back as an import manifest).
+type exportedIllustration struct {
+	ID         uint       `json:"id"`
+	Title      string     `json:"title"`
+	File       string     `json:"file"`
+	StorageKey string     `json:"storage_key"`
+	Category   string     `json:"category,omitempty"`
+	Style      string     `json:"style,omitempty"`
+	Pack       string     `json:"pack,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	IsPremium  bool       `json:"is_premium"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+func runExport(args []string) error {
+	var deleted bool
+	if _, err := subcommand("export", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&deleted, "deleted", false, "include soft-deleted illustrations")
+	}); err != nil {
+		return err
+	}
+	list, err := services.ExportIllustrations(deleted)
+	if err != nil {
+		return err
+	}
+
+	out := make([]exportedIllustration, 0, len(list))
+	t := &table{header: []string{"ID", "TITLE", "FILE", "CATEGORY", "STYLE", "PACK", "PREMIUM", "CREATED"}}
+	for _, ill := range list {
+		e := exportedIllustration{
+			ID:         ill.ID,
+			Title:      ill.Title,
+			File:       ill.FileName,
+			StorageKey: ill.StorageKey,
+			Tags:       services.SplitTags(ill.Tags),
+			IsPremium:  ill.IsPremium,
+			CreatedAt:  ill.CreatedAt,
+		}
+		if ill.CategoryRef != nil {
+			e.Category = ill.CategoryRef.Name
+		}
+		if ill.StyleRef != nil {
+			e.Style = ill.StyleRef.Name
+		}
+		if ill.PackRef != nil {
+			e.Pack = ill.PackRef.Name
+		}
+		if ill.DeletedAt.Valid {
+			e.DeletedAt = &ill.DeletedAt.Time
+		}
+		out = append(out, e)
+		t.add(e.ID, e.Title, e.File, e.Category, e.Style, e.Pack, e.IsPremium, e.CreatedAt)
+	}
+	return render(out, t)
+}
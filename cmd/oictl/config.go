@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-yaml"
+)
+
+// runConfig prints the effective configuration as YAML (or JSON with -o json),
+// followed by any validation errors. Secrets are masked unless -show-secrets
+// is given.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("usage: oictl %s", commands["config"].usage)
+	}
+	var showSecrets bool
+	if _, err := subcommand("config", args[1:], func(fs *flag.FlagSet) {
+		fs.BoolVar(&showSecrets, "show-secrets", false, "print passwords, tokens and secrets instead of masking them")
+	}); err != nil {
+		return err
+	}
+
+	out := cfg.Redacted()
+	if showSecrets {
+		out = *cfg
+	}
+	b, err := out.YAML()
+	if err != nil {
+		return err
+	}
+	if *output == "json" {
+		if b, err = yaml.YAMLToJSON(b); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, b, "", "  "); err != nil {
+			return err
+		}
+		b = append(buf.Bytes(), '\n')
+	}
+	os.Stdout.Write(b)
+
+	if err := cfg.Validate(); err != nil {
+		return errors.New("invalid configuration:\n" + err.Error())
+	}
+	return nil
+}
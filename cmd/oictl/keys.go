@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"open-illustrations-go/models"
+	"open-illustrations-go/services"
+)
+
+func runKeys(args []string) error {
+	rest, err := subcommand("keys", args, nil)
+	if err != nil {
+		return err
+	}
+	var keys []models.SigningKey
+	switch {
+	case len(rest) == 1 && rest[0] == "list":
+		keys, err = services.ListSigningKeys()
+	case len(rest) == 1 && rest[0] == "rotate":
+		var k *models.SigningKey
+		if k, err = services.RotateSigningKey(); err == nil {
+			keys = []models.SigningKey{*k}
+		}
+	default:
+		return fmt.Errorf("usage: oictl %s", commands["keys"].usage)
+	}
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"ID", "KID", "STATUS", "VERIFY UNTIL", "CREATED"}}
+	for _, k := range keys {
+		t.add(k.ID, k.KID, k.Status, k.VerifyUntil, k.CreatedAt)
+	}
+	if rest[0] == "rotate" {
+		return render(keys[0], t)
+	}
+	return render(keys, t)
+}
+
+// mintedToken is the output of "token mint".
+type mintedToken struct {
+	Token     string               `json:"token"`
+	URL       string               `json:"url"`
+	ExpiresAt time.Time            `json:"expires_at"`
+	Claims    services.AssetClaims `json:"claims"`
+}
+
+// runMintToken issues an asset token the way the API would for the given
+// context, for debugging signed URLs. Revocation and replay state are untouched.
+func runMintToken(args []string) error {
+	var (
+		claims     services.AssetClaims
+		illID      string
+		urlCtx     string
+		pngSize    int
+		attachment bool
+		singleUse  bool
+		tier       string
+	)
+	if len(args) == 0 || args[0] != "mint" {
+		return fmt.Errorf("usage: oictl %s", commands["token"].usage)
+	}
+	rest, err := subcommand("token", args[1:], func(fs *flag.FlagSet) {
+		fs.StringVar(&illID, "illustration", "", "illustration ID")
+		fs.StringVar(&claims.StorageKey, "key", "", "storage key (instead of -illustration)")
+		fs.StringVar(&urlCtx, "context", string(services.URLContextDownload), "TTL policy: list, detail, download or internal")
+		fs.StringVar(&tier, "tier", "", "entitlement tier for the TTL policy")
+		fs.IntVar(&pngSize, "png", 0, "only unlock the PNG rendition at this width")
+		fs.BoolVar(&attachment, "attachment", false, "serve as a download")
+		fs.StringVar(&claims.ClientIP, "ip", "", "bind to a client IP")
+		fs.StringVar(&claims.Subject, "subject", "", "bind to an authenticated subject")
+		fs.BoolVar(&singleUse, "single-use", false, "reject the token after its first use")
+	})
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected arguments %v", rest)
+	}
+
+	switch {
+	case illID != "" && claims.StorageKey != "":
+		return errors.New("use either -illustration or -key")
+	case illID != "":
+		ill, err := services.GetIllustration(context.Background(), illID)
+		if err != nil {
+			return err
+		}
+		claims.StorageKey, claims.IllustrationID = ill.StorageKey, ill.ID
+	case claims.StorageKey == "":
+		return errors.New("-illustration or -key is required")
+	}
+	if pngSize > 0 {
+		claims.Variant = services.PNGVariant(pngSize)
+		if _, err := services.ParseAssetVariant(claims.Variant); err != nil {
+			return err
+		}
+	}
+	if attachment {
+		claims.Disposition = services.DispositionAttachment
+	}
+	if singleUse {
+		claims.Nonce = services.NewTokenNonce()
+	}
+
+	policy := services.TokenPolicy{Context: services.URLContext(urlCtx), Tier: tier}
+	tok, exp, err := services.IssuePolicyToken(claims, policy)
+	if err != nil {
+		return err
+	}
+	parsed, err := services.ParseAssetToken(tok)
+	if err != nil {
+		return err
+	}
+	out := mintedToken{Token: tok, URL: services.PublicURL("/api/v1/i/" + tok), ExpiresAt: exp, Claims: *parsed}
+	t := &table{header: []string{"URL", "EXPIRES", "KEY ID"}}
+	t.add(out.URL, out.ExpiresAt, parsed.KeyID)
+	return render(out, t)
+}
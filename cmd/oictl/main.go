@@ -0,0 +1,149 @@
+// Command oictl administers the illustration catalogue directly against the
+// database and bucket, using the same services as the API server.
+//
+//	oictl [-o table|json] [-config file] <command> [flags]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/logging"
+	"open-illustrations-go/services"
+)
+
+type command struct {
+	usage string
+	needs needs
+	run   func(args []string) error
+}
+
+// needs says which backends a command connects to before it runs.
+type needs int
+
+const (
+	needsDatabase needs = iota
+	needsStorage        // database and MinIO
+	needsNothing
+)
+
+var commands map[string]command
+
+// set up in init because the commands print their own usage from this table
+func init() {
+	commands = map[string]command{
+		"import":    {"import [-category c] [-style s] [-pack p] [-premium] [-manifest file] <dir>", needsStorage, runImport},
+		"export":    {"export [-deleted]", needsDatabase, runExport},
+		"category":  {"category list | create <name> | rename <id> <name>", needsDatabase, taxonomyCommand("category")},
+		"pack":      {"pack list | create <name> | rename <id> <name>", needsDatabase, taxonomyCommand("pack")},
+		"style":     {"style list | create <name> | rename <id> <name>", needsDatabase, taxonomyCommand("style")},
+		"keys":      {"keys list | rotate", needsDatabase, runKeys},
+		"reconcile": {"reconcile [-delete-orphans] [-grace 1h]", needsStorage, runReconcile},
+		"token":     {"token mint (-illustration id | -key storage-key) [-context download] [-png size] [-attachment] [-ip addr] [-subject s] [-single-use]", needsDatabase, runMintToken},
+		"migrate":   {"migrate [up [-to version] | down -steps n [-yes] | status]", needsDatabase, runMigrate},
+		"config":    {"config print [-show-secrets]", needsNothing, runConfig},
+	}
+}
+
+var (
+	output     = flag.String("o", "table", "output format: table or json")
+	configFile = flag.String("config", "", "YAML config file (default $CONFIG_FILE)")
+)
+
+// cfg is the loaded configuration, set before the command runs.
+var cfg *config.Config
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if *output != "table" && *output != "json" {
+		fatalf("unknown output format %q", *output)
+	}
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "oictl: unknown command %q\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	if cfg, err = config.Load(*configFile); err != nil {
+		fatalf("%v", err)
+	}
+	if cmd.needs != needsNothing {
+		if err := validate(cmd.needs); err != nil {
+			fatalf("invalid configuration:\n%v", err)
+		}
+		// LOG_LEVEL=debug prints every SQL statement
+		if err := logging.Init(logging.Options{Level: cfg.Log.Level, Format: "text"}); err != nil {
+			fatalf("%v", err)
+		}
+		services.Configure(cfg)
+		config.InitDatabase(cfg.Database)
+	}
+	if cmd.needs == needsStorage {
+		config.InitMinio(cfg.Storage)
+	}
+	if err := cmd.run(args[1:]); errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	} else if err != nil {
+		fatalf("%s: %v", args[0], err)
+	}
+}
+
+// validate checks the settings a command depends on; MinIO only matters to
+// storage commands, so "migrate" works on a host without bucket credentials.
+func validate(n needs) error {
+	errs := []error{
+		cfg.Server.Validate(),
+		cfg.Database.Validate(),
+		cfg.Assets.Validate(),
+		cfg.Jobs.Validate(),
+		cfg.Uploads.Validate(),
+		cfg.Log.Validate(),
+	}
+	if n == needsStorage {
+		errs = append(errs, cfg.Storage.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: oictl [-o table|json] [-config file] <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  oictl", commands[name].usage)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "oictl: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+// subcommand parses flags for one command; extra positional args are returned.
+func subcommand(name string, args []string, define func(fs *flag.FlagSet)) ([]string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, "usage: oictl", commands[name].usage) }
+	if define != nil {
+		define(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return fs.Args(), nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/migrations"
+	"open-illustrations-go/services"
+)
+
+func runReconcile(args []string) error {
+	var opts services.ReconcileOptions
+	if _, err := subcommand("reconcile", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&opts.DeleteOrphans, "delete-orphans", false, "remove objects no illustration points to")
+		fs.DurationVar(&opts.Grace, "grace", time.Hour, "keep orphans younger than this (uploads in flight)")
+	}); err != nil {
+		return err
+	}
+	report, err := services.ReconcileStorage(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"PROBLEM", "KEY", "ILLUSTRATION", "DETAIL"}}
+	for _, m := range report.Missing {
+		t.add("missing object", m.StorageKey, m.IllustrationID, m.Title)
+	}
+	for _, o := range report.Orphans {
+		t.add("orphaned object", o.Key, nil, fmt.Sprintf("%d bytes, %s", o.Size, cell(o.LastModified)))
+	}
+	if err := render(report, t); err != nil {
+		return err
+	}
+	if *output == "table" {
+		fmt.Fprintf(os.Stderr, "%d objects, %d records: %d missing, %d orphaned, %d deleted\n",
+			report.Objects, report.Records, len(report.Missing), len(report.Orphans), report.Deleted)
+	}
+	return nil
+}
+
+func runMigrate(args []string) error {
+	action := "up"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+	var target, steps int
+	var yes bool
+	if _, err := subcommand("migrate", args, func(fs *flag.FlagSet) {
+		fs.IntVar(&target, "to", 0, "up: stop after this version (default: latest)")
+		fs.IntVar(&steps, "steps", 0, "down: number of migrations to revert (required)")
+		fs.BoolVar(&yes, "yes", false, "down: allow reverting the baseline, which drops every table")
+	}); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var ran []migrations.Migration
+	var err error
+	switch action {
+	case "up":
+		ran, err = migrations.Up(ctx, config.DB, target)
+	case "down":
+		if steps < 1 {
+			return errors.New("migrate down: -steps is required")
+		}
+		ran, err = migrations.Down(ctx, config.DB, steps, yes)
+		if errors.Is(err, migrations.ErrBaselineRevert) {
+			return fmt.Errorf("%w; nothing was reverted, add -yes if you mean it", err)
+		}
+	case "status":
+		list, err := migrations.List(config.DB)
+		if err != nil {
+			return err
+		}
+		t := &table{header: []string{"VERSION", "NAME", "APPLIED"}}
+		for _, m := range list {
+			t.add(m.Version, m.Name, m.AppliedAt)
+		}
+		return render(list, t)
+	default:
+		return fmt.Errorf("usage: oictl %s", commands["migrate"].usage)
+	}
+
+	out := make([]migrations.Status, 0, len(ran))
+	t := &table{header: []string{"VERSION", "NAME"}}
+	for _, m := range ran {
+		out = append(out, migrations.Status{Version: m.Version, Name: m.Name})
+		t.add(m.Version, m.Name)
+	}
+	if rerr := render(out, t); rerr != nil && err == nil {
+		err = rerr
+	}
+	if err == nil && len(ran) == 0 && *output == "table" {
+		fmt.Fprintln(os.Stderr, "nothing to do")
+	}
+	return err
+}
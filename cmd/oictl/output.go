@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// table is what a command prints: v is written as-is for -o json, header and
+// rows for -o table.
+type table struct {
+	header []string
+	rows   [][]interface{}
+}
+
+func (t *table) add(cells ...interface{}) {
+	t.rows = append(t.rows, cells)
+}
+
+func render(v interface{}, t *table) error {
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = cell(c)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+func cell(v interface{}) string {
+	switch c := v.(type) {
+	case nil:
+		return "-"
+	case *uint:
+		if c == nil {
+			return "-"
+		}
+		return fmt.Sprint(*c)
+	case time.Time:
+		if c.IsZero() {
+			return "-"
+		}
+		return c.Local().Format("2006-01-02 15:04")
+	case *time.Time:
+		if c == nil {
+			return "-"
+		}
+		return cell(*c)
+	case string:
+		if c == "" {
+			return "-"
+		}
+		return c
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"open-illustrations-go/models"
+	"open-illustrations-go/services"
+)
+
+// taxonomy adapts the category, pack and style services to one shape. The three
+// models share their columns, so a Category stands in for all of them.
+type taxonomy struct {
+	list   func() ([]models.Category, error)
+	create func(name string) (*models.Category, error)
+	rename func(id, name string) (*models.Category, error)
+}
+
+var taxonomies = map[string]taxonomy{
+	"category": {
+		list:   func() ([]models.Category, error) { return services.GetCategories(services.QueryShape{}) },
+		create: services.CreateCategory,
+		rename: services.UpdateCategory,
+	},
+	"pack": {
+		list: func() ([]models.Category, error) {
+			packs, err := services.GetPacks(services.QueryShape{})
+			out := make([]models.Category, 0, len(packs))
+			for _, p := range packs {
+				out = append(out, packAsCategory(&p))
+			}
+			return out, err
+		},
+		create: func(name string) (*models.Category, error) { return wrapPack(services.CreatePack(name)) },
+		rename: func(id, name string) (*models.Category, error) { return wrapPack(services.UpdatePack(id, name)) },
+	},
+	"style": {
+		list: func() ([]models.Category, error) {
+			styles, err := services.GetStyles(services.QueryShape{})
+			out := make([]models.Category, 0, len(styles))
+			for _, s := range styles {
+				out = append(out, styleAsCategory(&s))
+			}
+			return out, err
+		},
+		create: func(name string) (*models.Category, error) { return wrapStyle(services.CreateStyle(name)) },
+		rename: func(id, name string) (*models.Category, error) { return wrapStyle(services.UpdateStyle(id, name)) },
+	},
+}
+
+func packAsCategory(p *models.Pack) models.Category {
+	return models.Category{ID: p.ID, Name: p.Name, Slug: p.Slug, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt}
+}
+
+func styleAsCategory(s *models.Style) models.Category {
+	return models.Category{ID: s.ID, Name: s.Name, Slug: s.Slug, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt}
+}
+
+func wrapPack(p *models.Pack, err error) (*models.Category, error) {
+	if err != nil {
+		return nil, err
+	}
+	c := packAsCategory(p)
+	return &c, nil
+}
+
+func wrapStyle(s *models.Style, err error) (*models.Category, error) {
+	if err != nil {
+		return nil, err
+	}
+	c := styleAsCategory(s)
+	return &c, nil
+}
+
+func taxonomyCommand(kind string) func(args []string) error {
+	return func(args []string) error {
+		tx := taxonomies[kind]
+		rest, err := subcommand(kind, args, nil)
+		if err != nil {
+			return err
+		}
+		if len(rest) == 0 {
+			return errors.New("expected list, create or rename")
+		}
+
+		var items []models.Category
+		switch {
+		case rest[0] == "list" && len(rest) == 1:
+			items, err = tx.list()
+		case rest[0] == "create" && len(rest) == 2:
+			var c *models.Category
+			if c, err = tx.create(rest[1]); err == nil {
+				items = []models.Category{*c}
+			}
+		case rest[0] == "rename" && len(rest) == 3:
+			var c *models.Category
+			if c, err = tx.rename(rest[1], rest[2]); err == nil {
+				items = []models.Category{*c}
+			}
+		default:
+			return fmt.Errorf("usage: oictl %s", commands[kind].usage)
+		}
+		if err != nil {
+			return err
+		}
+
+		t := &table{header: []string{"ID", "NAME", "SLUG", "CREATED"}}
+		for _, c := range items {
+			t.add(c.ID, c.Name, c.Slug, c.CreatedAt)
+		}
+		if rest[0] != "list" {
+			return render(items[0], t)
+		}
+		return render(items, t)
+	}
+}
@@ -0,0 +1,660 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joho/godotenv"
+)
+
+// Config is every setting the server and oictl read. Load fills it from, in
+// increasing priority: the defaults below, an optional YAML file, .env and the
+// process environment. The env tag names the variable; secret fields are
+// masked by Redacted.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Redis     RedisConfig     `yaml:"redis"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Assets    AssetConfig     `yaml:"assets"`
+	Jobs      JobConfig       `yaml:"jobs"`
+	Uploads   UploadConfig    `yaml:"uploads"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Log       LogConfig       `yaml:"log"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+}
+
+type ServerConfig struct {
+	// PublicBaseURL prefixes links in responses, e.g. http://localhost:8080.
+	PublicBaseURL string `yaml:"public_base_url" env:"API_PUBLIC_BASE_URL"`
+	Addr          string `yaml:"addr" env:"HTTP_ADDR"`
+	// TLS is served when both files are set.
+	TLSCertFile string `yaml:"tls_cert_file" env:"HTTP_TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"tls_key_file" env:"HTTP_TLS_KEY_FILE"`
+	// TrustedProxies lists the proxy addresses or CIDRs, comma-separated,
+	// whose X-Forwarded-For is believed when working out the client IP; "none"
+	// ignores the header. Empty trusts every peer, so clients can pick their
+	// own IP; set it in production.
+	TrustedProxies string `yaml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES"`
+	// Read and write timeouts don't apply to streaming routes (downloads and
+	// uploads); see middleware.Streaming.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	// ShutdownDelay is how long SIGTERM keeps serving with /readyz failing,
+	// so load balancers stop routing here before the listener closes.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"HTTP_SHUTDOWN_DELAY"`
+	// ShutdownTimeout is how long SIGTERM waits for in-flight requests.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+}
+
+// Proxies returns TrustedProxies as a list for gin's SetTrustedProxies. It is
+// nil for "none" and for an empty setting; check TrustedProxies to tell them
+// apart.
+func (s ServerConfig) Proxies() []string {
+	var out []string
+	for _, p := range strings.Split(s.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" && p != "none" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+type DatabaseConfig struct {
+	Driver      string `yaml:"driver" env:"DB_DRIVER"`
+	DSN         string `yaml:"dsn" env:"DB_DSN" secret:"true"`
+	Host        string `yaml:"host" env:"DB_HOST"`
+	Port        string `yaml:"port" env:"DB_PORT"`
+	User        string `yaml:"user" env:"DB_USER"`
+	Password    string `yaml:"password" env:"DB_PASS" secret:"true"`
+	Name        string `yaml:"name" env:"DB_NAME"`
+	SSLMode     string `yaml:"sslmode" env:"DB_SSLMODE"`
+	AutoMigrate bool   `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+	// SlowQueryThreshold logs statements that take longer at warn level;
+	// 0 turns it off. Every statement is logged at debug level.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD"`
+}
+
+type StorageConfig struct {
+	Endpoint  string `yaml:"endpoint" env:"MINIO_ENDPOINT"`
+	AccessKey string `yaml:"access_key" env:"MINIO_ROOT_USER"`
+	SecretKey string `yaml:"secret_key" env:"MINIO_ROOT_PASSWORD" secret:"true"`
+	UseSSL    bool   `yaml:"use_ssl" env:"MINIO_USE_SSL"`
+	Bucket    string `yaml:"bucket" env:"MINIO_BUCKET"`
+	// PublicBaseURL is the MinIO address browsers use, for presigned URLs.
+	PublicBaseURL     string `yaml:"public_base_url" env:"MINIO_PUBLIC_BASE_URL"`
+	PresignTTLSeconds int    `yaml:"presign_ttl_seconds" env:"PRESIGN_TTL_SECONDS"`
+}
+
+// RedisConfig is optional; Redis is used only when Host is set.
+type RedisConfig struct {
+	Host     string `yaml:"host" env:"REDIS_HOST"`
+	Port     string `yaml:"port" env:"REDIS_PORT"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	DB       int    `yaml:"db" env:"REDIS_DB"`
+}
+
+// Enabled reports whether a Redis server is configured.
+func (r RedisConfig) Enabled() bool {
+	return r.Host != ""
+}
+
+// Addr is host:port for the Redis client.
+func (r RedisConfig) Addr() string {
+	return net.JoinHostPort(r.Host, r.Port)
+}
+
+type AuthConfig struct {
+	// AdminToken enables /api/v1/admin; empty disables it.
+	AdminToken     string `yaml:"admin_token" env:"ADMIN_API_TOKEN" secret:"true"`
+	InternalSecret string `yaml:"internal_secret" env:"INTERNAL_PRESIGN_SECRET" secret:"true"`
+	// APIKeys identifies API clients: comma-separated "subject:tier:key"
+	// entries, tier may be empty. Clients send the key as a bearer token; the
+	// subject binds the URLs they mint and the tier picks token lifetimes.
+	APIKeys string `yaml:"api_keys" env:"API_KEYS" secret:"true"`
+}
+
+// APIClient is one entry of AuthConfig.APIKeys.
+type APIClient struct {
+	Subject string
+	Tier    string
+	Key     string
+}
+
+// Clients returns APIKeys as a list. Malformed entries are skipped; Validate
+// reports them.
+func (a AuthConfig) Clients() []APIClient {
+	var out []APIClient
+	for _, entry := range strings.Split(a.APIKeys, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			continue
+		}
+		out = append(out, APIClient{Subject: parts[0], Tier: strings.ToLower(parts[1]), Key: parts[2]})
+	}
+	return out
+}
+
+type AssetConfig struct {
+	// SigningSecret is a static signing key used alongside the keys in the database.
+	SigningSecret     string `yaml:"signing_secret" env:"ASSET_SIGNING_SECRET" secret:"true"`
+	ThumbnailSize     int    `yaml:"thumbnail_size" env:"ASSET_THUMBNAIL_SIZE"`
+	TTLBucketSeconds  int    `yaml:"ttl_bucket_seconds" env:"ASSET_TTL_BUCKET_SECONDS"`
+	KeyRetentionHours int    `yaml:"key_retention_hours" env:"ASSET_KEY_RETENTION_HOURS"`
+	KeyRotationDays   int    `yaml:"key_rotation_days" env:"ASSET_KEY_ROTATION_DAYS"`
+	ReplayStore       string `yaml:"replay_store" env:"TOKEN_REPLAY_STORE"`
+	// Token lifetimes by URL context (list, detail, download) and by tier,
+	// from ASSET_TTL_<CONTEXT>_SECONDS and ASSET_TTL_TIER_<TIER>_SECONDS.
+	ContextTTLSeconds map[string]int `yaml:"context_ttl_seconds"`
+	TierTTLSeconds    map[string]int `yaml:"tier_ttl_seconds"`
+}
+
+type JobConfig struct {
+	Backend     string `yaml:"backend" env:"JOB_BACKEND"`
+	Workers     int    `yaml:"workers" env:"JOB_WORKERS"`
+	MaxAttempts int    `yaml:"max_attempts" env:"JOB_MAX_ATTEMPTS"`
+	// LeaseSeconds is how long a running job stays claimed without a
+	// heartbeat from its worker before it is put back in the queue.
+	LeaseSeconds int `yaml:"lease_seconds" env:"JOB_LEASE_SECONDS"`
+}
+
+type UploadConfig struct {
+	TusMaxSizeBytes            int64 `yaml:"tus_max_size_bytes" env:"TUS_MAX_SIZE_BYTES"`
+	TusExpiryHours             int   `yaml:"tus_expiry_hours" env:"TUS_UPLOAD_EXPIRY_HOURS"`
+	ImportMaxFiles             int   `yaml:"import_max_files" env:"IMPORT_MAX_FILES"`
+	ImportMaxFileBytes         int64 `yaml:"import_max_file_bytes" env:"IMPORT_MAX_FILE_BYTES"`
+	ImportMaxTotalBytes        int64 `yaml:"import_max_total_bytes" env:"IMPORT_MAX_TOTAL_BYTES"`
+	PackArchiveDebounceSeconds int   `yaml:"pack_archive_debounce_seconds" env:"PACK_ARCHIVE_DEBOUNCE_SECONDS"`
+}
+
+// TracingConfig uses the standard OpenTelemetry variable names. The OTLP
+// exporter also honours the other OTEL_EXPORTER_OTLP_* variables (headers,
+// certificates) on its own.
+type TracingConfig struct {
+	// Exporter is none (default), stdout or otlp (OTLP over HTTP).
+	Exporter    string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	Endpoint    string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+	// SampleRatio is the share of new traces recorded; incoming sampled
+	// traces are always followed.
+	SampleRatio float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG"`
+}
+
+// RateLimitConfig holds the token-bucket policies for the expensive routes:
+// stream (/illustrations/:id/public and /i/:token) and download
+// (/packs/:id/download). Buckets are kept per client IP, or per API key for
+// callers that present one.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	// Store is memory (per instance) or redis (shared; needs REDIS_HOST).
+	Store             string `yaml:"store" env:"RATE_LIMIT_STORE"`
+	StreamPerMinute   int    `yaml:"stream_per_minute" env:"RATE_LIMIT_STREAM_PER_MINUTE"`
+	StreamBurst       int    `yaml:"stream_burst" env:"RATE_LIMIT_STREAM_BURST"`
+	DownloadPerMinute int    `yaml:"download_per_minute" env:"RATE_LIMIT_DOWNLOAD_PER_MINUTE"`
+	DownloadBurst     int    `yaml:"download_burst" env:"RATE_LIMIT_DOWNLOAD_BURST"`
+	// PackDownloadConcurrency caps simultaneous pack downloads per instance.
+	PackDownloadConcurrency int `yaml:"pack_download_concurrency" env:"PACK_DOWNLOAD_CONCURRENCY"`
+}
+
+// LogConfig sets the starting log level; it can be changed while the server
+// runs through the admin API.
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`   // debug, info, warn or error
+	Format string `yaml:"format" env:"LOG_FORMAT"` // json or text
+}
+
+// Default returns the built-in defaults.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownDelay:     5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{Driver: "mysql", SSLMode: "disable", SlowQueryThreshold: 200 * time.Millisecond},
+		Storage:  StorageConfig{PresignTTLSeconds: 600},
+		Redis:    RedisConfig{Port: "6379"},
+		Assets: AssetConfig{
+			ThumbnailSize:     256,
+			TTLBucketSeconds:  300,
+			KeyRetentionHours: 24,
+			ReplayStore:       "db",
+			ContextTTLSeconds: map[string]int{},
+			TierTTLSeconds:    map[string]int{},
+		},
+		Jobs: JobConfig{Backend: "db", Workers: 2, MaxAttempts: 5, LeaseSeconds: 60},
+		Uploads: UploadConfig{
+			TusMaxSizeBytes:            50 << 20,
+			TusExpiryHours:             24,
+			ImportMaxFiles:             2000,
+			ImportMaxFileBytes:         10 << 20,
+			ImportMaxTotalBytes:        1 << 30,
+			PackArchiveDebounceSeconds: 10,
+		},
+		Tracing: TracingConfig{Exporter: "none", ServiceName: "open-illustrations", SampleRatio: 1},
+		Log:     LogConfig{Level: "info", Format: "json"},
+		RateLimit: RateLimitConfig{
+			Enabled:                 true,
+			Store:                   "memory",
+			StreamPerMinute:         300,
+			StreamBurst:             60,
+			DownloadPerMinute:       6,
+			DownloadBurst:           3,
+			PackDownloadConcurrency: 4,
+		},
+	}
+}
+
+// Load reads the configuration. path is an optional YAML file; when empty,
+// CONFIG_FILE is used if set. The result is not validated; see Validate.
+func Load(path string) (*Config, error) {
+	// .env never overrides variables that are already set
+	_ = godotenv.Load()
+
+	cfg := Default()
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config file: %w", err)
+		}
+		if err := yaml.UnmarshalWithOptions(b, &cfg, yaml.Strict()); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return nil, err
+	}
+	if err := applyTTLEnv(&cfg.Assets); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// applyEnv overwrites every field with an env tag whose variable is set.
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			if err := applyEnv(fv); err != nil {
+				return err
+			}
+			continue
+		}
+		name := f.Tag.Get("env")
+		raw, ok := os.LookupEnv(name)
+		if name == "" || !ok || raw == "" {
+			continue
+		}
+		if f.Type == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a duration such as 30s or 2m", name, raw)
+			}
+			fv.SetInt(int64(d))
+			continue
+		}
+		switch f.Type.Kind() {
+		case reflect.String:
+			fv.SetString(raw)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a boolean", name, raw)
+			}
+			fv.SetBool(b)
+		case reflect.Float64:
+			n, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", name, raw)
+			}
+			fv.SetFloat(n)
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", name, raw)
+			}
+			fv.SetInt(n)
+		}
+	}
+	return nil
+}
+
+// applyTTLEnv collects ASSET_TTL_<CONTEXT>_SECONDS and ASSET_TTL_TIER_<TIER>_SECONDS.
+func applyTTLEnv(a *AssetConfig) error {
+	if a.ContextTTLSeconds == nil {
+		a.ContextTTLSeconds = map[string]int{}
+	}
+	if a.TierTTLSeconds == nil {
+		a.TierTTLSeconds = map[string]int{}
+	}
+	for _, kv := range os.Environ() {
+		key, val, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, "ASSET_TTL_") || !strings.HasSuffix(key, "_SECONDS") || key == "ASSET_TTL_BUCKET_SECONDS" {
+			continue
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", key, val)
+		}
+		name := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(key, "ASSET_TTL_"), "_SECONDS"))
+		if tier, ok := strings.CutPrefix(name, "tier_"); ok {
+			a.TierTTLSeconds[tier] = n
+		} else {
+			a.ContextTTLSeconds[name] = n
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	return errors.Join(
+		c.Server.Validate(),
+		c.Database.Validate(),
+		c.Storage.Validate(),
+		c.Redis.Validate(),
+		c.Auth.Validate(),
+		c.Assets.Validate(),
+		c.Jobs.Validate(),
+		c.Uploads.Validate(),
+		c.Tracing.Validate(),
+		c.Log.Validate(),
+		c.RateLimit.Validate(c.Redis),
+		c.validateKeyRetention(),
+	)
+}
+
+// defaultTokenTTL is the longest built-in signed-URL lifetime (list and
+// detail URLs), used when no override is longer.
+const defaultTokenTTL = 15 * time.Minute
+
+// MaxTokenTTL is the longest a signed URL can stay valid under these
+// settings: the longest context, tier or presign lifetime plus one expiry
+// bucket.
+func (c Config) MaxTokenTTL() time.Duration {
+	longest := defaultTokenTTL
+	seconds := []int{c.Storage.PresignTTLSeconds}
+	for _, n := range c.Assets.ContextTTLSeconds {
+		seconds = append(seconds, n)
+	}
+	for _, n := range c.Assets.TierTTLSeconds {
+		seconds = append(seconds, n)
+	}
+	for _, n := range seconds {
+		if d := time.Duration(n) * time.Second; d > longest {
+			longest = d
+		}
+	}
+	return longest + time.Duration(c.Assets.TTLBucketSeconds)*time.Second
+}
+
+// validateKeyRetention makes sure a rotated-out signing key keeps verifying
+// until every token it signed has expired.
+func (c *Config) validateKeyRetention() error {
+	retention := time.Duration(c.Assets.KeyRetentionHours) * time.Hour
+	if longest := c.MaxTokenTTL(); retention < longest {
+		return fmt.Errorf("ASSET_KEY_RETENTION_HOURS: %dh is shorter than the longest token lifetime (%s including one ASSET_TTL_BUCKET_SECONDS)",
+			c.Assets.KeyRetentionHours, longest)
+	}
+	return nil
+}
+
+func (s ServerConfig) Validate() error {
+	errs := []error{
+		checkURL("API_PUBLIC_BASE_URL", s.PublicBaseURL),
+		required("HTTP_ADDR", s.Addr),
+		atLeast("HTTP_READ_HEADER_TIMEOUT", s.ReadHeaderTimeout, time.Second),
+		atLeast("HTTP_READ_TIMEOUT", s.ReadTimeout, 0),
+		atLeast("HTTP_WRITE_TIMEOUT", s.WriteTimeout, 0),
+		atLeast("HTTP_IDLE_TIMEOUT", s.IdleTimeout, 0),
+		atLeast("HTTP_SHUTDOWN_DELAY", s.ShutdownDelay, 0),
+		atLeast("HTTP_SHUTDOWN_TIMEOUT", s.ShutdownTimeout, time.Second),
+	}
+	for _, p := range s.Proxies() {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				errs = append(errs, fmt.Errorf("HTTP_TRUSTED_PROXIES: %q is not an IP address or CIDR", p))
+			}
+		}
+	}
+	if (s.TLSCertFile == "") != (s.TLSKeyFile == "") {
+		errs = append(errs, errors.New("HTTP_TLS_CERT_FILE, HTTP_TLS_KEY_FILE: set both or neither"))
+	}
+	for name, file := range map[string]string{"HTTP_TLS_CERT_FILE": s.TLSCertFile, "HTTP_TLS_KEY_FILE": s.TLSKeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d DatabaseConfig) Validate() error {
+	var errs []error
+	switch d.Driver {
+	case "mysql", "postgres":
+		if d.DSN == "" {
+			errs = append(errs, required("DB_HOST", d.Host), required("DB_USER", d.User), required("DB_NAME", d.Name))
+		}
+	case "sqlite":
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER: %q is not mysql, postgres or sqlite", d.Driver))
+	}
+	errs = append(errs, atLeast("DB_SLOW_QUERY_THRESHOLD", d.SlowQueryThreshold, 0))
+	return errors.Join(errs...)
+}
+
+func (s StorageConfig) Validate() error {
+	return errors.Join(
+		required("MINIO_ENDPOINT", s.Endpoint),
+		required("MINIO_BUCKET", s.Bucket),
+		required("MINIO_ROOT_USER", s.AccessKey),
+		required("MINIO_ROOT_PASSWORD", s.SecretKey),
+		checkURL("MINIO_PUBLIC_BASE_URL", s.PublicBaseURL),
+		between("PRESIGN_TTL_SECONDS", s.PresignTTLSeconds, 60, 3600),
+	)
+}
+
+func (r RedisConfig) Validate() error {
+	if !r.Enabled() {
+		return nil
+	}
+	return errors.Join(
+		required("REDIS_PORT", r.Port),
+		between("REDIS_DB", r.DB, 0, 15),
+	)
+}
+
+func (a AuthConfig) Validate() error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, entry := range strings.Split(a.APIKeys, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		switch {
+		case len(parts) != 3 || parts[0] == "":
+			errs = append(errs, fmt.Errorf("API_KEYS: entry %d is not subject:tier:key", i+1))
+		case len(parts[2]) < 16:
+			errs = append(errs, fmt.Errorf("API_KEYS: key for %q is shorter than 16 characters", parts[0]))
+		case parts[2] == a.AdminToken:
+			errs = append(errs, fmt.Errorf("API_KEYS: key for %q is the admin token", parts[0]))
+		case seen[parts[2]]:
+			errs = append(errs, fmt.Errorf("API_KEYS: key for %q is used twice", parts[0]))
+		}
+		if len(parts) == 3 {
+			seen[parts[2]] = true
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a AssetConfig) Validate() error {
+	errs := []error{
+		between("ASSET_THUMBNAIL_SIZE", a.ThumbnailSize, 16, 4096),
+		between("ASSET_TTL_BUCKET_SECONDS", a.TTLBucketSeconds, 0, 3600),
+		between("ASSET_KEY_RETENTION_HOURS", a.KeyRetentionHours, 1, 24*30),
+		between("ASSET_KEY_ROTATION_DAYS", a.KeyRotationDays, 0, 365),
+		oneOf("TOKEN_REPLAY_STORE", a.ReplayStore, "db", "memory"),
+	}
+	if a.SigningSecret != "" && len(a.SigningSecret) < 16 {
+		errs = append(errs, errors.New("ASSET_SIGNING_SECRET: shorter than 16 characters"))
+	}
+	for name, n := range a.ContextTTLSeconds {
+		errs = append(errs, between("ASSET_TTL_"+strings.ToUpper(name)+"_SECONDS", n, 60, 86400))
+	}
+	for tier, n := range a.TierTTLSeconds {
+		errs = append(errs, between("ASSET_TTL_TIER_"+strings.ToUpper(tier)+"_SECONDS", n, 60, 86400))
+	}
+	return errors.Join(errs...)
+}
+
+func (j JobConfig) Validate() error {
+	return errors.Join(
+		oneOf("JOB_BACKEND", j.Backend, "db", "memory"),
+		between("JOB_WORKERS", j.Workers, 1, 64),
+		between("JOB_MAX_ATTEMPTS", j.MaxAttempts, 1, 100),
+		between("JOB_LEASE_SECONDS", j.LeaseSeconds, 10, 3600),
+	)
+}
+
+func (u UploadConfig) Validate() error {
+	var errs []error
+	if u.TusMaxSizeBytes <= 0 {
+		errs = append(errs, errors.New("TUS_MAX_SIZE_BYTES: not positive"))
+	}
+	if u.ImportMaxFileBytes <= 0 {
+		errs = append(errs, errors.New("IMPORT_MAX_FILE_BYTES: not positive"))
+	}
+	if u.ImportMaxTotalBytes < u.ImportMaxFileBytes {
+		errs = append(errs, errors.New("IMPORT_MAX_TOTAL_BYTES: smaller than IMPORT_MAX_FILE_BYTES"))
+	}
+	return errors.Join(append(errs,
+		between("TUS_UPLOAD_EXPIRY_HOURS", u.TusExpiryHours, 1, 168),
+		between("IMPORT_MAX_FILES", u.ImportMaxFiles, 1, 100000),
+		between("PACK_ARCHIVE_DEBOUNCE_SECONDS", u.PackArchiveDebounceSeconds, 1, 3600),
+	)...)
+}
+
+func (t TracingConfig) Validate() error {
+	var errs []error
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG: %g is outside [0, 1]", t.SampleRatio))
+	}
+	return errors.Join(append(errs,
+		oneOf("OTEL_TRACES_EXPORTER", t.Exporter, "none", "stdout", "otlp"),
+		checkURL("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", t.Endpoint),
+		required("OTEL_SERVICE_NAME", t.ServiceName),
+	)...)
+}
+
+func (r RateLimitConfig) Validate(redis RedisConfig) error {
+	errs := []error{
+		oneOf("RATE_LIMIT_STORE", r.Store, "memory", "redis"),
+		between("RATE_LIMIT_STREAM_PER_MINUTE", r.StreamPerMinute, 1, 1000000),
+		between("RATE_LIMIT_STREAM_BURST", r.StreamBurst, 1, 100000),
+		between("RATE_LIMIT_DOWNLOAD_PER_MINUTE", r.DownloadPerMinute, 1, 1000000),
+		between("RATE_LIMIT_DOWNLOAD_BURST", r.DownloadBurst, 1, 100000),
+		between("PACK_DOWNLOAD_CONCURRENCY", r.PackDownloadConcurrency, 1, 1000),
+	}
+	if r.Enabled && r.Store == "redis" && !redis.Enabled() {
+		errs = append(errs, errors.New("RATE_LIMIT_STORE: redis needs REDIS_HOST"))
+	}
+	return errors.Join(errs...)
+}
+
+func (l LogConfig) Validate() error {
+	return errors.Join(
+		oneOf("LOG_LEVEL", strings.ToLower(l.Level), "debug", "info", "warn", "error"),
+		oneOf("LOG_FORMAT", l.Format, "json", "text"),
+	)
+}
+
+func required(name, v string) error {
+	if strings.TrimSpace(v) == "" {
+		return fmt.Errorf("%s: required", name)
+	}
+	return nil
+}
+
+func between(name string, n, lo, hi int) error {
+	if n < lo || n > hi {
+		return fmt.Errorf("%s: %d is outside [%d, %d]", name, n, lo, hi)
+	}
+	return nil
+}
+
+func atLeast(name string, d, min time.Duration) error {
+	if d < min {
+		return fmt.Errorf("%s: %s is below %s", name, d, min)
+	}
+	return nil
+}
+
+func oneOf(name, v string, allowed ...string) error {
+	for _, a := range allowed {
+		if v == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %q is not one of %s", name, v, strings.Join(allowed, ", "))
+}
+
+func checkURL(name, v string) error {
+	if v == "" {
+		return nil
+	}
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s: %q is not an http(s) URL", name, v)
+	}
+	return nil
+}
+
+// Redacted returns a copy with every secret field masked, for printing.
+func (c Config) Redacted() Config {
+	out := c
+	redact(reflect.ValueOf(&out).Elem())
+	return out
+}
+
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			redact(fv)
+			continue
+		}
+		if f.Tag.Get("secret") == "true" && fv.Kind() == reflect.String && fv.String() != "" {
+			fv.SetString("********")
+		}
+	}
+}
+
+// YAML renders the configuration in the format Load accepts.
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxTokenTTL(t *testing.T) {
+	c := Default()
+	if got, want := c.MaxTokenTTL(), 20*time.Minute; got != want {
+		t.Errorf("defaults: MaxTokenTTL() = %s, want %s", got, want)
+	}
+	c.Assets.TierTTLSeconds = map[string]int{"pro": 86400}
+	if got, want := c.MaxTokenTTL(), 24*time.Hour+5*time.Minute; got != want {
+		t.Errorf("24h tier: MaxTokenTTL() = %s, want %s", got, want)
+	}
+}
+
+func TestValidateKeyRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention int
+		tierTTL   int
+		ok        bool
+	}{
+		{"defaults", 24, 0, true},
+		{"one hour covers the defaults", 1, 0, true},
+		{"24h tier needs more than 24h", 24, 86400, false},
+		{"25h covers a 24h tier", 25, 86400, true},
+		{"tier longer than retention", 1, 3600, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			c.Assets.KeyRetentionHours = tt.retention
+			if tt.tierTTL > 0 {
+				c.Assets.TierTTLSeconds = map[string]int{"pro": tt.tierTTL}
+			}
+			err := c.validateKeyRetention()
+			if (err == nil) != tt.ok {
+				t.Fatalf("validateKeyRetention() = %v, want ok=%v", err, tt.ok)
+			}
+			if err != nil && !strings.HasPrefix(err.Error(), "ASSET_KEY_RETENTION_HOURS: ") {
+				t.Errorf("error %q does not name the variable", err)
+			}
+		})
+	}
+}
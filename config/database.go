@@ -1,43 +1,103 @@
 package config
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"open-illustrations-go/models"
-	"os"
+	"log/slog"
+	"open-illustrations-go/logging"
+	"open-illustrations-go/migrations"
+	"open-illustrations-go/tracing"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func InitDatabase() {
-	_ = godotenv.Load()
+// InitDatabase connects config.DB using cfg.Database.
+func InitDatabase(cfg DatabaseConfig) {
+	driver := cfg.Driver
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		logging.Fatal("failed to configure database", "error", err)
+	}
 
-	dbUser := os.Getenv("DB_USER")
-	dbPass := os.Getenv("DB_PASS")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
+	DB, err = gorm.Open(dialector, &gorm.Config{
+		TranslateError: true,
+		Logger:         logging.GORM(cfg.SlowQueryThreshold),
+	})
+	if err != nil {
+		logging.Fatal("failed to connect to database", "error", err)
+	}
+	if driver == "sqlite" {
+		// one writer at a time; concurrent connections just get "database is locked"
+		if sqlDB, err := DB.DB(); err == nil {
+			sqlDB.SetMaxOpenConns(1)
+		}
+	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		dbUser, dbPass, dbHost, dbPort, dbName)
+	if err := DB.Use(tracing.GORM()); err != nil {
+		logging.Fatal("failed to register tracing", "error", err)
+	}
 
-	log.Printf("[DB] Using DSN: %s@tcp(%s:%s)/%s", dbUser, dbHost, dbPort, dbName)
+	slog.Info("connected to database", "driver", driver)
 
-	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	// Migrations normally run through "oictl migrate"; DB_AUTO_MIGRATE=true
+	// applies them at boot instead (the migration lock keeps replicas in line).
+	if cfg.AutoMigrate {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if _, err := migrations.Up(ctx, DB, 0); err != nil {
+			logging.Fatal("failed to migrate database", "error", err)
+		}
+		return
+	}
+	if n, err := migrations.Pending(DB); err != nil {
+		slog.Warn("could not check migrations", "error", err)
+	} else if n > 0 {
+		slog.Warn("pending migrations; run \"oictl migrate up\" or set DB_AUTO_MIGRATE=true", "pending", n)
 	}
+}
 
-	DB = DB.Debug()
+// openDialector builds the GORM dialector for DB_DRIVER (mysql, postgres or
+// sqlite). DB_DSN overrides the DSN assembled from DB_HOST, DB_PORT, DB_USER,
+// DB_PASS and DB_NAME; for sqlite DB_NAME is the database file.
+func openDialector(cfg DatabaseConfig) (gorm.Dialector, error) {
+	dsn := cfg.DSN
+	user, pass := cfg.User, cfg.Password
+	host, port, name := cfg.Host, cfg.Port, cfg.Name
 
-	if err := DB.AutoMigrate(&models.Category{}, &models.Pack{}, &models.Style{}, &models.Illustration{}); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+	switch cfg.Driver {
+	case "mysql":
+		if dsn == "" {
+			dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+				user, pass, host, port, name)
+		}
+		slog.Info("using MySQL", "user", user, "host", host, "port", port, "database", name)
+		return mysql.Open(dsn), nil
+	case "postgres":
+		if dsn == "" {
+			sslmode := cfg.SSLMode
+			if sslmode == "" {
+				sslmode = "disable"
+			}
+			dsn = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+				host, port, user, pass, name, sslmode)
+		}
+		slog.Info("using PostgreSQL", "user", user, "host", host, "port", port, "database", name)
+		return postgres.Open(dsn), nil
+	case "sqlite":
+		if dsn == "" {
+			if name == "" {
+				name = "open-illustrations.db"
+			}
+			dsn = "file:" + name + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+		}
+		slog.Info("using SQLite", "dsn", dsn)
+		return sqlite.Open(dsn), nil
 	}
-
-	log.Println("Connected to MySQL & migrated models")
+	return nil, fmt.Errorf("unsupported DB_DRIVER %q (want mysql, postgres or sqlite)", cfg.Driver)
 }
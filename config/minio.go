@@ -2,11 +2,12 @@ package config
 
 import (
 	"context"
-	"log"
-	"os"
-	"strconv"
+	"log/slog"
+
+	"open-illustrations-go/logging"
+	"open-illustrations-go/metrics"
+	"open-illustrations-go/tracing"
 
-	"github.com/joho/godotenv"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
@@ -14,36 +15,35 @@ import (
 var MinioClient *minio.Client
 var BucketName string
 
-func InitMinio() {
-	_ = godotenv.Load()
-
-	BucketName = os.Getenv("MINIO_BUCKET")
-
-	endpoint := os.Getenv("MINIO_ENDPOINT")
-	accessKey := os.Getenv("MINIO_ROOT_USER")
-	secretKey := os.Getenv("MINIO_ROOT_PASSWORD")
-	useSSL, _ := strconv.ParseBool(os.Getenv("MINIO_USE_SSL"))
+// InitMinio connects MinioClient using cfg and creates the bucket if needed.
+func InitMinio(cfg StorageConfig) {
+	BucketName = cfg.Bucket
 
-	client, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
-		Secure: useSSL,
+	transport, err := minio.DefaultTransport(cfg.UseSSL)
+	if err != nil {
+		logging.Fatal("failed to connect to MinIO", "error", err)
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:    cfg.UseSSL,
+		Transport: tracing.StorageTransport(metrics.StorageTransport(transport)),
 	})
 	if err != nil {
-		log.Fatalf("Error connect MinIO: %v", err)
+		logging.Fatal("failed to connect to MinIO", "error", err)
 	}
 	MinioClient = client
 
 	ctx := context.Background()
 	exists, err := client.BucketExists(ctx, BucketName)
 	if err != nil {
-		log.Fatalf("Error check bucket: %v", err)
+		logging.Fatal("failed to check bucket", "bucket", BucketName, "error", err)
 	}
 	if !exists {
 		if err := client.MakeBucket(ctx, BucketName, minio.MakeBucketOptions{Region: "us-east-1"}); err != nil {
-			log.Fatalf("Error create bucket: %v", err)
+			logging.Fatal("failed to create bucket", "bucket", BucketName, "error", err)
 		}
-		log.Printf("Bucket %s created!", BucketName)
+		slog.Info("bucket created", "bucket", BucketName)
 	} else {
-		log.Printf("Bucket %s already exists", BucketName)
+		slog.Info("bucket exists", "bucket", BucketName)
 	}
 }
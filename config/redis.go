@@ -0,0 +1,32 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"open-illustrations-go/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is nil unless REDIS_HOST is set.
+var Redis *redis.Client
+
+func InitRedis(cfg RedisConfig) {
+	if !cfg.Enabled() {
+		return
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr(),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		logging.Fatal("failed to connect to Redis", "addr", cfg.Addr(), "error", err)
+	}
+	Redis = client
+	slog.Info("connected to Redis", "addr", cfg.Addr())
+}
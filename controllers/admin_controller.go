@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/logging"
+	"open-illustrations-go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin guards /api/v1/admin routes with "Authorization: Bearer $ADMIN_API_TOKEN".
+// Admin routes are disabled entirely while ADMIN_API_TOKEN is unset.
+func RequireAdmin(c *gin.Context) {
+	token := auth.AdminToken
+	if token == "" {
+		apierror.Abort(c, apierror.Forbidden("admin API is disabled"))
+		return
+	}
+	if !isAdminRequest(c) {
+		apierror.Abort(c, apierror.Unauthorized("invalid admin token"))
+		return
+	}
+	c.Next()
+}
+
+// isAdminRequest reports whether the request carries the admin bearer token.
+func isAdminRequest(c *gin.Context) bool {
+	token := auth.AdminToken
+	got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// GetSigningKeys handles GET /api/v1/admin/signing-keys (secrets are never returned)
+func GetSigningKeys(c *gin.Context) {
+	list, err := services.ListSigningKeys()
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// RotateSigningKey handles POST /api/v1/admin/signing-keys/rotate
+// The previous active key keeps verifying tokens for ASSET_KEY_RETENTION_HOURS.
+func RotateSigningKey(c *gin.Context) {
+	key, err := services.RotateSigningKey()
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": key})
+}
+
+// GetRevocations handles GET /api/v1/admin/revocations
+func GetRevocations(c *gin.Context) {
+	list, err := services.ListRevocations()
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// RevokeTokens handles POST /api/v1/admin/revocations
+// Body: one of {"token"}, {"nonce"}, {"storage_key"} or {"issued_before": RFC3339}, plus optional "reason".
+func RevokeTokens(c *gin.Context) {
+	var in services.RevocationInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+	rev, err := services.RevokeTokens(in)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": rev})
+}
+
+// DeleteRevocation handles DELETE /api/v1/admin/revocations/:id
+func DeleteRevocation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, services.ErrRevocationNotFound)
+		return
+	}
+	if err := services.DeleteRevocation(uint(id)); err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "revocation deleted"})
+}
+
+type logLevelDTO struct {
+	Level string `json:"level" binding:"required"`
+}
+
+// GetLogLevel handles GET /api/v1/admin/log-level
+func GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": logLevelDTO{Level: logging.Level()}})
+}
+
+// SetLogLevel handles PUT /api/v1/admin/log-level with {"level": "debug"}.
+// The change applies to this process only and lasts until it restarts.
+func SetLogLevel(c *gin.Context) {
+	var in logLevelDTO
+	if err := c.ShouldBindJSON(&in); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+	if err := logging.SetLevel(in.Level); err != nil {
+		apierror.Abort(c, apierror.BadRequest(err.Error()))
+		return
+	}
+	slog.Info("log level changed", "level", logging.Level())
+	c.JSON(http.StatusOK, gin.H{"data": logLevelDTO{Level: logging.Level()}})
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"strings"
+
+	"open-illustrations-go/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authSubjectKey is the gin context key under which Authenticate stores the
+// caller's identity; tokens bound to a subject only work for that caller.
+const authSubjectKey = "auth.subject"
+
+// authTierKey holds the caller's entitlement tier, used to pick token lifetimes.
+const authTierKey = "auth.tier"
+
+// authKeyIDKey holds the keyID of the caller's API key, used for rate limiting.
+const authKeyIDKey = "auth.key_id"
+
+// Authenticate identifies API clients by "Authorization: Bearer <key>" against
+// API_KEYS and records their subject and tier. Requests without a bearer token
+// stay anonymous, and the admin token is left to RequireAdmin; any other
+// bearer token is rejected.
+func Authenticate(c *gin.Context) {
+	key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || key == "" || isAdminRequest(c) {
+		c.Next()
+		return
+	}
+	client, ok := apiClients[hashKey(key)]
+	if !ok {
+		apierror.Abort(c, apierror.Unauthorized("invalid API key"))
+		return
+	}
+	c.Set(authSubjectKey, client.Subject)
+	c.Set(authTierKey, client.Tier)
+	c.Set(authKeyIDKey, keyID(key))
+	c.Next()
+}
@@ -1,18 +1,20 @@
 package controllers
 
 import (
-	"archive/zip"
-	"context"
+	"errors"
 	"io"
+	"mime"
 	"net/http"
 	"time"
 
-	"open-illustrations-go/config"
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/dto"
+	"open-illustrations-go/metrics"
 	"open-illustrations-go/models"
 	"open-illustrations-go/services"
+	"open-illustrations-go/tracing"
 
 	"github.com/gin-gonic/gin"
-	"github.com/minio/minio-go/v7"
 )
 
 type createNamedDTO struct {
@@ -21,42 +23,68 @@ type createNamedDTO struct {
 
 // ---- Category Handlers ----
 func CreateCategory(c *gin.Context) {
-	var dto createNamedDTO
-	if err := c.ShouldBindJSON(&dto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+	var body createNamedDTO
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
-	cat, err := services.CreateCategory(dto.Name)
+	cat, err := services.CreateCategory(body.Name)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"data": cat})
+	writeTaxonomy(c, http.StatusCreated, func(s dto.Serializer) dto.TaxonomyResponse { return s.Category(cat) })
 }
 
 func GetCategories(c *gin.Context) {
-	cats, err := services.GetCategories()
+	s, ok := taxonomySerializer(c)
+	if !ok {
+		return
+	}
+	q, ok := paged(c, s.TaxonomyQuery("Illustrations"))
+	if !ok {
+		return
+	}
+	cats, err := services.GetCategories(q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": cats})
+	writeShaped(c, http.StatusOK, s, s.Categories(cats))
 }
 
 func GetCategory(c *gin.Context) {
-	cat, err := services.GetCategory(c.Param("id"))
+	s, ok := taxonomySerializer(c)
+	if !ok {
+		return
+	}
+	cat, err := services.FindCategory(c.Param("id"), s.TaxonomyQuery("Illustrations"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		apierror.Abort(c, apierror.Lookup(err, "category not found"))
+		return
+	}
+	writeShaped(c, http.StatusOK, s, s.Category(cat))
+}
+
+func taxonomySerializer(c *gin.Context) (dto.Serializer, bool) {
+	return serializerFor(c, dto.TaxonomyFields, dto.TaxonomyIncludes)
+}
+
+// writeTaxonomy renders a category, pack or style written by a create or update
+// in the same shape the read endpoints return.
+func writeTaxonomy(c *gin.Context, status int, render func(dto.Serializer) dto.TaxonomyResponse) {
+	s, ok := taxonomySerializer(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": cat})
+	writeShaped(c, status, s, render(s))
 }
 
 func DeleteCategory(c *gin.Context) {
 	id := c.Param("id")
 	cat, err := services.SoftDeleteCategory(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
 	ts := ""
@@ -68,42 +96,54 @@ func DeleteCategory(c *gin.Context) {
 
 // ---- Pack Handlers ----
 func CreatePack(c *gin.Context) {
-	var dto createNamedDTO
-	if err := c.ShouldBindJSON(&dto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+	var body createNamedDTO
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
-	p, err := services.CreatePack(dto.Name)
+	p, err := services.CreatePack(body.Name)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"data": p})
+	writeTaxonomy(c, http.StatusCreated, func(s dto.Serializer) dto.TaxonomyResponse { return s.Pack(p) })
 }
 
 func GetPacks(c *gin.Context) {
-	list, err := services.GetPacks()
+	s, ok := taxonomySerializer(c)
+	if !ok {
+		return
+	}
+	q, ok := paged(c, s.TaxonomyQuery("Illustrations"))
+	if !ok {
+		return
+	}
+	list, err := services.GetPacks(q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": list})
+	writeShaped(c, http.StatusOK, s, s.Packs(list))
 }
 
 func GetPack(c *gin.Context) {
-	p, err := services.GetPack(c.Param("id"))
+	s, ok := taxonomySerializer(c)
+	if !ok {
+		return
+	}
+	p, err := services.FindPack(c.Param("id"), s.TaxonomyQuery("Illustrations"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		apierror.Abort(c, apierror.Lookup(err, "pack not found"))
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": p})
+	writeShaped(c, http.StatusOK, s, s.Pack(p))
 }
 
 func DeletePack(c *gin.Context) {
 	id := c.Param("id")
 	p, err := services.SoftDeletePack(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
 	ts := ""
@@ -113,35 +153,105 @@ func DeletePack(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"id": p.ID, "deleted_at": ts})
 }
 
-// DownloadPacks: create a zip stream with all illustration SVGs in a pack.
+// DownloadPacks serves a prebuilt ZIP of a pack (see services.BuildPackArchive).
+// Query options: format=svg|png|both, size=<px> (PNG), ids=1,2,3 (subset of the pack)
+// and layout=flat|category|style. Every archive contains manifest.json and LICENSE.txt.
+// The archive is streamed with Range support, or with ?redirect=1 the client is sent
+// to a presigned URL. If the archive is still being built the response is 202 with
+// Retry-After; if illustrations are missing from storage the last complete archive
+// is served, or without one the response is 409 with the details.
+// Options that are not cached (subsets, uncommon PNG sizes) are built for the
+// request and streamed from a temporary file; ?redirect=1 does not apply to them
+// and they are limited to 50 illustrations.
 func DownloadPacks(c *gin.Context) {
 	pack, err := services.GetPack(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "pack not found"})
+		apierror.Abort(c, apierror.Lookup(err, "pack not found"))
 		return
 	}
-	// Load illustrations for this pack
-	var ills []models.Illustration
-	if err := config.DB.Where("pack_id = ? AND deleted_at IS NULL", pack.ID).Find(&ills).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	opts, err := services.ParsePackArchiveOptions(c.Query("format"), c.Query("size"), c.Query("ids"), c.Query("layout"))
+	if err != nil {
+		apierror.Abort(c, err)
 		return
 	}
-	filename := services.PackArchiveFileName(pack)
-	c.Header("Content-Type", "application/zip")
-	c.Header("Content-Disposition", "attachment; filename="+filename)
-	w := zip.NewWriter(c.Writer)
-	for _, ill := range ills {
-		// fetch object from MinIO
-		obj, err := config.MinioClient.GetObject(context.Background(), config.BucketName, ill.StorageKey, minio.GetObjectOptions{})
+	if !opts.Cacheable() {
+		downloadUncachedPack(c, pack, opts)
+		return
+	}
+	archive, err := services.CurrentPackArchive(pack, opts)
+	switch {
+	case errors.Is(err, services.ErrPackArchivePending):
+		job, err := services.EnqueuePackArchive(c.Request.Context(), pack.ID, opts, time.Now())
 		if err != nil {
-			continue
+			apierror.Abort(c, apierror.Internal(err))
+			return
 		}
-		f, err := w.Create(ill.FileName)
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusAccepted, gin.H{"status": "building", "job_id": job.ID})
+		return
+	case errors.Is(err, services.ErrPackArchiveIncomplete):
+		// try again in case the missing objects have been restored since
+		_, _ = services.EnqueuePackArchive(c.Request.Context(), pack.ID, opts, time.Now().Add(services.PackArchiveDebounce()))
+		apierror.Abort(c, apierror.From(err).WithDetails(archive.Error))
+		return
+	case errors.Is(err, services.ErrPackArchiveStale):
+		// serve the last complete archive and try again for the current content
+		_, _ = services.EnqueuePackArchive(c.Request.Context(), pack.ID, opts, time.Now().Add(services.PackArchiveDebounce()))
+	case err != nil:
+		apierror.Abort(c, err)
+		return
+	}
+
+	filename := services.PackArchiveFileName(pack)
+	if c.Query("redirect") == "1" {
+		u, err := services.GetDownloadURL(c.Request.Context(), archive.StorageKey, services.PresignTTL())
 		if err != nil {
-			continue
+			apierror.Abort(c, apierror.Internal(err))
+			return
 		}
-		io.Copy(f, obj)
-		obj.Close()
+		c.Redirect(http.StatusFound, u)
+		return
+	}
+
+	obj, err := services.OpenPackArchive(c.Request.Context(), archive)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
+	}
+	defer obj.Close()
+	servePackArchive(c, filename, archive, obj)
+}
+
+// downloadUncachedPack builds an archive that is not kept in the bucket and
+// streams it.
+func downloadUncachedPack(c *gin.Context, pack *models.Pack, opts services.PackArchiveOptions) {
+	ctx, span := tracing.Tracer().Start(c.Request.Context(), "build pack archive")
+	f, err := services.BuildUncachedPackArchive(ctx, pack, opts)
+	span.End()
+	if f != nil {
+		defer f.Close()
+	}
+	switch {
+	case errors.Is(err, services.ErrPackArchiveIncomplete):
+		apierror.Abort(c, apierror.From(err).WithDetails(f.Archive.Error))
+		return
+	case err != nil:
+		apierror.Abort(c, err)
+		return
+	}
+	servePackArchive(c, services.PackArchiveFileName(pack), &f.Archive, f)
+}
+
+func servePackArchive(c *gin.Context, filename string, archive *models.PackArchive, content io.ReadSeeker) {
+	c.Header("Content-Type", "application/zip")
+	// quoted, or RFC 2231-encoded for non-ASCII names, so any pack slug is safe
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Header("ETag", `"`+archive.ContentHash+`"`)
+	// ServeContent handles Range / If-Range / If-None-Match for resumable downloads
+	_, span := tracing.Tracer().Start(c.Request.Context(), "stream pack")
+	http.ServeContent(c.Writer, c.Request, filename, *archive.BuiltAt, content)
+	span.End()
+	if n := c.Writer.Size(); n > 0 {
+		metrics.StreamedBytes.WithLabelValues("pack").Add(float64(n))
 	}
-	w.Close()
 }
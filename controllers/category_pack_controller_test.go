@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"open-illustrations-go/middleware"
+	"open-illustrations-go/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServePackArchiveFileName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	built := time.Now()
+	for _, name := range []string{"pack-space-1-20261019.zip", "pack-my pack; v2-1-20261019.zip", `pack-"quoted"-1.zip`, "pack-éclair-1.zip"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/packs/1/download", nil)
+		servePackArchive(c, name, &models.PackArchive{ContentHash: "abc", BuiltAt: &built}, bytes.NewReader([]byte("zip")))
+
+		disposition, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition"))
+		if err != nil || disposition != "attachment" || params["filename"] != name {
+			t.Errorf("%q: Content-Disposition %q parses as %q %v, %v", name, w.Header().Get("Content-Disposition"), disposition, params, err)
+		}
+	}
+}
+
+func TestCreateMatchesReadShape(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useTestDB(t)
+	r := gin.New()
+	r.Use(middleware.Errors())
+	r.POST("/category", CreateCategory)
+	r.GET("/categories/:id", GetCategory)
+	r.POST("/pack", CreatePack)
+	r.GET("/packs/:id", GetPack)
+	r.POST("/styles", CreateStyle)
+	r.PUT("/styles/:id", UpdateStyle)
+	r.GET("/styles/:id", GetStyle)
+
+	do := func(method, path, body string, want int) map[string]any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if w.Code != want {
+			t.Fatalf("%s %s = %d %s, want %d", method, path, w.Code, w.Body, want)
+		}
+		var out struct{ Data map[string]any }
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out.Data
+	}
+	keys := func(m map[string]any) string {
+		var out []string
+		for k := range m {
+			out = append(out, k)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+
+	for _, tt := range []struct{ create, read string }{
+		{"/category", "/categories/"},
+		{"/pack", "/packs/"},
+		{"/styles", "/styles/"},
+	} {
+		created := do(http.MethodPost, tt.create, `{"name":"Space"}`, http.StatusCreated)
+		read := do(http.MethodGet, tt.read+strconv.Itoa(int(created["id"].(float64))), "", http.StatusOK)
+		if keys(created) != keys(read) {
+			t.Errorf("POST %s returns %s, GET returns %s", tt.create, keys(created), keys(read))
+		}
+	}
+	updated := do(http.MethodPut, "/styles/1", `{"name":"Flat"}`, http.StatusOK)
+	if keys(updated) != "created_at,id,name,slug,updated_at" {
+		t.Errorf("PUT /styles/1 returns %s", keys(updated))
+	}
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/services"
+)
+
+// Service errors and the status/code clients see for them. An empty message
+// passes the error text through (it is written for clients already).
+func init() {
+	for _, m := range []struct {
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{services.ErrTokenReplayed, http.StatusGone, "token_used", ""},
+		{services.ErrTokenWrongClient, http.StatusForbidden, "token_wrong_client", ""},
+		{services.ErrTokenWrongSubject, http.StatusForbidden, "token_wrong_subject", ""},
+		{services.ErrTokenRevoked, http.StatusForbidden, "token_revoked", ""},
+		{services.ErrInvalidAssetClaims, http.StatusBadRequest, "invalid_token_claims", ""},
+		{services.ErrNoSigningKey, http.StatusServiceUnavailable, "signing_unavailable", "asset signing is not configured"},
+
+		{services.ErrTitleRequired, http.StatusBadRequest, "title_required", "form field 'title' is required"},
+		{services.ErrNotSVG, http.StatusBadRequest, "not_svg", ""},
+		{services.ErrObjectExists, http.StatusConflict, "file_exists", ""},
+		{services.ErrStorageCheck, http.StatusInternalServerError, "storage_error", "storage check failed"},
+		{services.ErrStorageUpload, http.StatusInternalServerError, "storage_error", "failed to upload to storage"},
+		{services.ErrObjectMissing, http.StatusBadRequest, "object_missing", ""},
+		{services.ErrRecordNotStored, http.StatusInternalServerError, "internal", "failed to save record"},
+
+		{services.ErrInvalidRevocation, http.StatusBadRequest, "invalid_revocation", ""},
+		{services.ErrRevocationNotFound, http.StatusNotFound, "not_found", ""},
+		{services.ErrJobNotFound, http.StatusNotFound, "not_found", ""},
+		{services.ErrJobNotRetryable, http.StatusConflict, "job_not_retryable", ""},
+
+		{services.ErrImportNotZip, http.StatusBadRequest, "invalid_archive", ""},
+		{services.ErrImportEmpty, http.StatusBadRequest, "invalid_archive", ""},
+		{services.ErrImportBadManifest, http.StatusBadRequest, "invalid_manifest", ""},
+		{services.ErrImportTooLarge, http.StatusRequestEntityTooLarge, "too_large", ""},
+		{services.ErrImportFileTooBig, http.StatusRequestEntityTooLarge, "too_large", ""},
+		{services.ErrImportTooBig, http.StatusRequestEntityTooLarge, "too_large", ""},
+		{services.ErrImportNotFound, http.StatusNotFound, "not_found", ""},
+		{services.ErrImportDuplicate, http.StatusBadRequest, "invalid_archive", ""},
+
+		{services.ErrUploadNotFound, http.StatusNotFound, "not_found", ""},
+		{services.ErrUploadExpired, http.StatusGone, "upload_expired", ""},
+		{services.ErrUploadOffsetMismatch, http.StatusConflict, "offset_mismatch", "Upload-Offset does not match current offset"},
+		{services.ErrUploadFinished, http.StatusConflict, "upload_finished", ""},
+		{services.ErrUploadTooLarge, http.StatusRequestEntityTooLarge, "too_large", ""},
+		{services.ErrInvalidMetadata, http.StatusBadRequest, "invalid_metadata", ""},
+
+		{services.ErrPackEmpty, http.StatusNotFound, "pack_empty", ""},
+		{services.ErrPackArchiveIncomplete, http.StatusConflict, "pack_archive_incomplete", ""},
+		{services.ErrInvalidArchiveOptions, http.StatusBadRequest, "invalid_archive_options", ""},
+	} {
+		apierror.Register(m.err, m.status, m.code, m.message)
+	}
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"net/http"
+
+	"open-illustrations-go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz handles GET /healthz: the process is up and serving requests.
+// It checks no dependencies, so a database outage doesn't restart the container.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz handles GET /readyz: 200 when the database, bucket and Redis (if
+// configured) answer and no migrations are pending; 503 otherwise and while
+// the server is shutting down.
+func Readyz(c *gin.Context) {
+	ready, checks := services.CheckReadiness(c.Request.Context())
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
+
+// GetStatus handles GET /api/v1/status
+func GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": services.Status(c.Request.Context())})
+}
@@ -1,25 +1,26 @@
 package controllers
 
 import (
-	"crypto/rand"
+	"bytes"
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/hex"
-	"fmt"
+	"errors"
 	"io"
-	"log"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/dto"
+	"open-illustrations-go/metrics"
 	"open-illustrations-go/models"
 	"open-illustrations-go/services"
+	"open-illustrations-go/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type CreateIllustrationDTO struct {
@@ -31,219 +32,187 @@ type CreateIllustrationDTO struct {
 	StorageKey string `json:"storage_key"`
 }
 
+// tokenPolicy picks the signed-URL lifetime for this request; internal callers
+// get the presign TTL whatever endpoint they hit.
+func tokenPolicy(c *gin.Context, ctx services.URLContext) services.TokenPolicy {
+	if isInternalRequest(c) {
+		ctx = services.URLContextInternal
+	}
+	return services.TokenPolicy{Context: ctx, Tier: c.GetString(authTierKey)}
+}
+
+// signedAssetPath returns the /api/v1/i/ proxy path for storageKey.
+func signedAssetPath(c *gin.Context, ctx services.URLContext, storageKey string) (string, error) {
+	tok, _, err := services.IssuePolicyToken(services.AssetClaims{StorageKey: storageKey}, tokenPolicy(c, ctx))
+	if err != nil {
+		return "", err
+	}
+	return "/api/v1/i/" + tok, nil
+}
+
 // Only trusted internal callers may receive presigned URLs
 func isInternalRequest(c *gin.Context) bool {
-	secret := os.Getenv("INTERNAL_PRESIGN_SECRET")
+	secret := auth.InternalSecret
 	return secret != "" && c.GetHeader("X-Internal-Request") == secret
 }
 
-// LIST: GET /api/v1/illustrations
-func GetIllustrations(c *gin.Context) {
-	ills, err := services.GetIllustrations()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch illustrations"})
-		return
-	}
-
-	wantPresign := c.Query("include_presign") == "1" && isInternalRequest(c)
-
-	data := make([]gin.H, 0, len(ills))
-	for _, ill := range ills {
-		var url string
-
-		if ill.IsPremium {
-			if wantPresign {
-				if u, err := services.GetDownloadURL(ill.StorageKey, services.PresignTTL()); err == nil {
-					url = u
-				}
-			}
-			if url == "" {
-				if tok, err := services.GenerateAssetToken(ill.StorageKey, 15*time.Minute); err == nil {
-					url = "/api/v1/i/" + tok
-				} else {
-					url = fmt.Sprintf("/api/v1/illustrations/%d/public", ill.ID)
-				}
-			}
-		} else {
-			url = fmt.Sprintf("/api/v1/illustrations/%d/public", ill.ID)
-		}
+// assetRequester describes the caller for services.ResolveAssetURLs.
+func assetRequester(c *gin.Context) services.AssetRequester {
+	internal := isInternalRequest(c)
+	return services.AssetRequester{
+		Internal:    internal,
+		WantPresign: internal && c.Query("include_presign") == "1",
+		Tier:        c.GetString(authTierKey),
+	}
+}
 
-		url = makePublicURL(url)
-
-		data = append(data, gin.H{
-			"id":          ill.ID,
-			"title":       ill.Title,
-			"style_id":    ill.StyleID,
-			"category_id": ill.CategoryID,
-			"pack_id":     ill.PackID,
-			"file_name":   ill.FileName,
-			"is_premium":  ill.IsPremium,
-			"created_at":  ill.CreatedAt,
-			"updated_at":  ill.UpdatedAt,
-			"image_url":   url,
-			// optional: expose storage_key if needed internally
-			// "storage_key": ill.StorageKey,
-		})
-	}
-
-	c.JSON(http.StatusOK, gin.H{"data": data})
+// serializerFor picks the response audience and parses ?fields= and ?include=
+// against the names the endpoint supports. It writes a 400 and returns false
+// when either is invalid.
+func serializerFor(c *gin.Context, fields, includes []string) (dto.Serializer, bool) {
+	include, err := dto.ParseInclude(c.Query("include"), includes)
+	if err != nil {
+		apierror.Abort(c, apierror.New(http.StatusBadRequest, "invalid_query", err.Error()))
+		return dto.Serializer{}, false
+	}
+	only, err := dto.ParseFields(c.Query("fields"), fields)
+	if err != nil {
+		apierror.Abort(c, apierror.New(http.StatusBadRequest, "invalid_query", err.Error()))
+		return dto.Serializer{}, false
+	}
+	audience := dto.AudiencePublic
+	switch {
+	case isAdminRequest(c):
+		audience = dto.AudienceAdmin
+	case isInternalRequest(c):
+		audience = dto.AudienceInternal
+	}
+	return dto.Serializer{Audience: audience, Include: include, Fields: only, Requester: assetRequester(c)}, true
 }
 
-// GetIllustrationsByCategory handles GET /api/v1/categories/:id/illustrations
-func GetIllustrationsByCategory(c *gin.Context) {
-	wantPresign := c.Query("include_presign") == "1" && isInternalRequest(c)
-	id := c.Param("id")
+func illustrationSerializer(c *gin.Context) (dto.Serializer, bool) {
+	return serializerFor(c, dto.IllustrationFields, dto.IllustrationIncludes)
+}
 
-	data, err := services.GetIllustrationsByCategory(id)
+// writeShaped renders v as {"data": v}, keeping only the requested fields.
+func writeShaped(c *gin.Context, status int, s dto.Serializer, v interface{}) {
+	out, err := s.Render(v)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	out := make([]gin.H, 0, len(data))
-	for _, ill := range data {
-		var url string
-		item := gin.H{
-			"id": ill.ID, "title": ill.Title, "style_id": ill.StyleID,
-			"category_id": ill.CategoryID, "pack_id": ill.PackID,
-			"file_name": ill.FileName, "is_premium": ill.IsPremium,
-			"created_at": ill.CreatedAt, "updated_at": ill.UpdatedAt,
-		}
+	c.JSON(status, gin.H{"data": out})
+}
 
-		if ill.IsPremium {
-			if wantPresign {
-				if u, err := services.GetDownloadURL(ill.StorageKey, services.PresignTTL()); err == nil {
-					url = u
-				}
-			}
-			if url == "" {
-				if tok, err := services.GenerateAssetToken(ill.StorageKey, 15*time.Minute); err == nil {
-					url = "/api/v1/i/" + tok
-				} else {
-					url = fmt.Sprintf("/api/v1/illustrations/%d/public", ill.ID)
-				}
-			}
-		} else {
-			url = fmt.Sprintf("/api/v1/illustrations/%d/public", ill.ID)
+// maxPageSize caps ?limit= on list endpoints.
+const maxPageSize = 500
+
+// paged applies ?limit= and ?offset= to a list query. Without a limit the whole
+// list is returned. It writes a 400 and returns false when either is invalid.
+func paged(c *gin.Context, q services.QueryShape) (services.QueryShape, bool) {
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, "invalid_query", "limit must be between 1 and "+strconv.Itoa(maxPageSize)))
+			return q, false
 		}
-		item["image_url"] = makePublicURL(url)
-		out = append(out, item)
+		q.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || q.Limit == 0 {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, "invalid_query", "offset must be a non-negative integer and needs limit"))
+			return q, false
+		}
+		q.Offset = n
+	}
+	return q, true
+}
+
+// LIST: GET /api/v1/illustrations
+func GetIllustrations(c *gin.Context) {
+	s, ok := illustrationSerializer(c)
+	if !ok {
+		return
+	}
+	q, ok := paged(c, s.IllustrationQuery())
+	if !ok {
+		return
+	}
+	ills, err := services.GetIllustrations(c.Request.Context(), q)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": out})
+	writeShaped(c, http.StatusOK, s, s.Illustrations(ills))
+}
+
+// GetIllustrationsByCategory handles GET /api/v1/categories/:id/illustrations
+func GetIllustrationsByCategory(c *gin.Context) {
+	s, ok := illustrationSerializer(c)
+	if !ok {
+		return
+	}
+	q, ok := paged(c, s.IllustrationQuery())
+	if !ok {
+		return
+	}
+	data, err := services.GetIllustrationsByCategory(c.Request.Context(), c.Param("id"), q)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	writeShaped(c, http.StatusOK, s, s.Illustrations(data))
 }
 
 // GetIllustrationsByStyle handles GET /api/v1/styles/:id/illustrations
 func GetIllustrationsByStyle(c *gin.Context) {
-	wantPresign := c.Query("include_presign") == "1" && isInternalRequest(c)
-	id := c.Param("id")
-	data, err := services.GetIllustrationsByStyle(id)
+	s, ok := illustrationSerializer(c)
+	if !ok {
+		return
+	}
+	q, ok := paged(c, s.IllustrationQuery())
+	if !ok {
+		return
+	}
+	data, err := services.GetIllustrationsByStyle(c.Request.Context(), c.Param("id"), q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	out := make([]gin.H, 0, len(data))
-	for _, ill := range data {
-		item := gin.H{"id": ill.ID, "title": ill.Title, "style_id": ill.StyleID, "category_id": ill.CategoryID, "pack_id": ill.PackID, "file_name": ill.FileName, "is_premium": ill.IsPremium, "created_at": ill.CreatedAt, "updated_at": ill.UpdatedAt}
-		var url string
-		if ill.IsPremium {
-			if wantPresign {
-				if u, err := services.GetDownloadURL(ill.StorageKey, services.PresignTTL()); err == nil {
-					url = u
-				}
-			}
-			if url == "" {
-				if tok, err := services.GenerateAssetToken(ill.StorageKey, 15*time.Minute); err == nil {
-					url = "/api/v1/i/" + tok
-				} else {
-					url = fmt.Sprintf("/api/v1/illustrations/%d/public", ill.ID)
-				}
-			}
-		} else {
-			url = fmt.Sprintf("/api/v1/illustrations/%d/public", ill.ID)
-		}
-		item["image_url"] = makePublicURL(url)
-		out = append(out, item)
+		apierror.Abort(c, err)
+		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": out})
+	writeShaped(c, http.StatusOK, s, s.Illustrations(data))
 }
 
 // GetIllustrationsByPack handles GET /api/v1/packs/:id/illustrations
 func GetIllustrationsByPack(c *gin.Context) {
-	wantPresign := c.Query("include_presign") == "1" && isInternalRequest(c)
-	id := c.Param("id")
-	data, err := services.GetIllustrationsByPack(id)
+	s, ok := illustrationSerializer(c)
+	if !ok {
+		return
+	}
+	q, ok := paged(c, s.IllustrationQuery())
+	if !ok {
+		return
+	}
+	data, err := services.GetIllustrationsByPack(c.Request.Context(), c.Param("id"), q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	out := make([]gin.H, 0, len(data))
-	for _, ill := range data {
-		item := gin.H{"id": ill.ID, "title": ill.Title, "style_id": ill.StyleID, "category_id": ill.CategoryID, "pack_id": ill.PackID, "file_name": ill.FileName, "is_premium": ill.IsPremium, "created_at": ill.CreatedAt, "updated_at": ill.UpdatedAt}
-		var url string
-		if ill.IsPremium {
-			if wantPresign {
-				if u, err := services.GetDownloadURL(ill.StorageKey, services.PresignTTL()); err == nil {
-					url = u
-				}
-			}
-			if url == "" {
-				if tok, err := services.GenerateAssetToken(ill.StorageKey, 15*time.Minute); err == nil {
-					url = "/api/v1/i/" + tok
-				} else {
-					url = fmt.Sprintf("/api/v1/illustrations/%d/public", ill.ID)
-				}
-			}
-		} else {
-			url = fmt.Sprintf("/api/v1/illustrations/%d/public", ill.ID)
-		}
-		item["image_url"] = makePublicURL(url)
-		out = append(out, item)
+		apierror.Abort(c, err)
+		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": out})
+	writeShaped(c, http.StatusOK, s, s.Illustrations(data))
 }
 
 // DETAIL: GET /api/v1/illustrations/:id
 func GetIllustration(c *gin.Context) {
-	id := c.Param("id")
-	ill, err := services.GetIllustration(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	s, ok := illustrationSerializer(c)
+	if !ok {
 		return
 	}
-
-	wantPresign := c.Query("include_presign") == "1" && isInternalRequest(c)
-
-	var url string
-	if ill.IsPremium {
-		if wantPresign {
-			if u, err := services.GetDownloadURL(ill.StorageKey, services.PresignTTL()); err == nil {
-				url = u
-			}
-		}
-		if url == "" {
-			if tok, err := services.GenerateAssetToken(ill.StorageKey, 15*time.Minute); err == nil {
-				url = "/api/v1/i/" + tok
-			} else {
-				url = fmt.Sprintf("/api/v1/illustrations/%s/public", id)
-			}
-		}
-	} else {
-		url = fmt.Sprintf("/api/v1/illustrations/%s/public", id)
-	}
-
-	payload := gin.H{
-		"id":          ill.ID,
-		"title":       ill.Title,
-		"style_id":    ill.StyleID,
-		"category_id": ill.CategoryID,
-		"pack_id":     ill.PackID,
-		"file_name":   ill.FileName,
-		"is_premium":  ill.IsPremium,
-		"created_at":  ill.CreatedAt,
-		"updated_at":  ill.UpdatedAt,
-		"image_url":   url,
+	ill, err := services.FindIllustration(c.Request.Context(), c.Param("id"), s.IllustrationQuery())
+	if err != nil {
+		apierror.Abort(c, apierror.Lookup(err, "illustration not found"))
+		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{"data": payload})
+	writeShaped(c, http.StatusOK, s, s.Illustration(ill, services.URLContextDetail))
 }
 
 // GetIllustrationFileURL returns a short-lived presigned URL for a given storage key
@@ -251,7 +220,7 @@ func GetIllustration(c *gin.Context) {
 func GetIllustrationFileURL(c *gin.Context) {
 	key := c.Param("key")
 	if key == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing key"})
+		apierror.Abort(c, apierror.BadRequest("missing key"))
 		return
 	}
 
@@ -259,44 +228,91 @@ func GetIllustrationFileURL(c *gin.Context) {
 	// Enforce server-side policy for presign TTL
 	exp := services.PresignTTL()
 
-	u, err := services.GetDownloadURL(key, exp)
+	u, err := services.GetDownloadURL(c.Request.Context(), key, exp)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
 	// also include backend signed proxy path as a fallback that doesn't expose storage details
-	tok, _ := services.GenerateAssetToken(key, 15*time.Minute)
+	signed, _ := signedAssetPath(c, services.URLContextDownload, key)
 	c.JSON(http.StatusOK, gin.H{
 		"url":        u,
 		"expires_in": int(exp.Seconds()),
-		"signed_url": "/api/v1/i/" + tok,
+		"signed_url": signed,
 	})
 }
 
 // GetIllustrationFileURLByID returns a short-lived presigned URL for an illustration by its numeric ID
 // Route: GET /api/v1/illustrations/:id/file
+// The signed URL can be narrowed with query params:
+//
+//	variant=png&size=256      token only unlocks the PNG rendition
+//	disposition=attachment    serve as a download
+//	bind_ip=1                 only valid from the caller's IP
+//	bind_user=1               only valid with the caller's API key
+//	single_use=1              rejected after the first request
+//
+// The presigned storage URL (url, expires_in) is only returned when none of
+// variant, bind_ip, bind_user or single_use narrows the token.
 func GetIllustrationFileURLByID(c *gin.Context) {
 	id := c.Param("id")
 	// lookup illustration to get its storage key
-	ill, err := services.GetIllustration(id)
+	ill, err := services.GetIllustration(c.Request.Context(), id)
 	if err != nil || ill == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "illustration not found"})
+		apierror.Abort(c, apierror.Lookup(err, "illustration not found"))
 		return
 	}
 
-	exp := services.PresignTTL()
-
-	u, err := services.GetDownloadURL(ill.StorageKey, exp)
+	claims := services.AssetClaims{
+		StorageKey:     ill.StorageKey,
+		IllustrationID: ill.ID,
+		Disposition:    c.Query("disposition"),
+	}
+	switch c.DefaultQuery("variant", services.AssetVariantOriginal) {
+	case services.AssetVariantOriginal:
+	case "png":
+		size, err := strconv.Atoi(c.DefaultQuery("size", "512"))
+		if err != nil {
+			apierror.Abort(c, apierror.BadRequest("invalid size"))
+			return
+		}
+		claims.Variant = services.PNGVariant(size)
+	default:
+		apierror.Abort(c, apierror.BadRequest("variant must be original or png"))
+		return
+	}
+	if c.Query("bind_ip") == "1" {
+		claims.ClientIP = c.ClientIP()
+	}
+	if c.Query("bind_user") == "1" {
+		if claims.Subject = c.GetString(authSubjectKey); claims.Subject == "" {
+			apierror.Abort(c, apierror.Unauthorized("bind_user needs an API key"))
+			return
+		}
+	}
+	if c.Query("single_use") == "1" {
+		claims.Nonce = services.NewTokenNonce()
+	}
+	tok, signedExp, err := services.IssuePolicyToken(claims, tokenPolicy(c, services.URLContextDownload))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	tok, _ := services.GenerateAssetToken(ill.StorageKey, 15*time.Minute)
-	c.JSON(http.StatusOK, gin.H{
-		"url":        u,
-		"expires_in": int(exp.Seconds()),
-		"signed_url": "/api/v1/i/" + tok,
-	})
+	resp := gin.H{
+		"signed_url":            "/api/v1/i/" + tok,
+		"signed_url_expires_at": signedExp.UTC(),
+	}
+	// a presigned URL would unlock the original for anyone, bypassing the narrowed token
+	if !claims.Narrowed() {
+		exp := services.PresignTTL()
+		u, err := services.GetDownloadURL(c.Request.Context(), ill.StorageKey, exp)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+		resp["url"], resp["expires_in"] = u, int(exp.Seconds())
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // Removed explicit GetIllustrationURL in favor of signed URL embedded responses
@@ -306,92 +322,49 @@ func processUpload(c *gin.Context) {
 
 	fh, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "form field 'file' is required"})
+		apierror.Abort(c, apierror.BadRequest("form field 'file' is required"))
 		return
 	}
-	title := c.PostForm("title")
-	styleIDStr := c.PostForm("style_id")
-	catIDStr := c.PostForm("category_id")
-	packIDStr := c.PostForm("pack_id")
 	objectName := c.PostForm("file_name")
 	if objectName == "" {
 		objectName = fh.Filename
 	}
-	if title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "form field 'title' is required"})
+	if c.PostForm("title") == "" {
+		apierror.Abort(c, apierror.BadRequest("form field 'title' is required"))
 		return
 	}
-
 	f, err := fh.Open()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open uploaded file"})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 	defer f.Close()
 
-	// Validate SVG only (by extension + light content-type check)
-	if !isSVGFile(fh) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "only .svg files are allowed"})
-		return
-	}
-
-	// Generate unique storage key (random hex) while preserving original filename separately
-	storageKey := generateStorageKey(objectName)
-
-	// Jika sudah ada nama object yang sama -> tolak (hindari duplikat)
-	exists, err := services.MinioObjectExists(storageKey)
+	rec, err := services.IngestIllustration(services.IngestInput{
+		Title:       c.PostForm("title"),
+		FileName:    objectName,
+		SourceName:  fh.Filename,
+		StyleID:     services.ParseOptionalID(c.PostForm("style_id")),
+		CategoryID:  services.ParseOptionalID(c.PostForm("category_id")),
+		PackID:      services.ParseOptionalID(c.PostForm("pack_id")),
+		ContentType: fh.Header.Get("Content-Type"),
+	}, f, fh.Size)
 	if err != nil {
-		log.Println("check minio exists err:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "minio check failed"})
-		return
-	}
-	if exists {
-		c.JSON(http.StatusConflict, gin.H{"error": "file already exists in bucket", "file_name": objectName})
+		writeIngestError(c, err, objectName)
 		return
 	}
+	metrics.UploadSize.WithLabelValues("multipart").Observe(float64(fh.Size))
 
-	// upload ke MinIO
-	if err := services.UploadObject(storageKey, f, fh.Size, fh.Header.Get("Content-Type")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upload to storage", "detail": err.Error()})
-		return
-	}
+	writeCreatedIllustration(c, rec)
+}
 
-	var catIDPtr *uint
-	var styleIDPtr *uint
-	var packIDPtr *uint
-	if catIDStr != "" {
-		if v, err := strconv.ParseUint(catIDStr, 10, 64); err == nil {
-			vv := uint(v)
-			catIDPtr = &vv
-		}
+// writeIngestError maps services.IngestIllustration errors to the upload API responses.
+func writeIngestError(c *gin.Context, err error, objectName string) {
+	e := apierror.From(err)
+	if errors.Is(err, services.ErrObjectExists) {
+		e.WithDetails(gin.H{"file_name": objectName})
 	}
-	if styleIDStr != "" {
-		if v, err := strconv.ParseUint(styleIDStr, 10, 64); err == nil {
-			u := uint(v)
-			styleIDPtr = &u
-		}
-	}
-	if packIDStr != "" {
-		if v, err := strconv.ParseUint(packIDStr, 10, 64); err == nil {
-			vv := uint(v)
-			packIDPtr = &vv
-		}
-	}
-	rec := models.Illustration{
-		Title:      title,
-		StyleID:    styleIDPtr,
-		CategoryID: catIDPtr,
-		PackID:     packIDPtr,
-		FileName:   objectName,
-		StorageKey: storageKey,
-	}
-	if err := services.CreateIllustration(&rec); err != nil {
-		log.Println("db insert err:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save record"})
-		return
-	}
-
-	c.JSON(http.StatusCreated, gin.H{"data": rec})
+	apierror.Abort(c, e)
 }
 
 // Deprecated path: POST /illustrations/upload (still works). Prefer using POST /illustrations with multipart form-data.
@@ -409,7 +382,7 @@ func CreateIllustration(c *gin.Context) {
 
 	var dto CreateIllustrationDTO
 	if err := c.ShouldBindJSON(&dto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload (make sure Content-Type: application/json)"})
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
 	// c.JSON(http.StatusCreated, gin.H{"data": input})
@@ -417,7 +390,7 @@ func CreateIllustration(c *gin.Context) {
 	storageKey := dto.StorageKey
 	if storageKey == "" {
 		// For JSON-based creation we expect the storage_key (object already uploaded via another service)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "storage_key is required when creating via JSON"})
+		apierror.Abort(c, apierror.BadRequest("storage_key is required when creating via JSON"))
 		return
 	}
 	input := models.Illustration{
@@ -429,34 +402,42 @@ func CreateIllustration(c *gin.Context) {
 		StorageKey: storageKey,
 	}
 
-	if err := services.CreateIllustration(&input); err != nil {
-		log.Println("CreateIllustration DB/MINIO err:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := services.CreateIllustration(c.Request.Context(), &input); err != nil {
+		apierror.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"data": input})
+	writeCreatedIllustration(c, &input)
+}
+
+func writeCreatedIllustration(c *gin.Context, ill *models.Illustration) {
+	s, ok := illustrationSerializer(c)
+	if !ok {
+		return
+	}
+	writeShaped(c, http.StatusCreated, s, s.Illustration(ill, services.URLContextDetail))
 }
 
 func DeleteIllustration(c *gin.Context) {
 	id := c.Param("id")
 	if err := services.DeleteIllustration(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete illustration"})
+		apierror.Abort(c, apierror.Lookup(err, "illustration not found"))
+		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
 }
 
 func Download(c *gin.Context) {
 	id := c.Param("id")
-	ill, err := services.GetIllustration(id)
+	ill, err := services.GetIllustration(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		apierror.Abort(c, apierror.Lookup(err, "illustration not found"))
 		return
 	}
 
-	url, err := services.GetDownloadURL(ill.StorageKey, time.Hour*1)
+	url, err := services.GetDownloadURL(c.Request.Context(), ill.StorageKey, time.Hour*1)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate link"})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"download_url": url})
@@ -465,14 +446,28 @@ func Download(c *gin.Context) {
 // StreamSigned serves image via backend using signed token path: /api/v1/i/:token
 func StreamSigned(c *gin.Context) {
 	token := c.Param("token")
-	storageKey, err := services.ParseAndValidateAssetToken(token)
+	claims, err := services.VerifyAssetToken(c.Request.Context(), token, services.AssetRequest{
+		ClientIP: c.ClientIP(),
+		Subject:  c.GetString(authSubjectKey),
+	})
+	switch {
+	case errors.Is(err, services.ErrTokenReplayed), errors.Is(err, services.ErrTokenWrongClient),
+		errors.Is(err, services.ErrTokenWrongSubject), errors.Is(err, services.ErrTokenRevoked):
+		apierror.Abort(c, err)
+		return
+	case err != nil:
+		apierror.Abort(c, apierror.New(http.StatusUnauthorized, "token_invalid", "invalid or expired token").Wrap(err))
+		return
+	}
+	storageKey := claims.StorageKey
+	pngSize, err := services.ParseAssetVariant(claims.Variant)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+		apierror.Abort(c, apierror.New(http.StatusUnauthorized, "token_invalid", "invalid or expired token").Wrap(err))
 		return
 	}
-	obj, ct, reader, err := services.GetObjectStream(storageKey)
+	obj, ct, reader, err := services.GetObjectStream(c.Request.Context(), storageKey)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "object not found"})
+		apierror.Abort(c, apierror.From(err))
 		return
 	}
 	defer obj.Close()
@@ -480,36 +475,62 @@ func StreamSigned(c *gin.Context) {
 	if ct == "" || !strings.Contains(ct, "svg") {
 		ct = "image/svg+xml"
 	}
+	fileName := path.Base(storageKey)
 
-	sum := sha256.Sum256([]byte(storageKey))
+	sum := sha256.Sum256([]byte(storageKey + "|" + claims.Variant))
 	etag := base64.RawURLEncoding.EncodeToString(sum[:8])
 	if inm := c.GetHeader("If-None-Match"); inm != "" && inm == etag {
 		c.Status(http.StatusNotModified)
 		return
 	}
+
+	var body io.Reader = reader
+	if pngSize > 0 {
+		png, ok := renderPNG(c, reader, pngSize)
+		if !ok {
+			return
+		}
+		ct = "image/png"
+		fileName = strings.TrimSuffix(fileName, path.Ext(fileName)) + ".png"
+		body = bytes.NewReader(png)
+	}
+
+	disposition := services.DispositionInline
+	if claims.Disposition == services.DispositionAttachment {
+		disposition = services.DispositionAttachment
+	}
+	// caches must not keep serving the file after the token has expired
+	maxAge := claims.ExpiresAt - time.Now().Unix()
+	if maxAge > 900 {
+		maxAge = 900
+	}
+	cacheControl := "public, max-age=" + strconv.FormatInt(maxAge, 10)
+	if claims.Nonce != "" || claims.ClientIP != "" || claims.Subject != "" {
+		// bound tokens must not be served to someone else from a shared cache
+		cacheControl = "private, no-store"
+	}
 	c.Header("Content-Type", ct)
-	c.Header("Cache-Control", "public, max-age=900")
+	c.Header("Cache-Control", cacheControl)
 	c.Header("ETag", etag)
-	c.Header("Content-Disposition", "inline; filename=\""+storageKey+"\"")
+	c.Header("Content-Disposition", disposition+"; filename=\""+fileName+"\"")
 	c.Header("Content-Security-Policy", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'")
-	_, _ = io.Copy(c.Writer, reader)
+	streamBody(c, "signed", body)
 }
 
-// StreamPublic serves non-premium images publicly by illustration ID: /api/v1/illustrations/:id/public
 func StreamPublic(c *gin.Context) {
 	id := c.Param("id")
-	ill, err := services.GetIllustration(id)
+	ill, err := services.GetIllustration(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		apierror.Abort(c, apierror.Lookup(err, "illustration not found"))
 		return
 	}
 	if ill.IsPremium {
-		c.JSON(http.StatusForbidden, gin.H{"error": "premium content is not publicly accessible"})
+		apierror.Abort(c, apierror.Forbidden("premium content is not publicly accessible"))
 		return
 	}
-	obj, ct, reader, err := services.GetObjectStream(ill.StorageKey)
+	obj, ct, reader, err := services.GetObjectStream(c.Request.Context(), ill.StorageKey)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "object not found"})
+		apierror.Abort(c, apierror.From(err))
 		return
 	}
 	defer obj.Close()
@@ -517,57 +538,78 @@ func StreamPublic(c *gin.Context) {
 	if ct == "" || !strings.Contains(ct, "svg") {
 		ct = "image/svg+xml"
 	}
-	sum := sha256.Sum256([]byte(ill.StorageKey))
+	fileName := ill.FileName
+
+	// ?format=png&size=N serves a PNG rendition (used for thumbnail_url)
+	pngSize := 0
+	if c.Query("format") == "png" {
+		pngSize, err = services.ParseAssetVariant(services.PNGVariant(atoiDefault(c.Query("size"), services.ThumbnailSize())))
+		if err != nil {
+			apierror.Abort(c, apierror.BadRequest("invalid size"))
+			return
+		}
+	}
+
+	sum := sha256.Sum256([]byte(ill.StorageKey + "|" + strconv.Itoa(pngSize)))
 	etag := base64.RawURLEncoding.EncodeToString(sum[:8])
 	if inm := c.GetHeader("If-None-Match"); inm != "" && inm == etag {
 		c.Status(http.StatusNotModified)
 		return
 	}
+
+	var body io.Reader = reader
+	if pngSize > 0 {
+		png, ok := renderPNG(c, reader, pngSize)
+		if !ok {
+			return
+		}
+		ct = "image/png"
+		fileName = strings.TrimSuffix(fileName, path.Ext(fileName)) + ".png"
+		body = bytes.NewReader(png)
+	}
+
+	disposition := services.DispositionInline
+	if c.Query("download") == "1" {
+		disposition = services.DispositionAttachment
+	}
 	c.Header("Content-Type", ct)
 	c.Header("Cache-Control", "public, max-age=86400")
 	c.Header("ETag", etag)
-	c.Header("Content-Disposition", "inline; filename=\""+ill.FileName+"\"")
+	c.Header("Content-Disposition", disposition+"; filename=\""+fileName+"\"")
 	c.Header("Content-Security-Policy", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'")
-	_, _ = io.Copy(c.Writer, reader)
+	streamBody(c, "public", body)
 }
 
-// --- helpers ---
-func isSVGFile(fh *multipart.FileHeader) bool {
-	name := strings.ToLower(fh.Filename)
-	if !strings.HasSuffix(name, ".svg") {
-		return false
-	}
-	f, err := fh.Open()
-	if err != nil {
-		return false
-	}
-	defer f.Close()
-	buf := make([]byte, 512)
-	n, _ := f.Read(buf)
-	snippet := strings.ToLower(string(buf[:n]))
-	return strings.Contains(snippet, "<svg")
+// streamBody copies body to the response inside a span, so slow clients show
+// up separately from the storage call that opened the object.
+func streamBody(c *gin.Context, endpoint string, body io.Reader) {
+	_, span := tracing.Tracer().Start(c.Request.Context(), "stream "+endpoint)
+	defer span.End()
+	n, _ := io.Copy(c.Writer, body)
+	span.SetAttributes(attribute.Int64("http.response.body.size", n))
+	metrics.StreamedBytes.WithLabelValues(endpoint).Add(float64(n))
 }
 
-func generateStorageKey(original string) string {
-	// random 8 bytes -> 16 hex chars + original ext
-	b := make([]byte, 8)
-	if _, err := rand.Read(b); err != nil {
-		return original // fallback
+// renderPNG rasterizes the SVG in r, writing an error response on failure.
+func renderPNG(c *gin.Context, r io.Reader, size int) ([]byte, bool) {
+	svg, err := io.ReadAll(io.LimitReader(r, 10<<20))
+	if err != nil {
+		apierror.Abort(c, apierror.New(http.StatusBadGateway, "storage_error", "failed to read object").Wrap(err))
+		return nil, false
 	}
-	ext := filepath.Ext(original)
-	if ext == "" {
-		ext = ".svg" // default
+	png, err := services.RenderSVGToPNG(svg, size)
+	if err != nil {
+		apierror.Abort(c, apierror.New(http.StatusUnprocessableEntity, "render_failed", "failed to render png").Wrap(err))
+		return nil, false
 	}
-	return fmt.Sprintf("%s-%s%s", time.Now().Format("20060102"), hex.EncodeToString(b), ext)
+	return png, true
 }
 
-func makePublicURL(p string) string {
-	if strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") {
-		return p
-	}
-	base := os.Getenv("API_PUBLIC_BASE_URL") // contoh: http://localhost:8080
-	if base == "" {
-		return p
+func atoiDefault(s string, def int) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
 	}
-	return strings.TrimRight(base, "/") + p
+	return def
 }
+
+// --- helpers ---
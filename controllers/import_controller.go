@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/metrics"
+	"open-illustrations-go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateImport handles POST /api/v1/imports
+// multipart fields => file (ZIP of SVGs), manifest (optional .json/.csv; a manifest.json
+// or manifest.csv at the root of the ZIP is used otherwise), and optional defaults
+// category, style, pack, is_premium for files the manifest doesn't describe.
+func CreateImport(c *gin.Context) {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		apierror.Abort(c, apierror.BadRequest("form field 'file' is required"))
+		return
+	}
+	archive, err := fh.Open()
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	defer archive.Close()
+
+	var manifestName string
+	var manifest io.Reader
+	if mh, err := c.FormFile("manifest"); err == nil {
+		mf, err := mh.Open()
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+		defer mf.Close()
+		manifestName, manifest = mh.Filename, mf
+	}
+
+	premium, _ := strconv.ParseBool(c.PostForm("is_premium"))
+	job, err := services.CreateImport(archive, fh.Size, manifestName, manifest, services.ImportDefaults{
+		Category:  c.PostForm("category"),
+		Style:     c.PostForm("style"),
+		Pack:      c.PostForm("pack"),
+		IsPremium: premium,
+	})
+	if err != nil {
+		e := apierror.From(err)
+		if errors.Is(err, services.ErrImportTooLarge) {
+			e.WithDetails(gin.H{"max_files": services.ImportMaxFiles()})
+		}
+		apierror.Abort(c, e)
+		return
+	}
+	metrics.UploadSize.WithLabelValues("import").Observe(float64(fh.Size))
+
+	if err := services.EnqueueImport(c.Request.Context(), job); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"data":       gin.H{"id": job.ID, "status": job.Status, "total": job.Total, "job_id": job.JobID},
+		"status_url": services.PublicURL(fmt.Sprintf("/api/v1/imports/%d", job.ID)),
+	})
+}
+
+// GetImport handles GET /api/v1/imports/:id (job status with per-file results)
+func GetImport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, services.ErrImportNotFound)
+		return
+	}
+	job, err := services.GetImport(uint(id))
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
@@ -3,6 +3,8 @@ package controllers
 import (
 	"net/http"
 
+	"open-illustrations-go/services"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,6 +16,6 @@ func About(c *gin.Context) {
 
 func License(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"license": "All illustrations are free to use under the MIT license.",
+		"license": services.LicenseSummary,
 	})
 }
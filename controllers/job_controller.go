@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJobs handles GET /api/v1/admin/jobs?type=&status=&limit=&offset=
+func GetJobs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	list, err := services.Jobs.List(c.Request.Context(), services.JobFilter{
+		Type:   c.Query("type"),
+		Status: c.Query("status"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetJob handles GET /api/v1/admin/jobs/:id
+func GetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.BadRequest("invalid job id"))
+		return
+	}
+	job, err := services.Jobs.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
+
+// RetryJob handles POST /api/v1/admin/jobs/:id/retry (failed jobs only)
+func RetryJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.BadRequest("invalid job id"))
+		return
+	}
+	job, err := services.Jobs.Retry(c.Request.Context(), uint(id))
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"data": job})
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"open-illustrations-go/config"
+)
+
+// auth holds the admin token and internal presign secret; Configure sets it.
+var auth config.AuthConfig
+
+// apiClients maps the SHA-256 of each API key to its client, so lookups never
+// compare raw keys.
+var apiClients = map[string]config.APIClient{}
+
+// Configure hands the auth settings to the request handlers.
+func Configure(cfg *config.Config) {
+	auth = cfg.Auth
+	apiClients = map[string]config.APIClient{}
+	for _, client := range cfg.Auth.Clients() {
+		apiClients[hashKey(client.Key)] = client
+	}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// keyID is a short, stable name for a credential that is safe to use in
+// bucket names and logs.
+func keyID(key string) string {
+	return hashKey(key)[:16]
+}
@@ -3,6 +3,8 @@ package controllers
 import (
 	"net/http"
 
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/dto"
 	"open-illustrations-go/services"
 
 	"github.com/gin-gonic/gin"
@@ -17,58 +19,69 @@ type updateStyleDTO struct {
 }
 
 func CreateStyle(c *gin.Context) {
-	var dto createStyleDTO
-	if err := c.ShouldBindJSON(&dto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var body createStyleDTO
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
-	s, err := services.CreateStyle(dto.Name)
+	st, err := services.CreateStyle(body.Name)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"data": s})
+	writeTaxonomy(c, http.StatusCreated, func(s dto.Serializer) dto.TaxonomyResponse { return s.Style(st) })
 }
 
 func GetStyles(c *gin.Context) {
-	list, err := services.GetStyles()
+	s, ok := taxonomySerializer(c)
+	if !ok {
+		return
+	}
+	q, ok := paged(c, s.TaxonomyQuery("Illustration"))
+	if !ok {
+		return
+	}
+	list, err := services.GetStyles(q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": list})
+	writeShaped(c, http.StatusOK, s, s.Styles(list))
 }
 
 func GetStyle(c *gin.Context) {
-	id := c.Param("id")
-	s, err := services.GetStyle(id)
+	s, ok := taxonomySerializer(c)
+	if !ok {
+		return
+	}
+	st, err := services.FindStyle(c.Param("id"), s.TaxonomyQuery("Illustration"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		apierror.Abort(c, apierror.Lookup(err, "style not found"))
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": s})
+	writeShaped(c, http.StatusOK, s, s.Style(st))
 }
 
 func UpdateStyle(c *gin.Context) {
-	var dto updateStyleDTO
-	if err := c.ShouldBindJSON(&dto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var body updateStyleDTO
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
 	id := c.Param("id")
-	s, err := services.UpdateStyle(id, dto.Name)
+	st, err := services.UpdateStyle(id, body.Name)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": s})
+	writeTaxonomy(c, http.StatusOK, func(s dto.Serializer) dto.TaxonomyResponse { return s.Style(st) })
 }
 
 func DeleteStyle(c *gin.Context) {
 	id := c.Param("id")
 	s, err := services.SoftDeleteStyle(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
 	// return deleted_at through service struct json
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/models"
+	"open-illustrations-go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resumable uploads following the tus 1.0.0 protocol (https://tus.io/protocols/resumable-upload).
+// Supported extensions: creation, termination, expiration.
+// Illustration fields are passed in Upload-Metadata: filename, title, style_id,
+// category_id, pack_id, is_premium, filetype.
+
+func setTusHeaders(c *gin.Context) {
+	c.Header("Tus-Resumable", services.TusVersion)
+	c.Header("Cache-Control", "no-store")
+}
+
+// tusVersionOK rejects requests that don't speak our protocol version (412).
+func tusVersionOK(c *gin.Context) bool {
+	if c.GetHeader("Tus-Resumable") != services.TusVersion {
+		c.Header("Tus-Version", services.TusVersion)
+		apierror.Abort(c, apierror.New(http.StatusPreconditionFailed, "precondition_failed", "unsupported tus version"))
+		return false
+	}
+	return true
+}
+
+func writeUploadState(c *gin.Context, u *models.Upload) {
+	c.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(u.Length, 10))
+	if u.Status == models.UploadStatusPending {
+		c.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	if u.IllustrationID != nil {
+		c.Header("X-Illustration-Id", strconv.FormatUint(uint64(*u.IllustrationID), 10))
+	}
+}
+
+// TusOptions handles OPTIONS /api/v1/uploads (capability discovery)
+func TusOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", services.TusVersion)
+	c.Header("Tus-Version", services.TusVersion)
+	c.Header("Tus-Extension", "creation,termination,expiration")
+	c.Header("Tus-Max-Size", strconv.FormatInt(services.TusMaxSize(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload handles POST /api/v1/uploads
+func CreateUpload(c *gin.Context) {
+	setTusHeaders(c)
+	if !tusVersionOK(c) {
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		apierror.Abort(c, apierror.BadRequest("Upload-Length header is required"))
+		return
+	}
+	u, err := services.CreateUpload(length, c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTitleRequired):
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, "title_required", "metadata 'title' is required"))
+		default:
+			apierror.Abort(c, err)
+		}
+		return
+	}
+	c.Header("Location", services.PublicURL(fmt.Sprintf("/api/v1/uploads/%s", u.ID)))
+	c.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// UploadStatus handles HEAD /api/v1/uploads/:id
+func UploadStatus(c *gin.Context) {
+	setTusHeaders(c)
+	if !tusVersionOK(c) {
+		return
+	}
+	u, err := services.GetUpload(c.Param("id"))
+	if err != nil {
+		writeUploadError(c, err)
+		return
+	}
+	writeUploadState(c, u)
+	c.Header("Upload-Metadata", u.Metadata)
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload handles PATCH /api/v1/uploads/:id
+func PatchUpload(c *gin.Context) {
+	setTusHeaders(c)
+	if !tusVersionOK(c) {
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		apierror.Abort(c, apierror.New(http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/offset+octet-stream"))
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		apierror.Abort(c, apierror.BadRequest("Upload-Offset header is required"))
+		return
+	}
+
+	u, err := services.AppendUploadChunk(c.Param("id"), offset, c.Request.Body)
+	if err != nil {
+		if u != nil && u.Status == models.UploadStatusPending && u.Offset > offset {
+			// partial body was stored; report the new offset so the client can resume
+			writeUploadState(c, u)
+		}
+		writeUploadError(c, err)
+		return
+	}
+	writeUploadState(c, u)
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload handles DELETE /api/v1/uploads/:id (termination extension)
+func DeleteUpload(c *gin.Context) {
+	setTusHeaders(c)
+	if !tusVersionOK(c) {
+		return
+	}
+	if err := services.TerminateUpload(c.Param("id")); err != nil {
+		writeUploadError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// writeUploadError reports upload errors; an assembled upload that failed the
+// same validation as a multipart upload is reported like one.
+func writeUploadError(c *gin.Context, err error) {
+	writeIngestError(c, err, "")
+}
@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParseFields parses a comma-separated ?fields= list against the allowed JSON
+// names. An empty list returns nil, meaning every field.
+func ParseFields(q string, allowed []string) (map[string]bool, error) {
+	if strings.TrimSpace(q) == "" {
+		return nil, nil
+	}
+	known := map[string]bool{}
+	for _, f := range allowed {
+		known[f] = true
+	}
+	out := map[string]bool{}
+	for _, f := range strings.Split(q, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !known[f] {
+			return nil, fmt.Errorf("unknown field %q (allowed: %s)", f, strings.Join(allowed, ", "))
+		}
+		out[f] = true
+	}
+	return out, nil
+}
+
+// Shape drops every top-level JSON key of v (an object or a list of objects)
+// that is not in fields. With nil fields v is returned unchanged.
+func Shape(v interface{}, fields map[string]bool) (interface{}, error) {
+	if fields == nil {
+		return v, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > 0 && raw[0] == '[' {
+		var list []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		for _, m := range list {
+			pick(m, fields)
+		}
+		return list, nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	pick(m, fields)
+	return m, nil
+}
+
+func pick(m map[string]json.RawMessage, fields map[string]bool) {
+	for k := range m {
+		if !fields[k] {
+			delete(m, k)
+		}
+	}
+}
+
+// columnsFor returns the database columns needed to render fields, given the
+// columns each field depends on. "id" is always selected.
+func columnsFor(fields map[string]bool, deps map[string][]string) []string {
+	if fields == nil {
+		return nil
+	}
+	seen := map[string]bool{"id": true}
+	cols := []string{"id"}
+	for f := range fields {
+		for _, col := range deps[f] {
+			if !seen[col] {
+				seen[col] = true
+				cols = append(cols, col)
+			}
+		}
+	}
+	return cols
+}
@@ -0,0 +1,220 @@
+// Package dto holds the API response types and the serializer that decides which
+// fields each audience gets to see.
+package dto
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"open-illustrations-go/models"
+	"open-illustrations-go/services"
+)
+
+// Audience is who a response is rendered for. Higher audiences see more fields.
+type Audience int
+
+const (
+	AudiencePublic Audience = iota
+	// AudienceInternal adds storage details for trusted internal callers.
+	AudienceInternal
+	// AudienceAdmin adds bookkeeping fields such as deleted_at.
+	AudienceAdmin
+)
+
+type IllustrationResponse struct {
+	ID         uint      `json:"id"`
+	Title      string    `json:"title"`
+	StyleID    *uint     `json:"style_id"`
+	CategoryID *uint     `json:"category_id"`
+	PackID     *uint     `json:"pack_id"`
+	FileName   string    `json:"file_name"`
+	IsPremium  bool      `json:"is_premium"`
+	Tags       []string  `json:"tags,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	services.AssetURLs
+
+	// internal and admin only
+	StorageKey string `json:"storage_key,omitempty"`
+	// admin only
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// only with ?include=
+	Category *TaxonomyResponse `json:"category,omitempty"`
+	Pack     *TaxonomyResponse `json:"pack,omitempty"`
+	Style    *TaxonomyResponse `json:"style,omitempty"`
+}
+
+// Relations that can be embedded with ?include=.
+const (
+	IncludeCategory      = "category"
+	IncludePack          = "pack"
+	IncludeStyle         = "style"
+	IncludeIllustrations = "illustrations"
+)
+
+// IllustrationIncludes are accepted by illustration endpoints.
+var IllustrationIncludes = []string{IncludeCategory, IncludePack, IncludeStyle}
+
+// ParseInclude parses a comma-separated include list, rejecting names not in allowed.
+func ParseInclude(q string, allowed []string) (map[string]bool, error) {
+	out := map[string]bool{}
+	for _, name := range strings.Split(q, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		ok := false
+		for _, a := range allowed {
+			ok = ok || a == name
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown include %q (allowed: %s)", name, strings.Join(allowed, ", "))
+		}
+		out[name] = true
+	}
+	return out, nil
+}
+
+// IllustrationFields are the names accepted by ?fields= on illustration endpoints.
+var IllustrationFields = []string{
+	"id", "title", "style_id", "category_id", "pack_id", "file_name", "is_premium", "tags",
+	"created_at", "updated_at", "image_url", "image_url_expires_at", "download_url",
+	"download_url_expires_at", "thumbnail_url", "thumbnail_url_expires_at", "storage_key", "deleted_at",
+}
+
+var urlColumns = []string{"is_premium", "storage_key"}
+
+// illustrationColumns maps each field to the columns it is built from.
+var illustrationColumns = map[string][]string{
+	"title":                    {"title"},
+	"style_id":                 {"style_id"},
+	"category_id":              {"category_id"},
+	"pack_id":                  {"pack_id"},
+	"file_name":                {"file_name"},
+	"is_premium":               {"is_premium"},
+	"tags":                     {"tags"},
+	"created_at":               {"created_at"},
+	"updated_at":               {"updated_at"},
+	"image_url":                urlColumns,
+	"image_url_expires_at":     urlColumns,
+	"download_url":             urlColumns,
+	"download_url_expires_at":  urlColumns,
+	"thumbnail_url":            urlColumns,
+	"thumbnail_url_expires_at": urlColumns,
+	"storage_key":              {"storage_key"},
+	"deleted_at":               {"deleted_at"},
+	IncludeCategory:            {"category_id"},
+	IncludePack:                {"pack_id"},
+	IncludeStyle:               {"style_id"},
+}
+
+// Serializer turns models into responses for one request.
+type Serializer struct {
+	Audience  Audience
+	Include   map[string]bool
+	Requester services.AssetRequester
+	// Fields limits the response to these JSON names (nil = all).
+	Fields map[string]bool
+}
+
+// IllustrationQuery returns the query shape that loads just enough to render
+// the requested fields and includes.
+func (s Serializer) IllustrationQuery() services.QueryShape {
+	var q services.QueryShape
+	if s.Fields != nil {
+		fields := map[string]bool{}
+		for f := range s.Fields {
+			fields[f] = true
+		}
+		for inc := range s.Include {
+			fields[inc] = true
+		}
+		q.Columns = columnsFor(fields, illustrationColumns)
+	}
+	if s.Include[IncludeCategory] {
+		q.Preloads = append(q.Preloads, "CategoryRef")
+	}
+	if s.Include[IncludePack] {
+		q.Preloads = append(q.Preloads, "PackRef")
+	}
+	if s.Include[IncludeStyle] {
+		q.Preloads = append(q.Preloads, "StyleRef")
+	}
+	return q
+}
+
+func (s Serializer) wantsURLs() bool {
+	if s.Fields == nil {
+		return true
+	}
+	for _, f := range []string{"image_url", "image_url_expires_at", "download_url", "download_url_expires_at", "thumbnail_url", "thumbnail_url_expires_at"} {
+		if s.Fields[f] {
+			return true
+		}
+	}
+	return false
+}
+
+// Render applies Fields to a response built by this serializer; included
+// relations are always kept.
+func (s Serializer) Render(v interface{}) (interface{}, error) {
+	if s.Fields == nil {
+		return v, nil
+	}
+	fields := map[string]bool{}
+	for f := range s.Fields {
+		fields[f] = true
+	}
+	for inc := range s.Include {
+		fields[inc] = true
+	}
+	return Shape(v, fields)
+}
+
+// Illustration renders ill; ctx is where its image URL will be shown.
+func (s Serializer) Illustration(ill *models.Illustration, ctx services.URLContext) IllustrationResponse {
+	out := IllustrationResponse{
+		ID:         ill.ID,
+		Title:      ill.Title,
+		StyleID:    ill.StyleID,
+		CategoryID: ill.CategoryID,
+		PackID:     ill.PackID,
+		FileName:   ill.FileName,
+		IsPremium:  ill.IsPremium,
+		Tags:       services.SplitTags(ill.Tags),
+		CreatedAt:  ill.CreatedAt,
+		UpdatedAt:  ill.UpdatedAt,
+	}
+	// signing tokens is skipped when no URL field was asked for
+	if s.wantsURLs() {
+		out.AssetURLs = services.ResolveAssetURLs(ill, s.Requester, ctx)
+	}
+	if s.Audience >= AudienceInternal {
+		out.StorageKey = ill.StorageKey
+	}
+	if s.Audience >= AudienceAdmin && ill.DeletedAt.Valid {
+		t := ill.DeletedAt.Time
+		out.DeletedAt = &t
+	}
+	if s.Include[IncludeCategory] && ill.CategoryRef != nil {
+		out.Category = &TaxonomyResponse{ID: ill.CategoryRef.ID, Name: ill.CategoryRef.Name, Slug: ill.CategoryRef.Slug}
+	}
+	if s.Include[IncludePack] && ill.PackRef != nil {
+		out.Pack = &TaxonomyResponse{ID: ill.PackRef.ID, Name: ill.PackRef.Name, Slug: ill.PackRef.Slug}
+	}
+	if s.Include[IncludeStyle] && ill.StyleRef != nil {
+		out.Style = &TaxonomyResponse{ID: ill.StyleRef.ID, Name: ill.StyleRef.Name, Slug: ill.StyleRef.Slug}
+	}
+	return out
+}
+
+// Illustrations renders a list view.
+func (s Serializer) Illustrations(ills []models.Illustration) []IllustrationResponse {
+	out := make([]IllustrationResponse, 0, len(ills))
+	for i := range ills {
+		out = append(out, s.Illustration(&ills[i], services.URLContextList))
+	}
+	return out
+}
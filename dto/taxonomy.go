@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"time"
+
+	"open-illustrations-go/models"
+	"open-illustrations-go/services"
+)
+
+// TaxonomyFields are the names accepted by ?fields= on category, pack and style endpoints.
+var TaxonomyFields = []string{"id", "name", "slug", "created_at", "updated_at"}
+
+// TaxonomyIncludes are accepted by category, pack and style endpoints.
+var TaxonomyIncludes = []string{IncludeIllustrations}
+
+var taxonomyColumns = map[string][]string{
+	"name":       {"name"},
+	"slug":       {"slug"},
+	"created_at": {"created_at"},
+	"updated_at": {"updated_at"},
+}
+
+// TaxonomyResponse is a category, pack or style. Embedded in an illustration it
+// only carries id, name and slug.
+type TaxonomyResponse struct {
+	ID            uint                   `json:"id"`
+	Name          string                 `json:"name"`
+	Slug          string                 `json:"slug,omitempty"`
+	CreatedAt     *time.Time             `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time             `json:"updated_at,omitempty"`
+	Illustrations []IllustrationResponse `json:"illustrations,omitempty"`
+}
+
+// TaxonomyQuery returns the query shape for a category, pack or style read;
+// relation is the model's illustrations field ("Illustrations" or "Illustration").
+func (s Serializer) TaxonomyQuery(relation string) services.QueryShape {
+	q := services.QueryShape{Columns: columnsFor(s.Fields, taxonomyColumns)}
+	if s.Include[IncludeIllustrations] {
+		q.Preloads = []string{relation}
+	}
+	return q
+}
+
+func (s Serializer) taxonomy(id uint, name, slug string, created, updated time.Time, ills []models.Illustration) TaxonomyResponse {
+	out := TaxonomyResponse{ID: id, Name: name, Slug: slug, CreatedAt: &created, UpdatedAt: &updated}
+	if s.Include[IncludeIllustrations] {
+		// nested illustrations are never field-filtered
+		inner := Serializer{Audience: s.Audience, Requester: s.Requester}
+		out.Illustrations = inner.Illustrations(ills)
+	}
+	return out
+}
+
+func (s Serializer) Category(c *models.Category) TaxonomyResponse {
+	return s.taxonomy(c.ID, c.Name, c.Slug, c.CreatedAt, c.UpdatedAt, c.Illustrations)
+}
+
+func (s Serializer) Pack(p *models.Pack) TaxonomyResponse {
+	return s.taxonomy(p.ID, p.Name, p.Slug, p.CreatedAt, p.UpdatedAt, p.Illustrations)
+}
+
+func (s Serializer) Style(st *models.Style) TaxonomyResponse {
+	return s.taxonomy(st.ID, st.Name, st.Slug, st.CreatedAt, st.UpdatedAt, st.Illustration)
+}
+
+func (s Serializer) Categories(list []models.Category) []TaxonomyResponse {
+	out := make([]TaxonomyResponse, 0, len(list))
+	for i := range list {
+		out = append(out, s.Category(&list[i]))
+	}
+	return out
+}
+
+func (s Serializer) Packs(list []models.Pack) []TaxonomyResponse {
+	out := make([]TaxonomyResponse, 0, len(list))
+	for i := range list {
+		out = append(out, s.Pack(&list[i]))
+	}
+	return out
+}
+
+func (s Serializer) Styles(list []models.Style) []TaxonomyResponse {
+	out := make([]TaxonomyResponse, 0, len(list))
+	for i := range list {
+		out = append(out, s.Style(&list[i]))
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GORM returns a GORM logger that writes through slog. Failed statements are
+// logged at error level and statements slower than slow (when positive) at
+// warn; every other statement is logged at debug, so LOG_LEVEL=debug shows
+// all SQL. Statements carry the request ID when run with db.WithContext.
+func GORM(slow time.Duration) gormlogger.Interface {
+	return gormLogger{slow: slow}
+}
+
+type gormLogger struct {
+	slow time.Duration
+}
+
+// LogMode is a no-op: the level follows LOG_LEVEL rather than GORM's own.
+func (l gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface { return l }
+
+func (gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	log := FromContext(ctx)
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		log.ErrorContext(ctx, "query failed", "sql", sql, "rows", rows, "duration_ms", millis(elapsed), "error", err)
+	case l.slow > 0 && elapsed > l.slow:
+		sql, rows := fc()
+		log.WarnContext(ctx, "slow query", "sql", sql, "rows", rows, "duration_ms", millis(elapsed), "threshold_ms", millis(l.slow))
+	case log.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		log.DebugContext(ctx, "query", "sql", sql, "rows", rows, "duration_ms", millis(elapsed))
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,81 @@
+// Package logging sets up the process-wide log/slog logger and carries a
+// per-request logger (with the request and trace IDs) through contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+var level = new(slog.LevelVar)
+
+// Options configures Init; see config.LogConfig.
+type Options struct {
+	Level  string // debug, info, warn or error
+	Format string // json or text
+}
+
+// Init installs the default slog logger writing to stderr. Output from the
+// standard log package, e.g. from libraries, goes through it at info level.
+func Init(opts Options) error {
+	if err := SetLevel(opts.Level); err != nil {
+		return err
+	}
+	hopts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch opts.Format {
+	case "", "json":
+		h = slog.NewJSONHandler(os.Stderr, hopts)
+	case "text":
+		h = slog.NewTextHandler(os.Stderr, hopts)
+	default:
+		return fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// Level returns the current minimum level, e.g. "info".
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+// SetLevel changes the minimum level of every logger at once; it is safe to
+// call while requests are being served.
+func SetLevel(name string) error {
+	if name == "" {
+		name = "info"
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return fmt.Errorf("unknown log level %q", name)
+	}
+	level.Set(l)
+	return nil
+}
+
+type ctxKey struct{}
+
+// WithLogger returns a copy of ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger stored by WithLogger, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+			return l
+		}
+	}
+	return slog.Default()
+}
+
+// Fatal logs at error level and exits, for startup failures.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -1,18 +1,161 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"open-illustrations-go/config"
+	"open-illustrations-go/controllers"
+	"open-illustrations-go/logging"
+	"open-illustrations-go/metrics"
+	"open-illustrations-go/middleware"
 	"open-illustrations-go/routes"
+	"open-illustrations-go/services"
+	"open-illustrations-go/tracing"
 )
 
 func main() {
-	config.InitDatabase()
-	config.InitMinio()
+	cfg, err := config.Load("")
+	if err != nil {
+		logging.Fatal("failed to load config", "error", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		logging.Fatal("invalid configuration", "error", err)
+	}
+	if err := logging.Init(logging.Options{Level: cfg.Log.Level, Format: cfg.Log.Format}); err != nil {
+		logging.Fatal("failed to set up logging", "error", err)
+	}
+	services.Configure(cfg)
+	controllers.Configure(cfg)
+
+	flushTraces, err := tracing.Init(context.Background(), tracing.Options{
+		Exporter:       cfg.Tracing.Exporter,
+		Endpoint:       cfg.Tracing.Endpoint,
+		ServiceName:    cfg.Tracing.ServiceName,
+		ServiceVersion: services.Version,
+		SampleRatio:    cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logging.Fatal("failed to set up tracing", "error", err)
+	}
+
+	config.InitDatabase(cfg.Database)
+	if sqlDB, err := config.DB.DB(); err == nil {
+		metrics.RegisterDB(sqlDB, cfg.Database.Driver)
+	}
+	config.InitMinio(cfg.Storage)
+	config.InitRedis(cfg.Redis)
+
+	// background work keeps running while the HTTP server drains
+	bg, stopBackground := context.WithCancel(context.Background())
+	services.StartUploadJanitor(bg, time.Hour)
+	services.InitJobs()
+	services.Jobs.Start(bg)
+	services.StartKeyRotation(bg)
+	services.StartReplayJanitor(bg, time.Hour)
 
-	r := gin.Default()
+	// gin.New: request logging is done by AccessLog, panics by Errors
+	r := gin.New()
+	if cfg.Server.TrustedProxies != "" {
+		if err := r.SetTrustedProxies(cfg.Server.Proxies()); err != nil {
+			logging.Fatal("invalid trusted proxies", "error", err)
+		}
+	}
+	r.Use(tracing.Middleware(cfg.Tracing.ServiceName), middleware.RequestID(), middleware.AccessLog(), middleware.Metrics(), middleware.Errors())
 	routes.RegisterRoutes(r)
 
-	r.Run(":8080")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := serve(ctx, cfg.Server, r); err != nil {
+		logging.Fatal("server error", "error", err)
+	}
+
+	// running jobs see their context cancelled; one that doesn't stop in time
+	// is abandoned and its lease expires, so another replica retries it
+	stopBackground()
+	if !waitFor(services.Jobs.Wait, cfg.Server.ShutdownTimeout) {
+		slog.Warn("background jobs still running at the shutdown deadline", "timeout", cfg.Server.ShutdownTimeout)
+	}
+
+	fctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := flushTraces(fctx); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+// waitFor runs wait and reports whether it returned within timeout.
+func waitFor(wait func(), timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-done:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// serve runs the HTTP server until ctx is cancelled, then stops accepting
+// connections and waits up to ShutdownTimeout for in-flight requests, such as
+// pack downloads, before closing whatever is left.
+func serve(ctx context.Context, cfg config.ServerConfig, h http.Handler) error {
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           h,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		if cfg.TLSCertFile != "" {
+			slog.Info("listening", "addr", cfg.Addr, "tls", true)
+			errc <- srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			slog.Info("listening", "addr", cfg.Addr, "tls", false)
+			errc <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	// keep serving while /readyz reports the shutdown, so load balancers
+	// take this instance out before the listener closes
+	services.BeginShutdown()
+	if cfg.ShutdownDelay > 0 {
+		slog.Info("draining before shutdown", "delay", cfg.ShutdownDelay)
+		time.Sleep(cfg.ShutdownDelay)
+	}
+	slog.Info("shutting down", "timeout", cfg.ShutdownTimeout, "active_transfers", middleware.ActiveStreams())
+	sctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(sctx); err != nil {
+		slog.Warn("shutdown deadline passed, closing connections", "active_transfers", middleware.ActiveStreams())
+		_ = srv.Close()
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
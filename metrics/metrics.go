@@ -0,0 +1,108 @@
+// Package metrics defines the Prometheus collectors exposed on /metrics.
+// Collectors live on the default registry, next to the Go runtime and
+// process collectors that client_golang registers there.
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "open_illustrations"
+
+var (
+	// HTTPRequests counts requests by method, route template and status code.
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration is the time to serve a request, including streamed bodies.
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route"})
+
+	// HTTPPanics counts handler panics by route template, including those
+	// recovered after the response had started.
+	HTTPPanics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_panics_total",
+		Help:      "Handler panics recovered by route.",
+	}, []string{"route"})
+
+	// StreamedBytes counts file bytes sent by the streaming endpoints:
+	// public, signed and pack.
+	StreamedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "streamed_bytes_total",
+		Help:      "Bytes of assets and archives streamed to clients, by endpoint.",
+	}, []string{"endpoint"})
+
+	// StorageDuration is the latency of MinIO requests up to the response
+	// headers; object bodies are read afterwards.
+	StorageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "MinIO request latency by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// StorageErrors counts failed MinIO requests. "Not found" answers are
+	// expected (existence checks) and not counted.
+	StorageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_errors_total",
+		Help:      "Failed MinIO requests by operation.",
+	}, []string{"operation"})
+
+	// TokensIssued counts signed asset tokens by URL context.
+	TokensIssued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "asset_tokens_issued_total",
+		Help:      "Signed asset tokens issued, by URL context.",
+	}, []string{"context"})
+
+	// TokenValidations counts token checks on /i/:token by outcome: ok,
+	// expired, signature_mismatch, unknown_key, malformed, wrong_client,
+	// wrong_subject, revoked, replayed or error.
+	TokenValidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "asset_token_validations_total",
+		Help:      "Signed asset token validations by result.",
+	}, []string{"result"})
+
+	// UploadSize is the size of accepted uploads by source: multipart, tus or import.
+	UploadSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_size_bytes",
+		Help:      "Size of accepted uploads by source.",
+		Buckets:   prometheus.ExponentialBuckets(1024, 4, 10), // 1 KiB .. 256 MiB
+	}, []string{"source"})
+
+	// RateLimited counts requests rejected by a rate-limit policy (stream or
+	// download) or by the pack download concurrency cap (pack_concurrency).
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by rate limiting, by policy.",
+	}, []string{"policy"})
+
+	// PackDownloadsInFlight is the number of pack archives being streamed.
+	PackDownloadsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pack_downloads_in_flight",
+		Help:      "Pack archive downloads currently being served.",
+	})
+)
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
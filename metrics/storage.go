@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// StorageTransport wraps the MinIO client's transport so every S3 request is
+// timed and failures are counted, whichever service made it.
+func StorageTransport(next http.RoundTripper) http.RoundTripper {
+	return storageTransport{next}
+}
+
+type storageTransport struct {
+	next http.RoundTripper
+}
+
+func (t storageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	op := StorageOperation(req)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	StorageDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil || (resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
+		StorageErrors.WithLabelValues(op).Inc()
+	}
+	return resp, err
+}
+
+// StorageOperation names the S3 call from its method and query.
+func StorageOperation(req *http.Request) string {
+	q := req.URL.Query()
+	switch req.Method {
+	case http.MethodHead:
+		return "stat"
+	case http.MethodGet:
+		if q.Has("list-type") || q.Has("prefix") || q.Has("delimiter") {
+			return "list"
+		}
+		if q.Has("location") {
+			return "location"
+		}
+		return "get"
+	case http.MethodPut:
+		if q.Has("partNumber") {
+			return "put_part"
+		}
+		return "put"
+	case http.MethodDelete:
+		return "remove"
+	case http.MethodPost:
+		if q.Has("delete") {
+			return "remove"
+		}
+		return "multipart"
+	}
+	return "other"
+}
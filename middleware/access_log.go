@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"open-illustrations-go/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quiet routes are polled by probes and scrapers; they are logged at debug.
+var quiet = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// AccessLog logs one line per request once it completes. It replaces gin's
+// text logger and must run after RequestID so the line carries the ID.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		switch status := c.Writer.Status(); {
+		case quiet[c.FullPath()] && status < 500:
+			level = slog.LevelDebug
+		case status >= 500:
+			level = slog.LevelError
+		}
+		bytes := c.Writer.Size()
+		if bytes < 0 {
+			bytes = 0
+		}
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).Log(ctx, level, "request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"bytes", bytes,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
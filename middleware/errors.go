@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/logging"
+	"open-illustrations-go/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Errors renders the last error recorded with apierror.Abort (or c.Error) if
+// the handler did not write a response itself, and turns panics into a 500.
+// A panic after the response has started can only be logged and counted.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			route := c.FullPath()
+			if route == "" {
+				route = "unmatched"
+			}
+			metrics.HTTPPanics.WithLabelValues(route).Inc()
+			err := fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+			if c.Writer.Written() {
+				logging.FromContext(c.Request.Context()).Error("panic after the response was written",
+					"status", c.Writer.Status(), "error", err)
+				c.Abort()
+				return
+			}
+			apierror.Write(c, err)
+		}()
+		c.Next()
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			apierror.Write(c, c.Errors.Last().Err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Package middleware holds the gin middleware shared by all routes.
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is read from incoming requests and echoed on every response.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID keeps the caller's X-Request-ID (if it looks sane) or generates one.
+// The request context gets a logger tagged with the ID, and with the trace ID
+// when the request is traced; see logging.FromContext.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			b := make([]byte, 8)
+			_, _ = rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		c.Set(apierror.RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		ctx := c.Request.Context()
+		log := logging.FromContext(ctx).With("request_id", id)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			log = log.With("trace_id", sc.TraceID().String())
+		}
+		c.Request = c.Request.WithContext(logging.WithLogger(ctx, log))
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var activeStreams atomic.Int64
+
+// ActiveStreams is the number of Streaming requests in progress.
+func ActiveStreams() int64 {
+	return activeStreams.Load()
+}
+
+// Streaming marks routes that move large bodies (pack ZIPs, asset streams,
+// uploads). It lifts the server's read and write timeouts for the request, so
+// a slow client isn't cut off mid-file, and counts the request so shutdown can
+// report what it is waiting for.
+func Streaming() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rc := http.NewResponseController(c.Writer)
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		activeStreams.Add(1)
+		defer activeStreams.Add(-1)
+		c.Next()
+	}
+}
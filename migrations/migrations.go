@@ -0,0 +1,51 @@
+// Package migrations versions the database schema. Migrations run in order,
+// each inside a transaction where the database allows it, and the applied
+// versions are recorded in schema_migrations. A row in schema_migration_lock
+// keeps replicas that start together from migrating at the same time.
+//
+// To change the schema, append a Migration to All with the next version.
+// Never edit a migration that has shipped, and never reference the models
+// package from one: models change, a shipped migration must not. Version 1 is
+// the schema AutoMigrate used to create, so existing databases adopt it
+// without changes; its tables are snapshotted in v1_baseline.go.
+package migrations
+
+import "gorm.io/gorm"
+
+// Migration is one schema step. Down undoes Up; it may be nil for steps that
+// can't be reversed.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// baselineVersion is the migration that creates every table.
+const baselineVersion = 1
+
+// All lists every migration in version order.
+var All = []Migration{
+	{
+		Version: baselineVersion,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(baselineTables()...)
+		},
+		Down: func(tx *gorm.DB) error {
+			tables := baselineTables()
+			for i := len(tables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(tables[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 2,
+		Name:    "signing_key_active_slot",
+		Up:      signingKeySlotUp,
+		Down:    signingKeySlotDown,
+	},
+}
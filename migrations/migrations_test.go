@@ -0,0 +1,157 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"open-illustrations-go/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	name := strings.ReplaceAll(t.Name(), "/", "_")
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared&_pragma=foreign_keys(1)"),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+var allModels = []interface{}{
+	&models.Category{}, &models.Pack{}, &models.Style{}, &models.Illustration{},
+	&models.Upload{}, &models.UploadChunk{},
+	&models.ImportJob{}, &models.ImportItem{},
+	&models.Job{}, &models.PackArchive{},
+	&models.SigningKey{}, &models.UsedNonce{}, &models.TokenRevocation{},
+}
+
+// TestMigrationsCoverModels catches a model change without a migration: after
+// Up, every column a model maps must exist.
+func TestMigrationsCoverModels(t *testing.T) {
+	db := testDB(t)
+	if _, err := Up(context.Background(), db, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range allModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			t.Fatal(err)
+		}
+		if !db.Migrator().HasTable(stmt.Schema.Table) {
+			t.Errorf("table %s is missing", stmt.Schema.Table)
+			continue
+		}
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName != "" && !db.Migrator().HasColumn(m, f.DBName) {
+				t.Errorf("column %s.%s is missing", stmt.Schema.Table, f.DBName)
+			}
+		}
+	}
+}
+
+func TestDownRefusesBaselineUnlessAsked(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	if _, err := Up(ctx, db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ran, err := Down(ctx, db, len(All), false)
+	if !errors.Is(err, ErrBaselineRevert) {
+		t.Fatalf("Down without revertBaseline: %v, want ErrBaselineRevert", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("reverted %d migrations before refusing", len(ran))
+	}
+	if lvl, err := CurrentLevel(ctx, db); err != nil || lvl.Pending != 0 {
+		t.Errorf("level after refusal = %+v, %v; want nothing pending", lvl, err)
+	}
+
+	if _, err := Down(ctx, db, len(All), true); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"illustrations", "categories", "jobs", "token_revocations"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s survived reverting the baseline", table)
+		}
+	}
+	if pending, err := Pending(db); err != nil || pending != len(All) {
+		t.Errorf("pending after revert = %d, %v; want %d", pending, err, len(All))
+	}
+}
+
+func TestSigningKeySlotKeepsTheNewestActiveKey(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	if _, err := Up(ctx, db, baselineVersion); err != nil {
+		t.Fatal(err)
+	}
+	// two replicas both created the first key
+	for _, kid := range []string{"first", "second"} {
+		if err := db.Exec("INSERT INTO signing_keys (kid, secret, status) VALUES (?, 'c2VjcmV0', 'active')", kid).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Up(ctx, db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []models.SigningKey
+	if err := db.Order("id").Find(&keys).Error; err != nil {
+		t.Fatal(err)
+	}
+	if keys[0].Status != models.SigningKeyVerify || keys[0].VerifyUntil == nil || keys[0].ActiveSlot != nil {
+		t.Errorf("older key = %+v, want it demoted", keys[0])
+	}
+	if keys[1].Status != models.SigningKeyActive || keys[1].ActiveSlot == nil || *keys[1].ActiveSlot != 1 {
+		t.Errorf("newer key = %+v, want it active in slot 1", keys[1])
+	}
+	if err := db.Exec("INSERT INTO signing_keys (kid, secret, status, active_slot) VALUES ('third', 'c2VjcmV0', 'active', 1)").Error; err == nil {
+		t.Error("a second key took the active slot")
+	}
+}
+
+func TestLockIsRenewedWhileHeld(t *testing.T) {
+	db := testDB(t)
+	prev := lockHeartbeat
+	lockHeartbeat = 10 * time.Millisecond
+	t.Cleanup(func() { lockHeartbeat = prev })
+
+	lockedAt := func() time.Time {
+		var l schemaMigrationLock
+		if err := db.First(&l, 1).Error; err != nil {
+			t.Fatal(err)
+		}
+		return l.LockedAt
+	}
+	err := withLock(context.Background(), db, func() error {
+		taken := lockedAt()
+		time.Sleep(50 * time.Millisecond)
+		if renewed := lockedAt(); !renewed.After(taken) {
+			t.Errorf("lock taken at %v still dated %v", taken, renewed)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	db.Model(&schemaMigrationLock{}).Count(&n)
+	if n != 0 {
+		t.Error("lock not released")
+	}
+}
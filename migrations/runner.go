@@ -0,0 +1,284 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SchemaMigration records one applied migration.
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	AppliedAt time.Time `gorm:"not null" json:"applied_at"`
+}
+
+// schemaMigrationLock has at most one row; whoever inserted it may migrate.
+type schemaMigrationLock struct {
+	ID       uint      `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string    `gorm:"size:100;not null"`
+	LockedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigrationLock) TableName() string { return "schema_migration_lock" }
+
+var (
+	ErrUnknownVersion = errors.New("unknown migration version")
+	// ErrBaselineRevert stops Down from dropping every table by accident.
+	ErrBaselineRevert = errors.New("reverting the baseline drops every table")
+)
+
+// lockStaleAfter frees a lock left behind by a process that died mid-migration.
+// The holder renews the lock every lockHeartbeat, so a long migration never
+// looks stale.
+const lockStaleAfter = 10 * time.Minute
+
+var lockHeartbeat = lockStaleAfter / 5
+
+// Status is a migration and whether it has been applied.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+func ensureTables(db *gorm.DB) error {
+	return db.AutoMigrate(&SchemaMigration{}, &schemaMigrationLock{})
+}
+
+func applied(db *gorm.DB) (map[int]SchemaMigration, error) {
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[int]SchemaMigration, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+// List reports every known migration, oldest first.
+func List(db *gorm.DB) ([]Status, error) {
+	if err := ensureTables(db); err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(All))
+	for _, m := range sorted() {
+		s := Status{Version: m.Version, Name: m.Name}
+		if r, ok := done[m.Version]; ok {
+			s.AppliedAt = &r.AppliedAt
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// Pending returns how many migrations have not been applied yet.
+func Pending(db *gorm.DB) (int, error) {
+	list, err := List(db)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range list {
+		if s.AppliedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Level summarises the schema version of a database.
+type Level struct {
+	Current int `json:"current"` // highest applied version
+	Latest  int `json:"latest"`  // highest version this binary knows
+	Pending int `json:"pending"`
+}
+
+// CurrentLevel reads the schema level without creating the bookkeeping tables,
+// so it is cheap enough for health checks. A database that has never been
+// migrated returns an error.
+func CurrentLevel(ctx context.Context, db *gorm.DB) (Level, error) {
+	done, err := applied(db.WithContext(ctx))
+	if err != nil {
+		return Level{}, err
+	}
+	var lvl Level
+	for _, m := range sorted() {
+		lvl.Latest = m.Version
+		if _, ok := done[m.Version]; ok {
+			lvl.Current = max(lvl.Current, m.Version)
+		} else {
+			lvl.Pending++
+		}
+	}
+	return lvl, nil
+}
+
+// Up applies pending migrations up to and including target (0 = all).
+func Up(ctx context.Context, db *gorm.DB, target int) ([]Migration, error) {
+	if target != 0 && find(target) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	var ran []Migration
+	err := withLock(ctx, db, func() error {
+		done, err := applied(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range sorted() {
+			if target != 0 && m.Version > target {
+				break
+			}
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			slog.Info("migration applied", "version", m.Version, "name", m.Name)
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+			}
+			ran = append(ran, m)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts the last steps applied migrations, newest first. Reverting
+// the baseline (version 1) deletes all data, so it is refused with
+// ErrBaselineRevert unless revertBaseline is set; nothing is reverted then.
+func Down(ctx context.Context, db *gorm.DB, steps int, revertBaseline bool) ([]Migration, error) {
+	var ran []Migration
+	err := withLock(ctx, db, func() error {
+		done, err := applied(db)
+		if err != nil {
+			return err
+		}
+		var plan []Migration
+		list := sorted()
+		for i := len(list) - 1; i >= 0 && len(plan) < steps; i-- {
+			m := list[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if m.Down == nil {
+				return fmt.Errorf("migration %d %s cannot be reverted", m.Version, m.Name)
+			}
+			if m.Version == baselineVersion && !revertBaseline {
+				return ErrBaselineRevert
+			}
+			plan = append(plan, m)
+		}
+		for _, m := range plan {
+			slog.Info("migration reverted", "version", m.Version, "name", m.Name)
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, m.Version).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+			}
+			ran = append(ran, m)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// withLock runs fn while holding the migration lock, waiting for another
+// process to release it until ctx is done.
+func withLock(ctx context.Context, db *gorm.DB, fn func() error) error {
+	if err := ensureTables(db); err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", host, os.Getpid())
+	for {
+		err := db.Create(&schemaMigrationLock{ID: 1, Owner: owner, LockedAt: time.Now()}).Error
+		if err == nil {
+			break
+		}
+		// the row exists: someone else is migrating, or died doing so
+		res := db.Where("id = 1 AND locked_at < ?", time.Now().Add(-lockStaleAfter)).Delete(&schemaMigrationLock{})
+		if res.Error == nil && res.RowsAffected > 0 {
+			slog.Warn("removed a stale migration lock")
+			continue
+		}
+		slog.Info("waiting for the migration lock")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("migration lock: %w", ctx.Err())
+		case <-time.After(2 * time.Second):
+		}
+	}
+	stop := keepLock(db, owner)
+	defer func() {
+		stop()
+		if err := db.Where("id = 1 AND owner = ?", owner).Delete(&schemaMigrationLock{}).Error; err != nil {
+			slog.Error("release migration lock failed", "error", err)
+		}
+	}()
+	return fn()
+}
+
+// keepLock renews the lock held by owner every lockHeartbeat until the returned
+// stop function is called.
+func keepLock(db *gorm.DB, owner string) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		t := time.NewTicker(lockHeartbeat)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			res := db.Model(&schemaMigrationLock{}).Where("id = 1 AND owner = ?", owner).Update("locked_at", time.Now())
+			switch {
+			case res.Error != nil:
+				slog.Error("renew migration lock failed", "error", res.Error)
+			case res.RowsAffected == 0:
+				slog.Error("migration lock lost to another process")
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+func sorted() []Migration {
+	list := append([]Migration(nil), All...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+func find(version int) *Migration {
+	for i := range All {
+		if All[i].Version == version {
+			return &All[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The tables of migration 1 as the models defined them when it shipped.
+// Field names and tags are copied verbatim: column, index and constraint names
+// are derived from them. Do not change these to follow the models.
+
+type v1Illustration struct {
+	ID         uint   `gorm:"primaryKey"`
+	Title      string `gorm:"size:200;not null"`
+	StyleID    *uint  `gorm:"column:style_id;index"`
+	CategoryID *uint  `gorm:"column:category_id;index"`
+	PackID     *uint  `gorm:"column:pack_id;index"`
+	FileName   string `gorm:"size:191;not null"`
+	StorageKey string `gorm:"size:191;not null;uniqueIndex"`
+	IsPremium  bool   `gorm:"index"`
+	Tags       string `gorm:"size:500"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+
+	CategoryRef *v1Category `gorm:"foreignKey:CategoryID"`
+	PackRef     *v1Pack     `gorm:"foreignKey:PackID"`
+	StyleRef    *v1Style    `gorm:"foreignKey:StyleID"`
+}
+
+func (v1Illustration) TableName() string { return "illustrations" }
+
+type v1Category struct {
+	ID            uint   `gorm:"primaryKey"`
+	Name          string `gorm:"size:100;not null;uniqueIndex"`
+	Slug          string `gorm:"size:120;uniqueIndex"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt   `gorm:"index"`
+	Illustrations []v1Illustration `gorm:"foreignKey:CategoryID"`
+}
+
+func (v1Category) TableName() string { return "categories" }
+
+type v1Pack struct {
+	ID            uint   `gorm:"primaryKey"`
+	Name          string `gorm:"size:100;not null;uniqueIndex"`
+	Slug          string `gorm:"size:120;uniqueIndex"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt   `gorm:"index"`
+	Illustrations []v1Illustration `gorm:"foreignKey:PackID"`
+}
+
+func (v1Pack) TableName() string { return "packs" }
+
+type v1Style struct {
+	ID           uint   `gorm:"primaryKey"`
+	Name         string `gorm:"size:100;not null;uniqueIndex"`
+	Slug         string `gorm:"size:120;uniqueIndex"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt   `gorm:"index"`
+	Illustration []v1Illustration `gorm:"foreignKey:StyleID"`
+}
+
+func (v1Style) TableName() string { return "styles" }
+
+type v1Upload struct {
+	ID             string `gorm:"primaryKey;size:64"`
+	Length         int64  `gorm:"not null"`
+	Offset         int64  `gorm:"column:upload_offset;not null;default:0"`
+	Metadata       string `gorm:"type:text"`
+	Status         string `gorm:"size:20;not null;index"`
+	Error          string `gorm:"size:500"`
+	IllustrationID *uint
+	ExpiresAt      time.Time `gorm:"index"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (v1Upload) TableName() string { return "uploads" }
+
+type v1UploadChunk struct {
+	ID         uint   `gorm:"primaryKey"`
+	UploadID   string `gorm:"size:64;not null;uniqueIndex:idx_upload_chunk_offset"`
+	Offset     int64  `gorm:"column:chunk_offset;not null;uniqueIndex:idx_upload_chunk_offset"`
+	Size       int64  `gorm:"not null"`
+	StorageKey string `gorm:"size:191;not null"`
+	CreatedAt  time.Time
+}
+
+func (v1UploadChunk) TableName() string { return "upload_chunks" }
+
+type v1ImportJob struct {
+	ID         uint   `gorm:"primaryKey"`
+	Status     string `gorm:"size:20;not null;index"`
+	JobID      *uint
+	ArchiveKey string `gorm:"size:191;not null"`
+	Total      int
+	Succeeded  int
+	Failed     int
+	Error      string `gorm:"size:500"`
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Items      []v1ImportItem `gorm:"foreignKey:ImportJobID"`
+}
+
+func (v1ImportJob) TableName() string { return "import_jobs" }
+
+type v1ImportItem struct {
+	ID             uint   `gorm:"primaryKey"`
+	ImportJobID    uint   `gorm:"not null;index"`
+	Path           string `gorm:"size:500;not null"`
+	Title          string `gorm:"size:200"`
+	Category       string `gorm:"size:100"`
+	Style          string `gorm:"size:100"`
+	Pack           string `gorm:"size:100"`
+	Tags           string `gorm:"size:500"`
+	IsPremium      bool
+	Status         string `gorm:"size:20;not null"`
+	Error          string `gorm:"size:500"`
+	IllustrationID *uint
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (v1ImportItem) TableName() string { return "import_items" }
+
+type v1Job struct {
+	ID          uint      `gorm:"primaryKey"`
+	Type        string    `gorm:"size:64;not null;index"`
+	UniqueKey   string    `gorm:"size:191;index"`
+	Payload     string    `gorm:"type:text"`
+	Status      string    `gorm:"size:20;not null;index:idx_job_status_run_at"`
+	Attempts    int       `gorm:"not null;default:0"`
+	MaxAttempts int       `gorm:"not null;default:5"`
+	RunAt       time.Time `gorm:"index:idx_job_status_run_at"`
+	LockedBy    string    `gorm:"size:100"`
+	LockedAt    *time.Time
+	LastError   string `gorm:"size:1000"`
+	Result      string `gorm:"type:text"`
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (v1Job) TableName() string { return "jobs" }
+
+type v1PackArchive struct {
+	ID          uint   `gorm:"primaryKey"`
+	PackID      uint   `gorm:"not null;index:idx_pack_archive_lookup"`
+	Variant     string `gorm:"size:64;not null;index:idx_pack_archive_lookup"`
+	SourceHash  string `gorm:"size:64;not null;index:idx_pack_archive_lookup"`
+	ContentHash string `gorm:"size:64"`
+	StorageKey  string `gorm:"size:191"`
+	Size        int64
+	FileCount   int
+	Status      string `gorm:"size:20;not null"`
+	Error       string `gorm:"type:text"`
+	BuiltAt     *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (v1PackArchive) TableName() string { return "pack_archives" }
+
+type v1SigningKey struct {
+	ID          uint   `gorm:"primaryKey"`
+	KID         string `gorm:"column:kid;size:32;not null;uniqueIndex"`
+	Secret      string `gorm:"size:128;not null"`
+	Status      string `gorm:"size:20;not null;index"`
+	VerifyUntil *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (v1SigningKey) TableName() string { return "signing_keys" }
+
+type v1UsedNonce struct {
+	ID        uint      `gorm:"primaryKey"`
+	Nonce     string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (v1UsedNonce) TableName() string { return "used_nonces" }
+
+type v1TokenRevocation struct {
+	ID         uint      `gorm:"primaryKey"`
+	Kind       string    `gorm:"size:20;not null;index"`
+	Nonce      string    `gorm:"size:64;index"`
+	StorageKey string    `gorm:"size:191;index"`
+	RevokedAt  time.Time `gorm:"not null"`
+	Reason     string    `gorm:"size:255"`
+	CreatedAt  time.Time
+}
+
+func (v1TokenRevocation) TableName() string { return "token_revocations" }
+
+// baselineTables are created parents first.
+func baselineTables() []interface{} {
+	return []interface{}{
+		&v1Category{}, &v1Pack{}, &v1Style{}, &v1Illustration{},
+		&v1Upload{}, &v1UploadChunk{},
+		&v1ImportJob{}, &v1ImportItem{},
+		&v1Job{}, &v1PackArchive{},
+		&v1SigningKey{}, &v1UsedNonce{}, &v1TokenRevocation{},
+	}
+}
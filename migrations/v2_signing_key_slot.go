@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// v2SigningKey adds active_slot to signing_keys: 1 on the active key, NULL on
+// the others. NULLs never collide in a unique index, so the index lets at most
+// one key be active, even when two replicas create the first one together.
+type v2SigningKey struct {
+	ActiveSlot *int `gorm:"uniqueIndex"`
+}
+
+func (v2SigningKey) TableName() string { return "signing_keys" }
+
+// v2Retention is how long keys demoted by the backfill keep verifying: the
+// default ASSET_KEY_RETENTION_HOURS when this migration shipped.
+const v2Retention = 24 * time.Hour
+
+func signingKeySlotUp(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.AddColumn(&v2SigningKey{}, "ActiveSlot"); err != nil {
+		return err
+	}
+	// a database that already raced keeps its newest active key
+	var newest []uint
+	if err := tx.Table("signing_keys").Where("status = ?", "active").Order("id DESC").Limit(1).Pluck("id", &newest).Error; err != nil {
+		return err
+	}
+	if len(newest) == 1 {
+		if err := tx.Table("signing_keys").Where("status = ? AND id <> ?", "active", newest[0]).
+			Updates(map[string]interface{}{"status": "verify", "verify_until": time.Now().Add(v2Retention)}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("signing_keys").Where("id = ?", newest[0]).Update("active_slot", 1).Error; err != nil {
+			return err
+		}
+	}
+	return m.CreateIndex(&v2SigningKey{}, "ActiveSlot")
+}
+
+func signingKeySlotDown(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.DropIndex(&v2SigningKey{}, "ActiveSlot"); err != nil {
+		return err
+	}
+	return m.DropColumn(&v2SigningKey{}, "ActiveSlot")
+}
@@ -15,6 +15,7 @@ type Illustration struct {
 	FileName   string         `gorm:"size:191;not null" json:"file_name"`
 	StorageKey string         `gorm:"size:191;not null;uniqueIndex" json:"storage_key"`
 	IsPremium  bool           `gorm:"index" json:"is_premium"`
+	Tags       string         `gorm:"size:500" json:"tags,omitempty"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
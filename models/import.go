@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// ImportJob is a bulk import of illustrations from a ZIP archive.
+type ImportJob struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	Status     string       `gorm:"size:20;not null;index" json:"status"`
+	JobID      *uint        `json:"job_id,omitempty"`
+	ArchiveKey string       `gorm:"size:191;not null" json:"-"`
+	Total      int          `json:"total"`
+	Succeeded  int          `json:"succeeded"`
+	Failed     int          `json:"failed"`
+	Error      string       `gorm:"size:500" json:"error,omitempty"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+	Items      []ImportItem `gorm:"foreignKey:ImportJobID" json:"items,omitempty"`
+}
+
+// ImportItem is the per-file result of an import.
+type ImportItem struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ImportJobID    uint      `gorm:"not null;index" json:"import_job_id"`
+	Path           string    `gorm:"size:500;not null" json:"path"`
+	Title          string    `gorm:"size:200" json:"title"`
+	Category       string    `gorm:"size:100" json:"category,omitempty"`
+	Style          string    `gorm:"size:100" json:"style,omitempty"`
+	Pack           string    `gorm:"size:100" json:"pack,omitempty"`
+	Tags           string    `gorm:"size:500" json:"tags,omitempty"`
+	IsPremium      bool      `json:"is_premium"`
+	Status         string    `gorm:"size:20;not null" json:"status"`
+	Error          string    `gorm:"size:500" json:"error,omitempty"`
+	IllustrationID *uint     `json:"illustration_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+const (
+	ImportStatusQueued    = "queued"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+
+	ImportItemPending  = "pending"
+	ImportItemImported = "imported"
+	ImportItemFailed   = "failed"
+)
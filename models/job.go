@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Job is a unit of background work processed by the job queue (see services.JobQueue).
+type Job struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Type        string     `gorm:"size:64;not null;index" json:"type"`
+	UniqueKey   string     `gorm:"size:191;index" json:"unique_key,omitempty"`
+	Payload     string     `gorm:"type:text" json:"payload"`
+	Status      string     `gorm:"size:20;not null;index:idx_job_status_run_at" json:"status"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"not null;default:5" json:"max_attempts"`
+	RunAt       time.Time  `gorm:"index:idx_job_status_run_at" json:"run_at"`
+	LockedBy    string     `gorm:"size:100" json:"locked_by,omitempty"`
+	LockedAt    *time.Time `json:"locked_at,omitempty"`
+	LastError   string     `gorm:"size:1000" json:"last_error,omitempty"`
+	Result      string     `gorm:"type:text" json:"result,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// PackArchive is a prebuilt ZIP of a pack's illustrations stored in the bucket.
+// SourceHash fingerprints the pack membership it was built from, so a change in
+// membership makes the archive stale; ContentHash is the SHA-256 of the ZIP itself.
+type PackArchive struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	PackID      uint       `gorm:"not null;index:idx_pack_archive_lookup" json:"pack_id"`
+	Variant     string     `gorm:"size:64;not null;index:idx_pack_archive_lookup" json:"variant"`
+	SourceHash  string     `gorm:"size:64;not null;index:idx_pack_archive_lookup" json:"source_hash"`
+	ContentHash string     `gorm:"size:64" json:"content_hash,omitempty"`
+	StorageKey  string     `gorm:"size:191" json:"-"`
+	Size        int64      `json:"size"`
+	FileCount   int        `json:"file_count"`
+	Status      string     `gorm:"size:20;not null" json:"status"`
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	BuiltAt     *time.Time `json:"built_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+const (
+	PackArchiveReady      = "ready"
+	PackArchiveIncomplete = "incomplete"
+)
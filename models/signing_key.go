@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// SigningKey is an HMAC key for asset tokens. Exactly one key is "active" (used to
+// sign); "verify" keys are kept until VerifyUntil so tokens minted before a rotation
+// stay valid. ActiveSlot is 1 on the active key and nil on the others; its unique
+// index keeps a second key from becoming active.
+type SigningKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	KID         string     `gorm:"column:kid;size:32;not null;uniqueIndex" json:"kid"`
+	Secret      string     `gorm:"size:128;not null" json:"-"`
+	Status      string     `gorm:"size:20;not null;index" json:"status"`
+	VerifyUntil *time.Time `json:"verify_until,omitempty"`
+	ActiveSlot  *int       `gorm:"uniqueIndex" json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+const (
+	SigningKeyActive = "active"
+	SigningKeyVerify = "verify"
+)
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// TokenRevocation invalidates signed asset tokens before they expire. Exactly one
+// of Nonce, StorageKey or a global cutoff applies, depending on Kind:
+//
+//	nonce          the single token carrying that nonce
+//	storage_key    every token for StorageKey issued at or before RevokedAt
+//	issued_before  every token issued at or before RevokedAt
+type TokenRevocation struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Kind       string    `gorm:"size:20;not null;index" json:"kind"`
+	Nonce      string    `gorm:"size:64;index" json:"nonce,omitempty"`
+	StorageKey string    `gorm:"size:191;index" json:"storage_key,omitempty"`
+	RevokedAt  time.Time `gorm:"not null" json:"revoked_at"`
+	Reason     string    `gorm:"size:255" json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+const (
+	RevokeNonce        = "nonce"
+	RevokeStorageKey   = "storage_key"
+	RevokeIssuedBefore = "issued_before"
+)
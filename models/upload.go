@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Upload tracks a resumable (tus) upload so it can be continued after a client
+// disconnect or a server restart. Received bytes live in MinIO as UploadChunk objects.
+type Upload struct {
+	ID             string    `gorm:"primaryKey;size:64" json:"id"`
+	Length         int64     `gorm:"not null" json:"length"`
+	Offset         int64     `gorm:"column:upload_offset;not null;default:0" json:"offset"`
+	Metadata       string    `gorm:"type:text" json:"metadata"`
+	Status         string    `gorm:"size:20;not null;index" json:"status"`
+	Error          string    `gorm:"size:500" json:"error,omitempty"`
+	IllustrationID *uint     `json:"illustration_id,omitempty"`
+	ExpiresAt      time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// UploadChunk is one PATCH worth of bytes stored under StorageKey.
+type UploadChunk struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UploadID   string    `gorm:"size:64;not null;uniqueIndex:idx_upload_chunk_offset" json:"upload_id"`
+	Offset     int64     `gorm:"column:chunk_offset;not null;uniqueIndex:idx_upload_chunk_offset" json:"offset"`
+	Size       int64     `gorm:"not null" json:"size"`
+	StorageKey string    `gorm:"size:191;not null" json:"storage_key"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+const (
+	UploadStatusPending   = "pending"
+	UploadStatusCompleted = "completed"
+	UploadStatusFailed    = "failed"
+)
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// UsedNonce records a single-use asset token that has already been redeemed.
+// Rows can be deleted once ExpiresAt has passed, since the token is dead anyway.
+type UsedNonce struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Nonce     string    `gorm:"size:64;not null;uniqueIndex" json:"nonce"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,47 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// JSONHandler serves the rendered document; it is rendered once, up front.
+func (s *Spec) JSONHandler() gin.HandlerFunc {
+	doc := s.Document()
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// DocsHandler serves a Swagger UI page that loads openapi.json from the same
+// directory and the DocsAssets from below itself.
+func DocsHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
+
+// DocsAssets are the Swagger UI files docs.html needs. They are embedded in
+// the binary, so the docs page works offline and without a CDN.
+var DocsAssets = []string{"swagger-ui.css", "swagger-ui-bundle.js"}
+
+// DocsAsset serves one of DocsAssets.
+func DocsAsset(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.FileFromFS(name, http.FS(swaggerFiles.FS))
+	}
+}
+
+// Routes adapts gin's route list for Missing.
+func Routes(info gin.RoutesInfo) []RouteInfo {
+	out := make([]RouteInfo, 0, len(info))
+	for _, r := range info {
+		out = append(out, RouteInfo{Method: r.Method, Path: r.Path})
+	}
+	return out
+}
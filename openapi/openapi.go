@@ -0,0 +1,218 @@
+// Package openapi builds an OpenAPI 3 document from a table of operations and
+// Go types, and checks it against the routes gin actually serves.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a JSON Schema object as used by OpenAPI.
+type Schema = map[string]interface{}
+
+// Param is a path, query or header parameter.
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      Schema
+}
+
+// Body is a request body.
+type Body struct {
+	ContentType string
+	Schema      Schema
+}
+
+// Response is one documented response; an empty ContentType means no body.
+type Response struct {
+	Description string
+	ContentType string
+	Schema      Schema
+	Headers     []string
+}
+
+// Operation documents one route. Path uses gin syntax (/packs/:id); path
+// parameters are added automatically.
+type Operation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tag         string
+	Params      []Param
+	Request     *Body
+	Responses   map[int]Response
+	// Admin marks routes that need the admin bearer token.
+	Admin bool
+}
+
+// Spec collects operations and component schemas.
+type Spec struct {
+	Title   string
+	Version string
+	Info    string
+
+	ops        []Operation
+	components map[string]reflect.Type
+	names      map[string]string // Go type -> component name
+}
+
+func New(title, version, info string) *Spec {
+	return &Spec{Title: title, Version: version, Info: info, components: map[string]reflect.Type{}, names: map[string]string{}}
+}
+
+// Add appends operations.
+func (s *Spec) Add(ops ...Operation) {
+	s.ops = append(s.ops, ops...)
+}
+
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z_]+)`)
+
+func openAPIPath(p string) string {
+	return ginParam.ReplaceAllString(p, "{$1}")
+}
+
+// Document renders the OpenAPI 3 document.
+func (s *Spec) Document() map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	for _, op := range s.ops {
+		p := openAPIPath(op.Path)
+		if paths[p] == nil {
+			paths[p] = map[string]interface{}{}
+		}
+		paths[p][strings.ToLower(op.Method)] = s.operation(op)
+	}
+	schemas := map[string]Schema{}
+	for name, t := range s.components {
+		schemas[name] = s.schemaOf(t, true)
+	}
+	components := map[string]interface{}{
+		"schemas": schemas,
+		"securitySchemes": map[string]interface{}{
+			"adminToken": map[string]interface{}{"type": "http", "scheme": "bearer", "description": "ADMIN_API_TOKEN"},
+			"apiKey":     map[string]interface{}{"type": "http", "scheme": "bearer", "description": "A key from API_KEYS; sets the caller's subject and tier"},
+		},
+	}
+	return map[string]interface{}{
+		"openapi":    "3.0.3",
+		"info":       map[string]interface{}{"title": s.Title, "version": s.Version, "description": s.Info},
+		"paths":      paths,
+		"components": components,
+	}
+}
+
+func (s *Spec) operation(op Operation) map[string]interface{} {
+	var params []interface{}
+	for _, m := range ginParam.FindAllStringSubmatch(op.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name": m[1], "in": "path", "required": true, "schema": String(),
+		})
+	}
+	for _, p := range op.Params {
+		params = append(params, map[string]interface{}{
+			"name": p.Name, "in": p.In, "required": p.Required, "description": p.Description, "schema": p.Schema,
+		})
+	}
+
+	responses := map[string]interface{}{}
+	for code, r := range op.Responses {
+		out := map[string]interface{}{"description": r.Description}
+		if out["description"] == "" {
+			out["description"] = http.StatusText(code)
+		}
+		if r.ContentType != "" {
+			out["content"] = map[string]interface{}{r.ContentType: map[string]interface{}{"schema": r.Schema}}
+		}
+		if len(r.Headers) > 0 {
+			h := map[string]interface{}{}
+			for _, name := range r.Headers {
+				h[name] = map[string]interface{}{"schema": String()}
+			}
+			out["headers"] = h
+		}
+		responses[fmt.Sprint(code)] = out
+	}
+	responses["default"] = map[string]interface{}{
+		"description": "Error",
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": Ref("Error")}},
+	}
+
+	out := map[string]interface{}{
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+		"responses":   responses,
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if op.Tag != "" {
+		out["tags"] = []string{op.Tag}
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+	if op.Request != nil {
+		out["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{op.Request.ContentType: map[string]interface{}{"schema": op.Request.Schema}},
+		}
+	}
+	if op.Admin {
+		out["security"] = []interface{}{map[string]interface{}{"adminToken": []string{}}}
+	} else {
+		// anonymous or with an API key
+		out["security"] = []interface{}{map[string]interface{}{}, map[string]interface{}{"apiKey": []string{}}}
+	}
+	return out
+}
+
+func operationID(op Operation) string {
+	parts := []string{strings.ToLower(op.Method)}
+	for _, seg := range strings.Split(strings.Trim(op.Path, "/"), "/") {
+		if seg == "api" || seg == "v1" || seg == "" {
+			continue
+		}
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			seg = "by-" + seg[1:]
+		}
+		parts = append(parts, seg)
+	}
+	return strings.Join(parts, "-")
+}
+
+// RouteInfo is the part of gin.RouteInfo the check needs.
+type RouteInfo struct {
+	Method string
+	Path   string
+}
+
+// Missing compares the spec with the served routes and returns the routes
+// that are not documented, and documented operations that are not served.
+func (s *Spec) Missing(routes []RouteInfo) (undocumented, unserved []string) {
+	documented := map[string]bool{}
+	for _, op := range s.ops {
+		documented[op.Method+" "+op.Path] = true
+	}
+	served := map[string]bool{}
+	for _, r := range routes {
+		key := r.Method + " " + r.Path
+		served[key] = true
+		if !documented[key] {
+			undocumented = append(undocumented, key)
+		}
+	}
+	for key := range documented {
+		if !served[key] {
+			unserved = append(unserved, key)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(unserved)
+	return undocumented, unserved
+}
@@ -0,0 +1,147 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+func String() Schema  { return Schema{"type": "string"} }
+func Integer() Schema { return Schema{"type": "integer"} }
+func Boolean() Schema { return Schema{"type": "boolean"} }
+func Binary() Schema  { return Schema{"type": "string", "format": "binary"} }
+
+func Ref(name string) Schema {
+	return Schema{"$ref": "#/components/schemas/" + name}
+}
+
+func Array(items Schema) Schema {
+	return Schema{"type": "array", "items": items}
+}
+
+// Data wraps a schema in the {"data": ...} envelope every JSON endpoint uses.
+func Data(inner Schema) Schema {
+	return Object(map[string]Schema{"data": inner}, "data")
+}
+
+// Object builds an object schema from properties.
+func Object(props map[string]Schema, required ...string) Schema {
+	p := map[string]interface{}{}
+	for k, v := range props {
+		p[k] = v
+	}
+	out := Schema{"type": "object", "properties": p}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+// Enum restricts a string schema.
+func Enum(values ...string) Schema {
+	return Schema{"type": "string", "enum": values}
+}
+
+// Component registers v's type under name and returns a reference to it.
+// Registered types are referenced by $ref wherever they appear, which also
+// breaks cycles (illustration -> category -> illustrations). Schemas are
+// reflected in Document, once every component is known.
+func (s *Spec) Component(name string, v interface{}) Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s.names[t.String()] = name
+	s.components[name] = t
+	return Ref(name)
+}
+
+// SchemaOf reflects a Go value's type into a schema.
+func (s *Spec) SchemaOf(v interface{}) Schema {
+	return s.schemaOf(reflect.TypeOf(v), false)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (s *Spec) schemaOf(t reflect.Type, define bool) Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+	var out Schema
+	name, registered := s.names[t.String()]
+	switch {
+	case registered && !define:
+		out = Ref(name)
+	case t == timeType:
+		out = Schema{"type": "string", "format": "date-time"}
+	case t.Name() == "DeletedAt" && strings.HasSuffix(t.PkgPath(), "gorm"):
+		out = Schema{"type": "string", "format": "date-time", "nullable": true}
+	default:
+		switch t.Kind() {
+		case reflect.String:
+			out = String()
+		case reflect.Bool:
+			out = Boolean()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			out = Integer()
+		case reflect.Float32, reflect.Float64:
+			out = Schema{"type": "number"}
+		case reflect.Slice, reflect.Array:
+			out = Array(s.schemaOf(t.Elem(), false))
+		case reflect.Map:
+			out = Schema{"type": "object", "additionalProperties": s.schemaOf(t.Elem(), false)}
+		case reflect.Struct:
+			out = s.structSchema(t)
+		default:
+			out = Schema{}
+		}
+	}
+	if nullable {
+		if _, isRef := out["$ref"]; isRef {
+			out = Schema{"allOf": []interface{}{out}, "nullable": true}
+		} else {
+			out["nullable"] = true
+		}
+	}
+	return out
+}
+
+func (s *Spec) structSchema(t reflect.Type) Schema {
+	props := map[string]Schema{}
+	var required []string
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft)
+					continue
+				}
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = s.schemaOf(f.Type, false)
+			if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(t)
+	return Object(props, required...)
+}
@@ -0,0 +1,233 @@
+package routes
+
+import (
+	"net/http"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/controllers"
+	"open-illustrations-go/dto"
+	"open-illustrations-go/models"
+	"open-illustrations-go/openapi"
+	"open-illustrations-go/services"
+)
+
+// APISpec describes every route registered in RegisterRoutes. RegisterRoutes
+// logs any route that is served but missing here, so keep the two in step.
+func APISpec() *openapi.Spec {
+	s := openapi.New("Open Illustrations API", "1.0.0",
+		"Illustrations, taxonomies and packs backed by MinIO. Every JSON response is wrapped in {\"data\": ...}; errors use the {\"error\": ...} envelope.")
+
+	s.Component("Error", struct {
+		Error apierror.Error `json:"error"`
+	}{})
+	illustration := s.Component("Illustration", dto.IllustrationResponse{})
+	taxonomy := s.Component("Taxonomy", dto.TaxonomyResponse{})
+	job := s.Component("Job", models.Job{})
+	importJob := s.Component("ImportJob", models.ImportJob{})
+	signingKey := s.Component("SigningKey", models.SigningKey{})
+	revocation := s.Component("TokenRevocation", models.TokenRevocation{})
+	dependency := s.Component("DependencyCheck", services.DependencyCheck{})
+
+	fields := openapi.Param{Name: "fields", In: "query", Schema: openapi.String(), Description: "comma-separated list of fields to return"}
+	include := openapi.Param{Name: "include", In: "query", Schema: openapi.String(), Description: "comma-separated relations to embed"}
+	presign := openapi.Param{Name: "include_presign", In: "query", Schema: openapi.Enum("1"), Description: "internal callers only: add presigned storage URLs"}
+	shaped := []openapi.Param{fields, include, presign}
+	limit := openapi.Param{Name: "limit", In: "query", Schema: openapi.Integer(), Description: "page size, 1-500; without it the whole list is returned"}
+	offset := openapi.Param{Name: "offset", In: "query", Schema: openapi.Integer(), Description: "rows to skip, ordered by id; needs limit"}
+	listed := []openapi.Param{fields, include, presign, limit, offset}
+	nameBody := &openapi.Body{ContentType: "application/json", Schema: openapi.Object(map[string]openapi.Schema{"name": openapi.String()}, "name")}
+	deleted := openapi.Object(map[string]openapi.Schema{"id": openapi.Integer(), "deleted_at": openapi.String()})
+	message := openapi.Object(map[string]openapi.Schema{"message": openapi.String()})
+	logLevel := openapi.Object(map[string]openapi.Schema{"level": openapi.Enum("debug", "info", "warn", "error")}, "level")
+	uploadForm := &openapi.Body{ContentType: "multipart/form-data", Schema: openapi.Object(map[string]openapi.Schema{
+		"file":        openapi.Binary(),
+		"title":       openapi.String(),
+		"file_name":   openapi.String(),
+		"category_id": openapi.Integer(),
+		"style_id":    openapi.Integer(),
+		"pack_id":     openapi.Integer(),
+	}, "file", "title")}
+	tusHeaders := []string{"Tus-Resumable", "Upload-Offset", "Upload-Length", "Upload-Expires"}
+	ok := func(schema openapi.Schema) map[int]openapi.Response {
+		return map[int]openapi.Response{http.StatusOK: {ContentType: "application/json", Schema: schema}}
+	}
+	status := func(code int, schema openapi.Schema) map[int]openapi.Response {
+		return map[int]openapi.Response{code: {ContentType: "application/json", Schema: schema}}
+	}
+	illustrations := ok(openapi.Data(openapi.Array(illustration)))
+	rateLimited := openapi.Response{Description: "Rate limit exceeded", ContentType: "application/json", Schema: openapi.Ref("Error"),
+		Headers: []string{"Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"}}
+	readiness := openapi.Object(map[string]openapi.Schema{"status": openapi.Enum("ready", "unavailable"), "checks": openapi.Array(dependency)})
+
+	s.Add(
+		// Illustrations
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/illustrations", Tag: "illustrations", Summary: "List illustrations", Params: listed, Responses: illustrations},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/illustrations/upload", Tag: "illustrations", Summary: "Upload an illustration (deprecated, use POST /illustrations)", Request: uploadForm, Responses: status(http.StatusCreated, openapi.Data(illustration))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/illustrations/:id/file", Tag: "illustrations", Summary: "Get a presigned URL and a signed asset URL",
+			Description: "url and expires_in are omitted when variant, bind_ip, bind_user or single_use narrows the signed URL.",
+			Params: []openapi.Param{
+				{Name: "variant", In: "query", Schema: openapi.Enum(services.AssetVariantOriginal, "png")},
+				{Name: "size", In: "query", Schema: openapi.Integer(), Description: "PNG width in pixels (16-4096)"},
+				{Name: "disposition", In: "query", Schema: openapi.Enum(services.DispositionInline, services.DispositionAttachment)},
+				{Name: "bind_ip", In: "query", Schema: openapi.Enum("1"), Description: "token only valid from the caller's IP"},
+				{Name: "bind_user", In: "query", Schema: openapi.Enum("1"), Description: "token only valid with the caller's API key"},
+				{Name: "single_use", In: "query", Schema: openapi.Enum("1"), Description: "token is rejected after its first use"},
+			},
+			Responses: ok(openapi.Object(map[string]openapi.Schema{
+				"url":                   openapi.String(),
+				"expires_in":            openapi.Integer(),
+				"signed_url":            openapi.String(),
+				"signed_url_expires_at": {"type": "string", "format": "date-time"},
+			}))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/illustrations/:id", Tag: "illustrations", Summary: "Get an illustration", Params: shaped, Responses: ok(openapi.Data(illustration))},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/illustrations", Tag: "illustrations", Summary: "Create an illustration",
+			Description: "Send multipart/form-data to upload the file, or JSON with the storage_key of an object that is already in the bucket.",
+			Request:     &openapi.Body{ContentType: "application/json", Schema: s.SchemaOf(controllers.CreateIllustrationDTO{})},
+			Responses:   status(http.StatusCreated, openapi.Data(illustration))},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/illustrations/:id", Tag: "illustrations", Summary: "Delete an illustration", Responses: ok(message)},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/illustrations/:id/download", Tag: "illustrations", Summary: "Get a one-hour presigned download URL",
+			Responses: ok(openapi.Object(map[string]openapi.Schema{"download_url": openapi.String()}))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/illustrations/:id/public", Tag: "assets", Summary: "Stream a free illustration",
+			Params: []openapi.Param{
+				{Name: "format", In: "query", Schema: openapi.Enum("png")},
+				{Name: "size", In: "query", Schema: openapi.Integer()},
+				{Name: "download", In: "query", Schema: openapi.Enum("1")},
+			},
+			Responses: map[int]openapi.Response{
+				http.StatusOK:              {ContentType: "image/*", Schema: openapi.Binary()},
+				http.StatusTooManyRequests: rateLimited,
+			}},
+
+		// Resumable uploads
+		openapi.Operation{Method: http.MethodOptions, Path: "/api/v1/uploads", Tag: "uploads", Summary: "tus capability discovery",
+			Responses: map[int]openapi.Response{http.StatusNoContent: {Headers: []string{"Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size"}}}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/uploads", Tag: "uploads", Summary: "Create a resumable upload",
+			Params: []openapi.Param{
+				{Name: "Tus-Resumable", In: "header", Required: true, Schema: openapi.String()},
+				{Name: "Upload-Length", In: "header", Required: true, Schema: openapi.Integer()},
+				{Name: "Upload-Metadata", In: "header", Required: true, Schema: openapi.String(), Description: "tus metadata; title is required"},
+			},
+			Responses: map[int]openapi.Response{http.StatusCreated: {Headers: []string{"Location", "Upload-Expires"}}}},
+		openapi.Operation{Method: http.MethodHead, Path: "/api/v1/uploads/:id", Tag: "uploads", Summary: "Get the upload offset",
+			Responses: map[int]openapi.Response{http.StatusOK: {Headers: tusHeaders}}},
+		openapi.Operation{Method: http.MethodPatch, Path: "/api/v1/uploads/:id", Tag: "uploads", Summary: "Append a chunk",
+			Params: []openapi.Param{
+				{Name: "Tus-Resumable", In: "header", Required: true, Schema: openapi.String()},
+				{Name: "Upload-Offset", In: "header", Required: true, Schema: openapi.Integer()},
+			},
+			Request:   &openapi.Body{ContentType: "application/offset+octet-stream", Schema: openapi.Binary()},
+			Responses: map[int]openapi.Response{http.StatusNoContent: {Description: "Chunk stored; the illustration is created after the last one", Headers: tusHeaders}}},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/uploads/:id", Tag: "uploads", Summary: "Terminate an upload",
+			Responses: map[int]openapi.Response{http.StatusNoContent: {}}},
+
+		// Imports
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/imports", Tag: "imports", Summary: "Import a ZIP of SVGs in the background",
+			Request: &openapi.Body{ContentType: "multipart/form-data", Schema: openapi.Object(map[string]openapi.Schema{
+				"file":       openapi.Binary(),
+				"manifest":   openapi.Binary(),
+				"category":   openapi.String(),
+				"style":      openapi.String(),
+				"pack":       openapi.String(),
+				"is_premium": openapi.Boolean(),
+			}, "file")},
+			Responses: status(http.StatusAccepted, openapi.Object(map[string]openapi.Schema{
+				"data": openapi.Object(map[string]openapi.Schema{
+					"id": openapi.Integer(), "status": openapi.String(), "total": openapi.Integer(), "job_id": openapi.Integer(),
+				}),
+				"status_url": openapi.String(),
+			}))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/imports/:id", Tag: "imports", Summary: "Get an import with per-file results", Responses: ok(openapi.Data(importJob))},
+
+		// Signed assets
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/i/:token", Tag: "assets", Summary: "Stream an asset with a signed token",
+			Responses: map[int]openapi.Response{
+				http.StatusOK:              {ContentType: "image/*", Schema: openapi.Binary(), Headers: []string{"Cache-Control", "Content-Disposition"}},
+				http.StatusTooManyRequests: rateLimited,
+			}},
+
+		// Categories
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/category", Tag: "categories", Summary: "Create a category", Request: nameBody, Responses: status(http.StatusCreated, openapi.Data(taxonomy))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/categories", Tag: "categories", Summary: "List categories", Params: listed, Responses: ok(openapi.Data(openapi.Array(taxonomy)))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/categories/:id", Tag: "categories", Summary: "Get a category", Params: shaped, Responses: ok(openapi.Data(taxonomy))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/categories/:id/illustrations", Tag: "categories", Summary: "List a category's illustrations", Params: listed, Responses: illustrations},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/categories/:id", Tag: "categories", Summary: "Soft-delete a category", Responses: ok(deleted)},
+
+		// Packs
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/pack", Tag: "packs", Summary: "Create a pack", Request: nameBody, Responses: status(http.StatusCreated, openapi.Data(taxonomy))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/packs", Tag: "packs", Summary: "List packs", Params: listed, Responses: ok(openapi.Data(openapi.Array(taxonomy)))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/packs/:id", Tag: "packs", Summary: "Get a pack", Params: shaped, Responses: ok(openapi.Data(taxonomy))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/packs/:id/illustrations", Tag: "packs", Summary: "List a pack's illustrations", Params: listed, Responses: illustrations},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/packs/:id", Tag: "packs", Summary: "Soft-delete a pack", Responses: ok(deleted)},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/packs/:id/download", Tag: "packs", Summary: "Download a pack as a ZIP",
+			Description: "Returns 202 with Retry-After while the archive is being built, and 503 with Retry-After while too many packs are being downloaded. Supports Range requests. Only whole-pack archives at PNG sizes 256, 512 or 1024 are cached; subsets and other sizes are built for each request, ignore redirect and are limited to 50 illustrations.",
+			Params: []openapi.Param{
+				{Name: "format", In: "query", Schema: openapi.Enum("svg", "png", "both")},
+				{Name: "size", In: "query", Schema: openapi.Integer(), Description: "PNG size in pixels (16-1024)"},
+				{Name: "ids", In: "query", Schema: openapi.String(), Description: "comma-separated subset of at most 50 illustration IDs"},
+				{Name: "layout", In: "query", Schema: openapi.Enum("flat", "category", "style")},
+				{Name: "redirect", In: "query", Schema: openapi.Enum("1")},
+			},
+			Responses: map[int]openapi.Response{
+				http.StatusOK:                 {ContentType: "application/zip", Schema: openapi.Binary(), Headers: []string{"ETag", "Content-Disposition"}},
+				http.StatusFound:              {Description: "Redirect to a presigned URL", Headers: []string{"Location"}},
+				http.StatusAccepted:           {Description: "Archive is being built", ContentType: "application/json", Headers: []string{"Retry-After"}, Schema: openapi.Object(map[string]openapi.Schema{"status": openapi.String(), "job_id": openapi.Integer()})},
+				http.StatusTooManyRequests:    rateLimited,
+				http.StatusServiceUnavailable: {Description: "Too many downloads in progress", ContentType: "application/json", Schema: openapi.Ref("Error"), Headers: []string{"Retry-After"}},
+			}},
+
+		// Styles
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/styles", Tag: "styles", Summary: "Create a style", Request: nameBody, Responses: status(http.StatusCreated, openapi.Data(taxonomy))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/styles", Tag: "styles", Summary: "List styles", Params: listed, Responses: ok(openapi.Data(openapi.Array(taxonomy)))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/styles/:id", Tag: "styles", Summary: "Get a style", Params: shaped, Responses: ok(openapi.Data(taxonomy))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/styles/:id/illustrations", Tag: "styles", Summary: "List a style's illustrations", Params: listed, Responses: illustrations},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/styles/:id", Tag: "styles", Summary: "Rename a style", Request: nameBody, Responses: ok(openapi.Data(taxonomy))},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/styles/:id", Tag: "styles", Summary: "Soft-delete a style", Responses: ok(deleted)},
+
+		// Admin
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/signing-keys", Tag: "admin", Admin: true, Summary: "List signing keys", Responses: ok(openapi.Data(openapi.Array(signingKey)))},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/signing-keys/rotate", Tag: "admin", Admin: true, Summary: "Rotate the signing key", Responses: status(http.StatusCreated, openapi.Data(signingKey))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/revocations", Tag: "admin", Admin: true, Summary: "List token revocations", Responses: ok(openapi.Data(openapi.Array(revocation)))},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/revocations", Tag: "admin", Admin: true, Summary: "Revoke signed tokens",
+			Description: "Set exactly one of token, nonce, storage_key or issued_before.",
+			Request:     &openapi.Body{ContentType: "application/json", Schema: s.SchemaOf(services.RevocationInput{})},
+			Responses:   status(http.StatusCreated, openapi.Data(revocation))},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/admin/revocations/:id", Tag: "admin", Admin: true, Summary: "Delete a revocation", Responses: ok(message)},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/log-level", Tag: "admin", Admin: true, Summary: "Get the log level", Responses: ok(openapi.Data(logLevel))},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/admin/log-level", Tag: "admin", Admin: true, Summary: "Change the log level",
+			Description: "Applies to the instance that handles the request until it restarts.",
+			Request:     &openapi.Body{ContentType: "application/json", Schema: logLevel},
+			Responses:   ok(openapi.Data(logLevel))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/jobs", Tag: "jobs", Admin: true, Summary: "List background jobs",
+			Params: []openapi.Param{
+				{Name: "type", In: "query", Schema: openapi.String()},
+				{Name: "status", In: "query", Schema: openapi.String()},
+				{Name: "limit", In: "query", Schema: openapi.Integer(), Description: "1-500, default 50"},
+				{Name: "offset", In: "query", Schema: openapi.Integer(), Description: "jobs to skip, newest first"},
+			},
+			Responses: ok(openapi.Data(openapi.Array(job)))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/jobs/:id", Tag: "jobs", Admin: true, Summary: "Get a job", Responses: ok(openapi.Data(job))},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/jobs/:id/retry", Tag: "jobs", Admin: true, Summary: "Retry a failed job", Responses: status(http.StatusAccepted, openapi.Data(job))},
+
+		// Health
+		openapi.Operation{Method: http.MethodGet, Path: "/healthz", Tag: "health", Summary: "Liveness probe", Responses: ok(openapi.Object(map[string]openapi.Schema{"status": openapi.String()}))},
+		openapi.Operation{Method: http.MethodGet, Path: "/readyz", Tag: "health", Summary: "Readiness probe",
+			Description: "503 while a dependency is down, migrations are pending or the server is shutting down.",
+			Responses: map[int]openapi.Response{
+				http.StatusOK:                 {ContentType: "application/json", Schema: readiness},
+				http.StatusServiceUnavailable: {Description: "Not ready", ContentType: "application/json", Schema: readiness},
+			}},
+		openapi.Operation{Method: http.MethodGet, Path: "/metrics", Tag: "health", Summary: "Prometheus metrics", Responses: map[int]openapi.Response{http.StatusOK: {ContentType: "text/plain", Schema: openapi.String()}}},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/status", Tag: "health", Summary: "Version, uptime, schema level and dependency status", Responses: ok(openapi.Data(s.SchemaOf(services.ServiceStatus{})))},
+
+		// Info and docs
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/info/about", Tag: "info", Summary: "About this service", Responses: ok(openapi.Object(map[string]openapi.Schema{"about": openapi.String()}))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/info/license", Tag: "info", Summary: "License summary", Responses: ok(openapi.Object(map[string]openapi.Schema{"license": openapi.String()}))},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/openapi.json", Tag: "info", Summary: "This OpenAPI document", Responses: ok(openapi.Schema{"type": "object"})},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/docs", Tag: "info", Summary: "API documentation UI", Responses: map[int]openapi.Response{http.StatusOK: {ContentType: "text/html", Schema: openapi.String()}}},
+	)
+	for _, name := range openapi.DocsAssets {
+		s.Add(openapi.Operation{Method: http.MethodGet, Path: "/api/v1/docs/" + name, Tag: "info", Summary: "Swagger UI asset used by the docs page",
+			Responses: map[int]openapi.Response{http.StatusOK: {ContentType: "application/octet-stream", Schema: openapi.Binary()}}})
+	}
+	return s
+}
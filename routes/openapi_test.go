@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"open-illustrations-go/middleware"
+	"open-illustrations-go/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterRoutes(r)
+	return r
+}
+
+func TestAPISpecMatchesRoutes(t *testing.T) {
+	r := testRouter()
+	undocumented, unserved := APISpec().Missing(openapi.Routes(r.Routes()))
+	if len(undocumented) > 0 {
+		t.Errorf("served but not in the OpenAPI spec: %v", undocumented)
+	}
+	if len(unserved) > 0 {
+		t.Errorf("in the OpenAPI spec but not served: %v", unserved)
+	}
+}
+
+func TestDocsAreServedLocally(t *testing.T) {
+	r := testRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	page := get("/api/v1/docs")
+	if page.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/docs = %d", page.Code)
+	}
+	if strings.Contains(page.Body.String(), "https://") {
+		t.Error("docs page loads assets from another origin")
+	}
+	for _, name := range openapi.DocsAssets {
+		if !strings.Contains(page.Body.String(), `"docs/`+name+`"`) {
+			t.Errorf("docs page does not reference %s", name)
+		}
+		w := get("/api/v1/docs/" + name)
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Errorf("GET /api/v1/docs/%s = %d with %d bytes", name, w.Code, w.Body.Len())
+		}
+	}
+}
+
+func TestJobRoutesNeedTheAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Errors())
+	RegisterRoutes(r)
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/admin/jobs"},
+		{http.MethodGet, "/api/v1/admin/jobs/1"},
+		{http.MethodPost, "/api/v1/admin/jobs/1/retry"},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(req.method, req.path, nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s without the admin token = %d, want 403", req.method, req.path, w.Code)
+		}
+	}
+}
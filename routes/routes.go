@@ -1,16 +1,32 @@
 package routes
 
 import (
+	"log/slog"
+
+	"open-illustrations-go/apierror"
 	"open-illustrations-go/controllers"
+	"open-illustrations-go/middleware"
+	"open-illustrations-go/openapi"
+	"open-illustrations-go/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func RegisterRoutes(r *gin.Engine) {
-	api := r.Group("/api/v1")
+	r.NoRoute(func(c *gin.Context) {
+		apierror.Write(c, apierror.NotFound("route not found"))
+	})
+
+	// Liveness and readiness probes live outside the versioned API
+	r.GET("/healthz", controllers.Healthz)
+	r.GET("/readyz", controllers.Readyz)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	api := r.Group("/api/v1", controllers.Authenticate)
 
 	api.GET("/illustrations", controllers.GetIllustrations)
-	api.POST("/illustrations/upload", controllers.UploadIllustration)
+	api.POST("/illustrations/upload", middleware.Streaming(), controllers.UploadIllustration)
 
 	// Penting: letakkan sebelum /illustrations/:id agar tidak tertutup wildcard
 	// api.GET("/illustrations/file/:key", controllers.GetIllustrationFileURL)
@@ -23,10 +39,21 @@ func RegisterRoutes(r *gin.Engine) {
 	api.GET("/illustrations/:id/download", controllers.Download)
 
 	// Public stream for non-premium assets by ID
-	api.GET("/illustrations/:id/public", controllers.StreamPublic)
+	api.GET("/illustrations/:id/public", controllers.RateLimit(services.RateLimitStream), middleware.Streaming(), controllers.StreamPublic)
+
+	// Resumable uploads (tus 1.0.0)
+	api.OPTIONS("/uploads", controllers.TusOptions)
+	api.POST("/uploads", controllers.CreateUpload)
+	api.HEAD("/uploads/:id", controllers.UploadStatus)
+	api.PATCH("/uploads/:id", middleware.Streaming(), controllers.PatchUpload)
+	api.DELETE("/uploads/:id", controllers.DeleteUpload)
+
+	// Bulk import from a ZIP archive (runs in the background)
+	api.POST("/imports", middleware.Streaming(), controllers.CreateImport)
+	api.GET("/imports/:id", controllers.GetImport)
 
 	// Asset streaming via signed token path
-	api.GET("/i/:token", controllers.StreamSigned)
+	api.GET("/i/:token", controllers.RateLimit(services.RateLimitStream), middleware.Streaming(), controllers.StreamSigned)
 
 	api.POST("/category", controllers.CreateCategory)
 	api.GET("/categories", controllers.GetCategories)
@@ -39,7 +66,7 @@ func RegisterRoutes(r *gin.Engine) {
 	api.GET("/packs/:id", controllers.GetPack)
 	api.GET("/packs/:id/illustrations", controllers.GetIllustrationsByPack)
 	api.PUT("/packs/:id", controllers.DeletePack)
-	api.GET("/packs/:id/download", controllers.DownloadPacks)
+	api.GET("/packs/:id/download", controllers.RateLimit(services.RateLimitDownload), controllers.LimitPackDownloads, middleware.Streaming(), controllers.DownloadPacks)
 
 	api.POST("/styles", controllers.CreateStyle)
 	api.GET("/styles", controllers.GetStyles)
@@ -48,6 +75,36 @@ func RegisterRoutes(r *gin.Engine) {
 	api.PUT("/styles/:id", controllers.UpdateStyle)
 	api.DELETE("/styles/:id", controllers.DeleteStyle)
 
+	admin := api.Group("/admin", controllers.RequireAdmin)
+	admin.GET("/signing-keys", controllers.GetSigningKeys)
+	admin.POST("/signing-keys/rotate", controllers.RotateSigningKey)
+	admin.GET("/revocations", controllers.GetRevocations)
+	admin.POST("/revocations", controllers.RevokeTokens)
+	admin.DELETE("/revocations/:id", controllers.DeleteRevocation)
+	admin.GET("/log-level", controllers.GetLogLevel)
+	admin.PUT("/log-level", controllers.SetLogLevel)
+
+	// Background jobs: payloads name storage keys and retries re-run work
+	admin.GET("/jobs", controllers.GetJobs)
+	admin.GET("/jobs/:id", controllers.GetJob)
+	admin.POST("/jobs/:id/retry", controllers.RetryJob)
+
+	api.GET("/status", controllers.GetStatus)
 	api.GET("/info/about", controllers.About)
 	api.GET("/info/license", controllers.License)
+
+	spec := APISpec()
+	api.GET("/openapi.json", spec.JSONHandler())
+	api.GET("/docs", openapi.DocsHandler)
+	for _, name := range openapi.DocsAssets {
+		api.GET("/docs/"+name, openapi.DocsAsset(name))
+	}
+
+	undocumented, unserved := spec.Missing(openapi.Routes(r.Routes()))
+	for _, route := range undocumented {
+		slog.Warn("route is served but not in the OpenAPI spec", "route", route)
+	}
+	for _, route := range unserved {
+		slog.Warn("route is in the OpenAPI spec but not served", "route", route)
+	}
 }
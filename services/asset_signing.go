@@ -3,86 +3,282 @@ package services
 import (
 	"context"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"open-illustrations-go/config"
+	"open-illustrations-go/metrics"
 
 	"github.com/minio/minio-go/v7"
 )
 
-func assetSecret() ([]byte, error) {
-	sec := os.Getenv("ASSET_SIGNING_SECRET")
-	if sec == "" {
-		return nil, errors.New("ASSET_SIGNING_SECRET not set")
+// Token formats (base64url-encoded):
+//
+//	v3:     "v3|<kid>|<base64url(claims JSON)>|<sig>"  sig = HMAC("v3|kid|claims") with key kid
+//	v2:     "v2|<kid>|<storageKey>|<exp>|<sig>"        sig = HMAC(kid|storageKey|exp) with key kid
+//	legacy: "<storageKey>|<exp>|<sig>"                 sig = HMAC(storageKey.exp) with ASSET_SIGNING_SECRET
+//
+// New tokens are always v3; v2 and legacy tokens are still accepted until they expire.
+const assetTokenVersion = "v3"
+
+const (
+	AssetVariantOriginal = "original"
+
+	DispositionInline     = "inline"
+	DispositionAttachment = "attachment"
+)
+
+var (
+	ErrTokenReplayed      = errors.New("token already used")
+	ErrTokenWrongClient   = errors.New("token is bound to another client")
+	ErrTokenWrongSubject  = errors.New("token is bound to another user")
+	ErrInvalidAssetClaims = errors.New("invalid token claims")
+	ErrTokenExpired       = errors.New("expired")
+	ErrTokenSignature     = errors.New("signature mismatch")
+	ErrTokenUnknownKey    = errors.New("unknown signing key")
+
+	errMalformedToken = errors.New("malformed token")
+)
+
+// AssetClaims is what a signed asset URL grants. Only StorageKey and ExpiresAt are
+// required; every other claim narrows what the token can be used for.
+type AssetClaims struct {
+	StorageKey     string `json:"k"`
+	IllustrationID uint   `json:"i,omitempty"`
+	// Variant is "original" (the stored file) or "png:<size>"; a token minted for
+	// a PNG thumbnail cannot be used to fetch the original.
+	Variant     string `json:"v,omitempty"`
+	Disposition string `json:"d,omitempty"`
+	ClientIP    string `json:"ip,omitempty"`
+	Subject     string `json:"sub,omitempty"`
+	// Nonce makes the token single-use; it is recorded in the replay store on first use.
+	Nonce     string `json:"n,omitempty"`
+	KeyID     string `json:"-"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// AssetRequest describes who is presenting a token, for checking bound claims.
+type AssetRequest struct {
+	ClientIP string
+	Subject  string
+}
+
+// PNGVariant names the PNG rendition of the given size.
+func PNGVariant(size int) string {
+	return "png:" + strconv.Itoa(size)
+}
+
+// Narrowed reports whether the claims grant less than the whole original file
+// to anyone holding the token: a PNG rendition, a bound caller or a single use.
+func (c AssetClaims) Narrowed() bool {
+	return (c.Variant != "" && c.Variant != AssetVariantOriginal) || c.ClientIP != "" || c.Subject != "" || c.Nonce != ""
+}
+
+// ParseAssetVariant returns the PNG size of a "png:<size>" variant, or 0 for the original.
+func ParseAssetVariant(v string) (int, error) {
+	if v == "" || v == AssetVariantOriginal {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(v, "png:"))
+	if !strings.HasPrefix(v, "png:") || err != nil || n < 16 || n > 4096 {
+		return 0, fmt.Errorf("%w: unknown variant %q", ErrInvalidAssetClaims, v)
 	}
-	return []byte(sec), nil
+	return n, nil
+}
+
+func signAssetPayload(secret []byte, payload string) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(payload))
+	return m.Sum(nil)
 }
 
 // GenerateAssetToken creates a short-lived signed token for a storageKey.
 func GenerateAssetToken(storageKey string, ttl time.Duration) (string, error) {
-	secret, err := assetSecret()
+	return IssueAssetToken(AssetClaims{StorageKey: storageKey}, ttl)
+}
+
+// IssueAssetToken signs claims with the active key. IssuedAt and ExpiresAt are
+// set from the current time and ttl unless the caller already set them.
+func IssueAssetToken(claims AssetClaims, ttl time.Duration) (string, error) {
+	if claims.StorageKey == "" {
+		return "", fmt.Errorf("%w: storage key is required", ErrInvalidAssetClaims)
+	}
+	if _, err := ParseAssetVariant(claims.Variant); err != nil {
+		return "", err
+	}
+	if d := claims.Disposition; d != "" && d != DispositionInline && d != DispositionAttachment {
+		return "", fmt.Errorf("%w: disposition must be inline or attachment", ErrInvalidAssetClaims)
+	}
+	kid, secret, err := assetKeys.signingKey()
 	if err != nil {
 		return "", err
 	}
-	exp := time.Now().Add(ttl).Unix()
-	payload := storageKey + "." + strconv.FormatInt(exp, 10)
-	m := hmac.New(sha256.New, secret)
-	m.Write([]byte(payload))
-	sig := m.Sum(nil)
-	raw := storageKey + "|" + strconv.FormatInt(exp, 10) + "|" + base64.RawURLEncoding.EncodeToString(sig)
-	return base64.RawURLEncoding.EncodeToString([]byte(raw)), nil
+	now := time.Now()
+	if claims.IssuedAt == 0 {
+		claims.IssuedAt = now.Unix()
+	}
+	if claims.ExpiresAt == 0 {
+		claims.ExpiresAt = now.Add(ttl).Unix()
+	}
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	body := assetTokenVersion + "|" + kid + "|" + base64.RawURLEncoding.EncodeToString(raw)
+	sig := signAssetPayload(secret, body)
+	return base64.RawURLEncoding.EncodeToString([]byte(body + "|" + base64.RawURLEncoding.EncodeToString(sig))), nil
+}
+
+// NewTokenNonce returns a random nonce for single-use tokens.
+func NewTokenNonce() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
 // ParseAndValidateAssetToken validates token and returns storageKey if valid.
+// It does not check bound claims or consume nonces; see VerifyAssetToken.
 func ParseAndValidateAssetToken(token string) (string, error) {
-	secret, err := assetSecret()
+	claims, err := ParseAssetToken(token)
 	if err != nil {
 		return "", err
 	}
+	return claims.StorageKey, nil
+}
+
+// ParseAssetToken checks the signature and expiry of any supported token format.
+func ParseAssetToken(token string) (*AssetClaims, error) {
 	decoded, err := base64.RawURLEncoding.DecodeString(token)
 	if err != nil {
-		return "", errors.New("invalid token encoding")
+		return nil, fmt.Errorf("%w: invalid token encoding", errMalformedToken)
 	}
 	parts := strings.Split(string(decoded), "|")
-	if len(parts) != 3 {
-		return "", errors.New("invalid token parts")
+
+	var claims AssetClaims
+	var sigStr, payload string
+	var secret []byte
+	switch {
+	case len(parts) == 4 && parts[0] == assetTokenVersion:
+		claims.KeyID, sigStr = parts[1], parts[3]
+		payload = strings.Join(parts[:3], "|")
+		raw, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid claims encoding", errMalformedToken)
+		}
+		if err := json.Unmarshal(raw, &claims); err != nil {
+			return nil, fmt.Errorf("%w: invalid claims", errMalformedToken)
+		}
+	case len(parts) == 5 && parts[0] == "v2":
+		claims.KeyID, claims.StorageKey, sigStr = parts[1], parts[2], parts[4]
+		payload = parts[1] + "|" + parts[2] + "|" + parts[3]
+		if claims.ExpiresAt, err = strconv.ParseInt(parts[3], 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: invalid exp", errMalformedToken)
+		}
+	case len(parts) == 3:
+		claims.KeyID, claims.StorageKey, sigStr = envKeyID, parts[0], parts[2]
+		payload = parts[0] + "." + parts[1]
+		if claims.ExpiresAt, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: invalid exp", errMalformedToken)
+		}
+	default:
+		return nil, fmt.Errorf("%w: invalid token parts", errMalformedToken)
 	}
-	storageKey := parts[0]
-	expStr := parts[1]
-	sigStr := parts[2]
 
-	exp, err := strconv.ParseInt(expStr, 10, 64)
+	var ok bool
+	if secret, ok = assetKeys.verificationKey(claims.KeyID); !ok {
+		if claims.KeyID == envKeyID {
+			return nil, fmt.Errorf("%w: ASSET_SIGNING_SECRET not set", ErrTokenUnknownKey)
+		}
+		return nil, ErrTokenUnknownKey
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sigStr)
 	if err != nil {
-		return "", errors.New("invalid exp")
+		return nil, fmt.Errorf("%w: invalid sig encoding", errMalformedToken)
+	}
+	if !hmac.Equal(signAssetPayload(secret, payload), got) {
+		return nil, ErrTokenSignature
 	}
-	if time.Now().Unix() > exp {
-		return "", errors.New("expired")
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, ErrTokenExpired
 	}
+	if claims.StorageKey == "" {
+		return nil, ErrInvalidAssetClaims
+	}
+	return &claims, nil
+}
 
-	payload := storageKey + "." + expStr
-	m := hmac.New(sha256.New, secret)
-	m.Write([]byte(payload))
-	expected := m.Sum(nil)
-	got, err := base64.RawURLEncoding.DecodeString(sigStr)
+// VerifyAssetToken parses the token, checks IP/user bindings against the request
+// and the revocation list, and consumes the nonce of single-use tokens.
+func VerifyAssetToken(ctx context.Context, token string, req AssetRequest) (*AssetClaims, error) {
+	claims, err := verifyAssetToken(ctx, token, req)
+	metrics.TokenValidations.WithLabelValues(tokenResult(err)).Inc()
+	return claims, err
+}
+
+// tokenResult is the metrics label for a VerifyAssetToken outcome.
+func tokenResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, ErrTokenSignature):
+		return "signature_mismatch"
+	case errors.Is(err, ErrTokenUnknownKey):
+		return "unknown_key"
+	case errors.Is(err, ErrTokenWrongClient):
+		return "wrong_client"
+	case errors.Is(err, ErrTokenWrongSubject):
+		return "wrong_subject"
+	case errors.Is(err, ErrTokenRevoked):
+		return "revoked"
+	case errors.Is(err, ErrTokenReplayed):
+		return "replayed"
+	case errors.Is(err, errMalformedToken), errors.Is(err, ErrInvalidAssetClaims):
+		return "malformed"
+	}
+	return "error"
+}
+
+func verifyAssetToken(ctx context.Context, token string, req AssetRequest) (*AssetClaims, error) {
+	claims, err := ParseAssetToken(token)
 	if err != nil {
-		return "", errors.New("invalid sig encoding")
+		return nil, err
+	}
+	if claims.ClientIP != "" && claims.ClientIP != req.ClientIP {
+		return nil, ErrTokenWrongClient
+	}
+	if claims.Subject != "" && claims.Subject != req.Subject {
+		return nil, ErrTokenWrongSubject
+	}
+	if err := CheckRevoked(claims); err != nil {
+		return nil, err
 	}
-	if !hmac.Equal(expected, got) {
-		return "", errors.New("signature mismatch")
+	if claims.Nonce != "" {
+		first, err := TokenReplays.Consume(ctx, claims.Nonce, time.Unix(claims.ExpiresAt, 0))
+		if err != nil {
+			return nil, err
+		}
+		if !first {
+			return nil, ErrTokenReplayed
+		}
 	}
-	return storageKey, nil
+	return claims, nil
 }
 
 // GetObjectStream returns a readable MinIO object stream with its content-type.
-func GetObjectStream(storageKey string) (*minio.Object, string, io.ReadSeeker, error) {
-	obj, err := config.MinioClient.GetObject(context.Background(), config.BucketName, storageKey, minio.GetObjectOptions{})
+func GetObjectStream(ctx context.Context, storageKey string) (*minio.Object, string, io.ReadSeeker, error) {
+	obj, err := config.MinioClient.GetObject(ctx, config.BucketName, storageKey, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, "", nil, err
 	}
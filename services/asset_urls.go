@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"open-illustrations-go/models"
+)
+
+// AssetRequester describes who the URLs are for.
+type AssetRequester struct {
+	// Internal is a trusted internal caller; WantPresign additionally asks for
+	// direct storage URLs instead of proxy tokens.
+	Internal    bool
+	WantPresign bool
+	Tier        string
+}
+
+// AssetURLs are the URLs exposed for one illustration. Expiry fields are nil for
+// URLs that don't expire (free illustrations).
+type AssetURLs struct {
+	ImageURL              string     `json:"image_url"`
+	ImageURLExpiresAt     *time.Time `json:"image_url_expires_at,omitempty"`
+	DownloadURL           string     `json:"download_url"`
+	DownloadURLExpiresAt  *time.Time `json:"download_url_expires_at,omitempty"`
+	ThumbnailURL          string     `json:"thumbnail_url"`
+	ThumbnailURLExpiresAt *time.Time `json:"thumbnail_url_expires_at,omitempty"`
+}
+
+// ThumbnailSize is the PNG width used for thumbnail_url (ASSET_THUMBNAIL_SIZE, default 256).
+func ThumbnailSize() int {
+	return settings.Assets.ThumbnailSize
+}
+
+// ResolveAssetURLs returns the image, download and thumbnail URLs of ill for req.
+// ctx is where the image URL is shown (list or detail); it sets the token lifetime.
+//
+// Free illustrations are served from the public stream. Premium ones get signed
+// proxy URLs, or presigned storage URLs for internal callers that ask for them;
+// if signing fails they fall back to the public path, which answers 403.
+func ResolveAssetURLs(ill *models.Illustration, req AssetRequester, ctx URLContext) AssetURLs {
+	public := fmt.Sprintf("/api/v1/illustrations/%d/public", ill.ID)
+	thumbSize := ThumbnailSize()
+	if !ill.IsPremium {
+		return AssetURLs{
+			ImageURL:     PublicURL(public),
+			DownloadURL:  PublicURL(public + "?download=1"),
+			ThumbnailURL: PublicURL(fmt.Sprintf("%s?format=png&size=%d", public, thumbSize)),
+		}
+	}
+
+	var out AssetURLs
+	if req.Internal && req.WantPresign {
+		ttl := PresignTTL()
+		if u, err := GetDownloadURL(context.Background(), ill.StorageKey, ttl); err == nil {
+			exp := time.Now().Add(ttl)
+			out.ImageURL, out.ImageURLExpiresAt = u, &exp
+		}
+	}
+	policy := func(c URLContext) TokenPolicy {
+		if req.Internal {
+			c = URLContextInternal
+		}
+		return TokenPolicy{Context: c, Tier: req.Tier}
+	}
+	signed := func(claims AssetClaims, c URLContext) (string, *time.Time) {
+		claims.StorageKey, claims.IllustrationID = ill.StorageKey, ill.ID
+		tok, exp, err := IssuePolicyToken(claims, policy(c))
+		if err != nil {
+			return PublicURL(public), nil
+		}
+		return PublicURL("/api/v1/i/" + tok), &exp
+	}
+	if out.ImageURL == "" {
+		out.ImageURL, out.ImageURLExpiresAt = signed(AssetClaims{}, ctx)
+	}
+	out.DownloadURL, out.DownloadURLExpiresAt = signed(AssetClaims{Disposition: DispositionAttachment}, URLContextDownload)
+	out.ThumbnailURL, out.ThumbnailURLExpiresAt = signed(AssetClaims{Variant: PNGVariant(thumbSize)}, ctx)
+	return out
+}
+
+// PublicURL prefixes a relative API path with API_PUBLIC_BASE_URL when set.
+func PublicURL(p string) string {
+	if strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") {
+		return p
+	}
+	base := settings.Server.PublicBaseURL // contoh: http://localhost:8080
+	if base == "" {
+		return p
+	}
+	return strings.TrimRight(base, "/") + p
+}
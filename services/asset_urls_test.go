@@ -0,0 +1,286 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// useTestStorage points config.MinioClient at an address that is never
+// dialled; presigning is computed locally once the region is known.
+func useTestStorage(t *testing.T) {
+	t.Helper()
+	cli, err := minio.New("storage.internal:9000", &minio.Options{
+		Creds:  credentials.NewStaticV4("access", "secret-key", ""),
+		Region: "us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevClient, prevBucket := config.MinioClient, config.BucketName
+	config.MinioClient, config.BucketName = cli, "illustrations"
+	t.Cleanup(func() { config.MinioClient, config.BucketName = prevClient, prevBucket })
+}
+
+// signedClaims parses the token of a /api/v1/i/ URL.
+func signedClaims(t *testing.T, u string) *AssetClaims {
+	t.Helper()
+	_, tok, ok := strings.Cut(u, "/api/v1/i/")
+	if !ok {
+		t.Fatalf("%s is not a signed URL", u)
+	}
+	claims, err := ParseAssetToken(tok)
+	if err != nil {
+		t.Fatalf("%s: %v", u, err)
+	}
+	return claims
+}
+
+// lifetime checks that exp is set and returns how long from now it is.
+func lifetime(t *testing.T, name string, exp *time.Time) time.Duration {
+	t.Helper()
+	if exp == nil {
+		t.Fatalf("%s has no expiry", name)
+	}
+	return time.Until(*exp)
+}
+
+// within reports whether d lies in [ttl, ttl+bucket], allowing a second of
+// slack for the time the test takes.
+func within(d, ttl, bucket time.Duration) bool {
+	return d >= ttl-time.Second && d <= ttl+bucket
+}
+
+func TestResolveAssetURLs(t *testing.T) {
+	free := &models.Illustration{ID: 7, StorageKey: "illustrations/free.svg"}
+	premium := &models.Illustration{ID: 9, StorageKey: "illustrations/premium.svg", IsPremium: true}
+	unstored := &models.Illustration{ID: 11, IsPremium: true}
+	const bucket = 5 * time.Minute
+
+	tests := []struct {
+		name  string
+		ill   *models.Illustration
+		req   AssetRequester
+		ctx   URLContext
+		setup func()
+		check func(t *testing.T, got AssetURLs)
+	}{
+		{
+			name: "free",
+			ill:  free,
+			ctx:  URLContextList,
+			check: func(t *testing.T, got AssetURLs) {
+				want := AssetURLs{
+					ImageURL:     "/api/v1/illustrations/7/public",
+					DownloadURL:  "/api/v1/illustrations/7/public?download=1",
+					ThumbnailURL: "/api/v1/illustrations/7/public?format=png&size=256",
+				}
+				if got != want {
+					t.Errorf("got %+v, want %+v", got, want)
+				}
+			},
+		},
+		{
+			name:  "free behind a public base URL",
+			ill:   free,
+			ctx:   URLContextList,
+			setup: func() { settings.Server.PublicBaseURL = "https://cdn.example.com/" },
+			check: func(t *testing.T, got AssetURLs) {
+				if got.ImageURL != "https://cdn.example.com/api/v1/illustrations/7/public" {
+					t.Errorf("image_url = %s", got.ImageURL)
+				}
+				if !strings.HasPrefix(got.ThumbnailURL, "https://cdn.example.com/") || !strings.HasPrefix(got.DownloadURL, "https://cdn.example.com/") {
+					t.Errorf("thumbnail_url = %s, download_url = %s", got.ThumbnailURL, got.DownloadURL)
+				}
+			},
+		},
+		{
+			name: "premium list",
+			ill:  premium,
+			ctx:  URLContextList,
+			check: func(t *testing.T, got AssetURLs) {
+				img := signedClaims(t, got.ImageURL)
+				if img.StorageKey != premium.StorageKey || img.IllustrationID != premium.ID || img.Variant != "" || img.Disposition != "" {
+					t.Errorf("image claims = %+v", img)
+				}
+				if d := lifetime(t, "image_url", got.ImageURLExpiresAt); !within(d, 15*time.Minute, bucket) {
+					t.Errorf("image_url lives %s, want the list TTL", d)
+				}
+				if c := signedClaims(t, got.DownloadURL); c.Disposition != DispositionAttachment {
+					t.Errorf("download claims = %+v", c)
+				}
+				if d := lifetime(t, "download_url", got.DownloadURLExpiresAt); !within(d, 5*time.Minute, bucket) {
+					t.Errorf("download_url lives %s, want the download TTL", d)
+				}
+				if c := signedClaims(t, got.ThumbnailURL); c.Variant != PNGVariant(256) {
+					t.Errorf("thumbnail claims = %+v", c)
+				}
+				if got.ThumbnailURLExpiresAt == nil {
+					t.Error("thumbnail_url has no expiry")
+				}
+			},
+		},
+		{
+			name: "premium detail with a context override and public base URL",
+			ill:  premium,
+			ctx:  URLContextDetail,
+			setup: func() {
+				settings.Server.PublicBaseURL = "https://api.example.com"
+				settings.Assets.ContextTTLSeconds = map[string]int{"detail": 120}
+			},
+			check: func(t *testing.T, got AssetURLs) {
+				if !strings.HasPrefix(got.ImageURL, "https://api.example.com/api/v1/i/") {
+					t.Errorf("image_url = %s", got.ImageURL)
+				}
+				if d := lifetime(t, "image_url", got.ImageURLExpiresAt); !within(d, 2*time.Minute, bucket) {
+					t.Errorf("image_url lives %s, want 2m", d)
+				}
+			},
+		},
+		{
+			name:  "premium with tier override",
+			ill:   premium,
+			req:   AssetRequester{Tier: "pro"},
+			ctx:   URLContextList,
+			setup: func() { settings.Assets.TierTTLSeconds = map[string]int{"pro": 7200} },
+			check: func(t *testing.T, got AssetURLs) {
+				if d := lifetime(t, "image_url", got.ImageURLExpiresAt); !within(d, 2*time.Hour, bucket) {
+					t.Errorf("image_url lives %s, want the tier TTL", d)
+				}
+			},
+		},
+		{
+			name:  "premium without buckets",
+			ill:   premium,
+			ctx:   URLContextList,
+			setup: func() { settings.Assets.TTLBucketSeconds = 0 },
+			check: func(t *testing.T, got AssetURLs) {
+				if d := lifetime(t, "image_url", got.ImageURLExpiresAt); !within(d, 15*time.Minute, time.Second) {
+					t.Errorf("image_url lives %s, want exactly the list TTL", d)
+				}
+			},
+		},
+		{
+			name:  "premium without a signing key falls back to the public path",
+			ill:   premium,
+			ctx:   URLContextList,
+			setup: func() { settings.Assets.SigningSecret = "" },
+			check: func(t *testing.T, got AssetURLs) {
+				want := AssetURLs{
+					ImageURL:     "/api/v1/illustrations/9/public",
+					DownloadURL:  "/api/v1/illustrations/9/public",
+					ThumbnailURL: "/api/v1/illustrations/9/public",
+				}
+				if got != want {
+					t.Errorf("got %+v, want %+v", got, want)
+				}
+			},
+		},
+		{
+			name: "premium without a storage key falls back to the public path",
+			ill:  unstored,
+			ctx:  URLContextDetail,
+			check: func(t *testing.T, got AssetURLs) {
+				if got.ImageURL != "/api/v1/illustrations/11/public" || got.ImageURLExpiresAt != nil {
+					t.Errorf("image_url = %s (expires %v)", got.ImageURL, got.ImageURLExpiresAt)
+				}
+				if got.DownloadURL != "/api/v1/illustrations/11/public" || got.ThumbnailURL != "/api/v1/illustrations/11/public" {
+					t.Errorf("got %+v", got)
+				}
+			},
+		},
+		{
+			name: "internal caller gets the presign lifetime",
+			ill:  premium,
+			req:  AssetRequester{Internal: true},
+			ctx:  URLContextList,
+			check: func(t *testing.T, got AssetURLs) {
+				signedClaims(t, got.ImageURL)
+				for name, exp := range map[string]*time.Time{"image_url": got.ImageURLExpiresAt, "download_url": got.DownloadURLExpiresAt} {
+					if d := lifetime(t, name, exp); !within(d, 10*time.Minute, bucket) {
+						t.Errorf("%s lives %s, want PRESIGN_TTL_SECONDS", name, d)
+					}
+				}
+			},
+		},
+		{
+			name: "internal caller asking for presigned URLs",
+			ill:  premium,
+			req:  AssetRequester{Internal: true, WantPresign: true},
+			ctx:  URLContextList,
+			check: func(t *testing.T, got AssetURLs) {
+				u, err := url.Parse(got.ImageURL)
+				if err != nil || u.Host != "storage.internal:9000" || u.Path != "/illustrations/illustrations/premium.svg" || u.Query().Get("X-Amz-Signature") == "" {
+					t.Errorf("image_url = %s, want a presigned storage URL", got.ImageURL)
+				}
+				if d := lifetime(t, "image_url", got.ImageURLExpiresAt); !within(d, 10*time.Minute, 0) {
+					t.Errorf("image_url lives %s, want PRESIGN_TTL_SECONDS", d)
+				}
+				// downloads and thumbnails still go through the proxy
+				signedClaims(t, got.DownloadURL)
+				signedClaims(t, got.ThumbnailURL)
+			},
+		},
+		{
+			name: "presigned URLs use the public storage address",
+			ill:  premium,
+			req:  AssetRequester{Internal: true, WantPresign: true},
+			ctx:  URLContextDetail,
+			setup: func() {
+				settings.Storage.PublicBaseURL = "https://files.example.com"
+				settings.Storage.AccessKey, settings.Storage.SecretKey = "access", "secret-key"
+			},
+			check: func(t *testing.T, got AssetURLs) {
+				if !strings.HasPrefix(got.ImageURL, "https://files.example.com/illustrations/illustrations/premium.svg?") {
+					t.Errorf("image_url = %s", got.ImageURL)
+				}
+			},
+		},
+		{
+			name: "presign is ignored for external callers",
+			ill:  premium,
+			req:  AssetRequester{WantPresign: true},
+			ctx:  URLContextList,
+			check: func(t *testing.T, got AssetURLs) {
+				signedClaims(t, got.ImageURL)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSettings(t)
+			useTestStorage(t)
+			if tt.setup != nil {
+				tt.setup()
+			}
+			tt.check(t, ResolveAssetURLs(tt.ill, tt.req, tt.ctx))
+		})
+	}
+}
+
+func TestAssetClaimsNarrowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims AssetClaims
+		want   bool
+	}{
+		{"original", AssetClaims{}, false},
+		{"explicit original as attachment", AssetClaims{Variant: AssetVariantOriginal, Disposition: DispositionAttachment}, false},
+		{"png rendition", AssetClaims{Variant: PNGVariant(256)}, true},
+		{"bound to an IP", AssetClaims{ClientIP: "10.0.0.1"}, true},
+		{"bound to a user", AssetClaims{Subject: "alice"}, true},
+		{"single use", AssetClaims{Nonce: "n"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.claims.Narrowed(); got != tt.want {
+			t.Errorf("%s: Narrowed() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
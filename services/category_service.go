@@ -1,10 +1,14 @@
 package services
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"open-illustrations-go/config"
 	"open-illustrations-go/models"
+
+	"gorm.io/gorm"
 )
 
 func CreateCategory(name string) (*models.Category, error) {
@@ -15,15 +19,20 @@ func CreateCategory(name string) (*models.Category, error) {
 	return &cat, nil
 }
 
-func GetCategories() ([]models.Category, error) {
+func GetCategories(shape QueryShape) ([]models.Category, error) {
 	var list []models.Category
-	res := config.DB.Where("deleted_at IS NULL").Find(&list)
+	res := shape.apply(config.DB).Find(&list)
 	return list, res.Error
 }
 
 func GetCategory(id string) (*models.Category, error) {
+	return FindCategory(id, QueryShape{})
+}
+
+// FindCategory loads one category with only the columns and relations in shape.
+func FindCategory(id string, shape QueryShape) (*models.Category, error) {
 	var c models.Category
-	res := config.DB.First(&c, id)
+	res := shape.apply(config.DB).First(&c, id)
 	if res.Error != nil {
 		return nil, res.Error
 	}
@@ -61,3 +70,33 @@ func SoftDeleteCategory(id string) (*models.Category, error) {
 	}
 	return &c, nil
 }
+
+// FindOrCreateCategory returns the category with the given name (ignoring case, as the
+// MySQL collation always did), creating it if needed.
+// A soft-deleted category with the same name is restored instead, since names are unique.
+func FindOrCreateCategory(name string) (*models.Category, error) {
+	name = strings.TrimSpace(name)
+	var c models.Category
+	err := config.DB.Unscoped().Where("LOWER(name) = LOWER(?)", name).First(&c).Error
+	if err == nil {
+		if c.DeletedAt.Valid {
+			if err := config.DB.Unscoped().Model(&c).Update("deleted_at", nil).Error; err != nil {
+				return nil, err
+			}
+			c.DeletedAt = gorm.DeletedAt{}
+		}
+		return &c, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	created, err := CreateCategory(name)
+	if err != nil {
+		// lost a race with a concurrent create; the row exists now
+		if err2 := config.DB.Where("LOWER(name) = LOWER(?)", name).First(&c).Error; err2 == nil {
+			return &c, nil
+		}
+		return nil, err
+	}
+	return created, nil
+}
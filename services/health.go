@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/migrations"
+)
+
+// Version is stamped at build time:
+//
+//	go build -ldflags "-X open-illustrations-go/services.Version=1.4.0"
+var Version = "dev"
+
+var (
+	startedAt    = time.Now()
+	shuttingDown atomic.Bool
+)
+
+// BeginShutdown makes readiness fail so load balancers stop sending traffic
+// while in-flight requests drain.
+func BeginShutdown() {
+	shuttingDown.Store(true)
+}
+
+// DependencyCheck is the result of one readiness probe.
+type DependencyCheck struct {
+	Name      string `json:"name"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+type probe struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// readinessTimeout bounds each dependency probe.
+const readinessTimeout = 2 * time.Second
+
+// CheckReadiness probes the database, the bucket, Redis (when configured) and
+// the schema level in parallel. The service is ready when every check passes
+// and it is not shutting down.
+func CheckReadiness(ctx context.Context) (bool, []DependencyCheck) {
+	probes := []probe{
+		{"database", pingDatabase},
+		{"storage", checkBucket},
+		{"migrations", checkMigrations},
+	}
+	if config.Redis != nil {
+		probes = append(probes, probe{"redis", func(ctx context.Context) error { return config.Redis.Ping(ctx).Err() }})
+	}
+
+	checks := make([]DependencyCheck, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+			start := time.Now()
+			err := p.fn(pctx)
+			checks[i] = DependencyCheck{Name: p.name, OK: err == nil, LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				checks[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	ready := !shuttingDown.Load()
+	if !ready {
+		checks = append(checks, DependencyCheck{Name: "shutdown", Error: "server is shutting down"})
+	}
+	for _, c := range checks {
+		ready = ready && c.OK
+	}
+	return ready, checks
+}
+
+func pingDatabase(ctx context.Context) error {
+	if config.DB == nil {
+		return errors.New("not connected")
+	}
+	sqlDB, err := config.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func checkBucket(ctx context.Context) error {
+	if config.MinioClient == nil {
+		return errors.New("not connected")
+	}
+	ok, err := config.MinioClient.BucketExists(ctx, config.BucketName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("bucket %q does not exist", config.BucketName)
+	}
+	return nil
+}
+
+func checkMigrations(ctx context.Context) error {
+	if config.DB == nil {
+		return errors.New("not connected")
+	}
+	lvl, err := migrations.CurrentLevel(ctx, config.DB)
+	if err != nil {
+		return err
+	}
+	if lvl.Pending > 0 {
+		return fmt.Errorf("%d pending migrations (at %d, latest %d)", lvl.Pending, lvl.Current, lvl.Latest)
+	}
+	return nil
+}
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version      string `json:"version"`
+	GoVersion    string `json:"go_version"`
+	Revision     string `json:"revision,omitempty"`
+	RevisionTime string `json:"revision_time,omitempty"`
+	Modified     bool   `json:"modified,omitempty"`
+}
+
+// ServiceStatus is the body of GET /api/v1/status.
+type ServiceStatus struct {
+	Build         BuildInfo         `json:"build"`
+	StartedAt     time.Time         `json:"started_at"`
+	UptimeSeconds int64             `json:"uptime_seconds"`
+	Ready         bool              `json:"ready"`
+	Migrations    *migrations.Level `json:"migrations,omitempty"`
+	Dependencies  []DependencyCheck `json:"dependencies"`
+}
+
+// Status reports the version, uptime, schema level and dependency checks.
+func Status(ctx context.Context) ServiceStatus {
+	ready, checks := CheckReadiness(ctx)
+	st := ServiceStatus{
+		Build:         buildInfo(),
+		StartedAt:     startedAt,
+		UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+		Ready:         ready,
+		Dependencies:  checks,
+	}
+	if config.DB != nil {
+		if lvl, err := migrations.CurrentLevel(ctx, config.DB); err == nil {
+			st.Migrations = &lvl
+		}
+	}
+	return st
+}
+
+func buildInfo() BuildInfo {
+	b := BuildInfo{Version: Version, GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Revision = s.Value
+		case "vcs.time":
+			b.RevisionTime = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
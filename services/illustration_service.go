@@ -2,20 +2,23 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"net/url"
-	"os"
 	"path/filepath"
-	"strconv"
 	"time"
 
 	"open-illustrations-go/config"
 	"open-illustrations-go/models"
+	"open-illustrations-go/tracing"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // func UploadObject(objectName string, r io.Reader, size int64, contentType string) error {
@@ -65,56 +68,45 @@ func CreateIllustrationRecord(ill *models.Illustration) error {
 	return config.DB.Create(ill).Error
 }
 
-func GetIllustrations() ([]models.Illustration, error) {
+func GetIllustrations(ctx context.Context, shape QueryShape) ([]models.Illustration, error) {
 	var illustrations []models.Illustration
-	result := config.DB.
-		Preload("CategoryRef").
-		Preload("PackRef").
-		Preload("StyleRef").
-		Where("deleted_at IS NULL").
+	result := shape.apply(config.DB.WithContext(ctx)).
 		Find(&illustrations)
 	return illustrations, result.Error
 }
 
-func GetIllustrationsByCategory(categoryID string) ([]models.Illustration, error) {
+func GetIllustrationsByCategory(ctx context.Context, categoryID string, shape QueryShape) ([]models.Illustration, error) {
 	var illustrations []models.Illustration
-	result := config.DB.
-		Preload("CategoryRef").
-		Preload("PackRef").
-		Preload("StyleRef").
-		Where("deleted_at IS NULL AND category_id = ?", categoryID).
+	result := shape.apply(config.DB.WithContext(ctx)).
+		Where("category_id = ?", categoryID).
 		Find(&illustrations)
 	return illustrations, result.Error
 }
 
-func GetIllustrationsByStyle(styleID string) ([]models.Illustration, error) {
+func GetIllustrationsByStyle(ctx context.Context, styleID string, shape QueryShape) ([]models.Illustration, error) {
 	var illustrations []models.Illustration
-	result := config.DB.
-		Preload("CategoryRef").
-		Preload("PackRef").
-		Preload("StyleRef").
-		Where("deleted_at IS NULL AND style_id = ?", styleID).
+	result := shape.apply(config.DB.WithContext(ctx)).
+		Where("style_id = ?", styleID).
 		Find(&illustrations)
 	return illustrations, result.Error
 }
 
-func GetIllustrationsByPack(packID string) ([]models.Illustration, error) {
+func GetIllustrationsByPack(ctx context.Context, packID string, shape QueryShape) ([]models.Illustration, error) {
 	var illustrations []models.Illustration
-	result := config.DB.
-		Preload("CategoryRef").
-		Preload("PackRef").
-		Preload("StyleRef").
-		Where("deleted_at IS NULL AND pack_id = ?", packID).
+	result := shape.apply(config.DB.WithContext(ctx)).
+		Where("pack_id = ?", packID).
 		Find(&illustrations)
 	return illustrations, result.Error
 }
 
-func GetIllustration(id string) (*models.Illustration, error) {
+func GetIllustration(ctx context.Context, id string) (*models.Illustration, error) {
+	return FindIllustration(ctx, id, illustrationRefs)
+}
+
+// FindIllustration loads one illustration with only the columns and relations in shape.
+func FindIllustration(ctx context.Context, id string, shape QueryShape) (*models.Illustration, error) {
 	var illustration models.Illustration
-	result := config.DB.
-		Preload("CategoryRef").
-		Preload("PackRef").
-		Preload("StyleRef").
+	result := shape.apply(config.DB.WithContext(ctx)).
 		First(&illustration, id)
 	if result.Error != nil {
 		return nil, result.Error
@@ -122,41 +114,60 @@ func GetIllustration(id string) (*models.Illustration, error) {
 	return &illustration, nil
 }
 
-func minioObjectExists(objectName string) (bool, error) {
-	_, err := config.MinioClient.StatObject(context.Background(), config.BucketName, objectName, minio.StatObjectOptions{})
+func minioObjectExists(ctx context.Context, objectName string) (bool, error) {
+	_, err := config.MinioClient.StatObject(ctx, config.BucketName, objectName, minio.StatObjectOptions{})
 	if err != nil {
 		return false, nil
 	}
 	return true, nil
 }
 
-func CreateIllustration(ill *models.Illustration) error {
-	ok, err := minioObjectExists(ill.StorageKey)
+// ErrObjectMissing means a record was created for a storage key that has no object.
+var ErrObjectMissing = errors.New("file not found in bucket")
+
+func CreateIllustration(ctx context.Context, ill *models.Illustration) error {
+	ok, err := minioObjectExists(ctx, ill.StorageKey)
 	if err != nil {
-		return fmt.Errorf("minio check failed: %w", err)
+		return fmt.Errorf("%w: %v", ErrStorageCheck, err)
 	}
 	if !ok {
-		return fmt.Errorf("file not found in MinIO bucket '%s': %s", config.BucketName, ill.StorageKey)
+		return fmt.Errorf("%w: %s", ErrObjectMissing, ill.StorageKey)
 	}
 
-	return config.DB.Create(ill).Error
+	if err := config.DB.WithContext(ctx).Create(ill).Error; err != nil {
+		return err
+	}
+	PackMembershipChanged(ill.PackID)
+	return nil
 }
 
 func DeleteIllustration(id string) error {
-	return config.DB.Delete(&models.Illustration{}, id).Error
+	var ill models.Illustration
+	if err := config.DB.First(&ill, id).Error; err != nil {
+		return err
+	}
+	if err := config.DB.Delete(&ill).Error; err != nil {
+		return err
+	}
+	PackMembershipChanged(ill.PackID)
+	return nil
 }
 
-func GetDownloadURL(storageKey string, duration time.Duration) (string, error) {
-	ctx := context.Background()
+func GetDownloadURL(ctx context.Context, storageKey string, duration time.Duration) (string, error) {
+	// presigning is local, so the transport never sees it; give it its own span
+	ctx, span := tracing.Tracer().Start(ctx, "storage presign",
+		trace.WithAttributes(attribute.String("storage.key", storageKey)))
+	defer span.End()
+
 	reqParams := make(url.Values)
 
 	cli := config.MinioClient // default: endpoint internal (mini:9000)
 
 	// Jika MINIO_PUBLIC_BASE_URL diset (mis. http://localhost:9000), buat client khusus presign
-	if base := os.Getenv("MINIO_PUBLIC_BASE_URL"); base != "" {
+	if base := settings.Storage.PublicBaseURL; base != "" {
 		if pub, err := url.Parse(base); err == nil && pub.Host != "" {
 			pubCli, err := minio.New(pub.Host, &minio.Options{
-				Creds:  credentials.NewStaticV4(os.Getenv("MINIO_ROOT_USER"), os.Getenv("MINIO_ROOT_PASSWORD"), ""),
+				Creds:  credentials.NewStaticV4(settings.Storage.AccessKey, settings.Storage.SecretKey, ""),
 				Secure: pub.Scheme == "https",
 				Region: "us-east-1",
 			})
@@ -168,27 +179,27 @@ func GetDownloadURL(storageKey string, duration time.Duration) (string, error) {
 
 	u, err := cli.PresignedGetObject(ctx, config.BucketName, storageKey, duration, reqParams)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return "", err
 	}
 	return u.String(), nil
 }
 
 // PresignTTL returns the server-enforced TTL for presigned URLs.
-// It ignores any client-provided values and reads from PRESIGN_TTL_SECONDS env var.
-// Clamped to [60, 3600] seconds; defaults to 600 if unset/invalid.
+// It ignores any client-provided values; PRESIGN_TTL_SECONDS sets it (default
+// 600, validated to [60, 3600] at startup).
 func PresignTTL() time.Duration {
-	v := os.Getenv("PRESIGN_TTL_SECONDS")
-	n := 600
-	if v != "" {
-		if parsed, err := strconv.Atoi(v); err == nil {
-			n = parsed
-		}
-	}
-	if n < 60 {
-		n = 60
-	}
-	if n > 3600 {
-		n = 3600
+	return time.Duration(settings.Storage.PresignTTLSeconds) * time.Second
+}
+
+// ExportIllustrations loads the whole catalogue with its categories, packs and
+// styles, oldest first. Soft-deleted illustrations are included on request.
+func ExportIllustrations(includeDeleted bool) ([]models.Illustration, error) {
+	db := illustrationRefs.apply(config.DB)
+	if includeDeleted {
+		db = db.Unscoped()
 	}
-	return time.Duration(n) * time.Second
+	var illustrations []models.Illustration
+	err := db.Order("id").Find(&illustrations).Error
+	return illustrations, err
 }
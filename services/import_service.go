@@ -0,0 +1,544 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImportNotZip      = errors.New("archive is not a valid ZIP file")
+	ErrImportEmpty       = errors.New("archive contains no .svg files")
+	ErrImportTooLarge    = errors.New("archive contains too many files")
+	ErrImportFileTooBig  = errors.New("archive entry is too large")
+	ErrImportTooBig      = errors.New("archive is too large when uncompressed")
+	ErrImportBadManifest = errors.New("invalid manifest")
+	ErrImportNotFound    = errors.New("import not found")
+	ErrImportDuplicate   = errors.New("archive contains duplicate entries")
+)
+
+// ImportManifestEntry maps one file in the archive to its illustration fields.
+// File is matched against the path inside the ZIP, or its base name. A nil
+// IsPremium falls back to ImportDefaults.IsPremium.
+type ImportManifestEntry struct {
+	File      string   `json:"file"`
+	Title     string   `json:"title"`
+	Category  string   `json:"category"`
+	Style     string   `json:"style"`
+	Pack      string   `json:"pack"`
+	Tags      []string `json:"tags"`
+	IsPremium *bool    `json:"is_premium"`
+}
+
+// ImportDefaults apply to every file that the manifest doesn't describe (or leaves blank).
+type ImportDefaults struct {
+	Category  string
+	Style     string
+	Pack      string
+	IsPremium bool
+}
+
+// ImportMaxFiles caps the number of SVGs in one archive (IMPORT_MAX_FILES, default 2000).
+func ImportMaxFiles() int {
+	return settings.Uploads.ImportMaxFiles
+}
+
+// maxManifestBytes caps a manifest read from inside the archive.
+const maxManifestBytes = 4 << 20
+
+// ParseImportManifest reads a manifest.json (an array of entries, or {"files": [...]})
+// or a CSV with a header row: file,title,category,style,pack,tags,is_premium.
+// CSV tags are separated by ';' or '|'.
+func ParseImportManifest(name string, r io.Reader) ([]ImportManifestEntry, error) {
+	if strings.HasSuffix(strings.ToLower(name), ".csv") {
+		return parseCSVManifest(r)
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var list []ImportManifestEntry
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var wrapped struct {
+		Files []ImportManifestEntry `json:"files"`
+	}
+	if err := json.Unmarshal(raw, &wrapped); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportBadManifest, err)
+	}
+	return wrapped.Files, nil
+}
+
+func parseCSVManifest(r io.Reader) ([]ImportManifestEntry, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportBadManifest, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	col := map[string]int{}
+	for i, h := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["file"]; !ok {
+		return nil, fmt.Errorf("%w: CSV header must contain a 'file' column", ErrImportBadManifest)
+	}
+	get := func(row []string, key string) string {
+		if i, ok := col[key]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	out := make([]ImportManifestEntry, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		var premium *bool
+		if v := get(row, "is_premium"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: row %d: is_premium %q is not a boolean", ErrImportBadManifest, i+2, v)
+			}
+			premium = &b
+		}
+		out = append(out, ImportManifestEntry{
+			File:      get(row, "file"),
+			Title:     get(row, "title"),
+			Category:  get(row, "category"),
+			Style:     get(row, "style"),
+			Pack:      get(row, "pack"),
+			Tags:      strings.FieldsFunc(get(row, "tags"), func(r rune) bool { return r == ';' || r == '|' }),
+			IsPremium: premium,
+		})
+	}
+	return out, nil
+}
+
+// CreateImport validates the archive, stores it in the bucket and records one
+// pending item per SVG. The actual import runs in the background (see RunImport).
+// An external manifest (manifestName/manifest) takes precedence over one inside the ZIP.
+func CreateImport(archive io.ReaderAt, size int64, manifestName string, manifest io.Reader, defaults ImportDefaults) (*models.ImportJob, error) {
+	zr, err := zip.NewReader(archive, size)
+	if err != nil {
+		return nil, ErrImportNotZip
+	}
+
+	var entries []ImportManifestEntry
+	if manifest != nil {
+		if entries, err = ParseImportManifest(manifestName, manifest); err != nil {
+			return nil, err
+		}
+	} else if entries, err = manifestFromZip(zr); err != nil {
+		return nil, err
+	}
+	items, err := importItems(zr, entries, defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	job := models.ImportJob{
+		Status:     models.ImportStatusQueued,
+		ArchiveKey: fmt.Sprintf("imports/%s.zip", time.Now().Format("20060102-150405.000000000")),
+		Total:      len(items),
+	}
+	if err := UploadObject(job.ArchiveKey, io.NewSectionReader(archive, 0, size), size, "application/zip"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageUpload, err)
+	}
+	job.Items = items
+	if err := config.DB.Create(&job).Error; err != nil {
+		removeObjectQuietly(job.ArchiveKey)
+		return nil, err
+	}
+	return &job, nil
+}
+
+// importItems lists the SVGs of the archive with their manifest fields. The
+// sizes come from the ZIP headers; the import reads no more than they declare.
+func importItems(zr *zip.Reader, entries []ImportManifestEntry, defaults ImportDefaults) ([]models.ImportItem, error) {
+	byPath := map[string]ImportManifestEntry{}
+	for _, e := range entries {
+		byPath[strings.TrimPrefix(path.Clean("/"+e.File), "/")] = e
+	}
+
+	maxFile, maxTotal := settings.Uploads.ImportMaxFileBytes, settings.Uploads.ImportMaxTotalBytes
+	var items []models.ImportItem
+	var total uint64
+	seen := map[string]bool{}
+	for _, f := range zr.File {
+		// RunImport looks files up by name, so a second entry would shadow the first
+		if seen[f.Name] {
+			return nil, fmt.Errorf("%w: %s", ErrImportDuplicate, f.Name)
+		}
+		seen[f.Name] = true
+		if !isImportableEntry(f) {
+			continue
+		}
+		if f.UncompressedSize64 > uint64(maxFile) {
+			return nil, fmt.Errorf("%w: %s is %d bytes, the limit is %d", ErrImportFileTooBig, f.Name, f.UncompressedSize64, maxFile)
+		}
+		if total += f.UncompressedSize64; total > uint64(maxTotal) {
+			return nil, fmt.Errorf("%w: the limit is %d bytes", ErrImportTooBig, maxTotal)
+		}
+		e, ok := byPath[f.Name]
+		if !ok {
+			e = byPath[path.Base(f.Name)]
+		}
+		premium := defaults.IsPremium
+		if e.IsPremium != nil {
+			premium = *e.IsPremium
+		}
+		item := models.ImportItem{
+			Path:      f.Name,
+			Title:     firstNonEmpty(e.Title, titleFromFileName(f.Name)),
+			Category:  firstNonEmpty(e.Category, defaults.Category),
+			Style:     firstNonEmpty(e.Style, defaults.Style),
+			Pack:      firstNonEmpty(e.Pack, defaults.Pack),
+			Tags:      JoinTags(e.Tags),
+			IsPremium: premium,
+			Status:    models.ImportItemPending,
+		}
+		if msg := overlongImportField(item); msg != "" {
+			// fail just this file; the values are cut so that the item row fits
+			item.Status, item.Error = models.ImportItemFailed, msg
+			item.Path, item.Title, item.Tags = truncate(item.Path, 500), truncate(item.Title, 200), truncate(item.Tags, 500)
+			item.Category, item.Style, item.Pack = truncate(item.Category, 100), truncate(item.Style, 100), truncate(item.Pack, 100)
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return nil, ErrImportEmpty
+	}
+	if len(items) > ImportMaxFiles() {
+		return nil, ErrImportTooLarge
+	}
+	return items, nil
+}
+
+// overlongImportField describes the first value of item that is longer than
+// its column (in the import item or the illustration it becomes), or "".
+func overlongImportField(item models.ImportItem) string {
+	for _, f := range []struct {
+		name, value string
+		max         int
+	}{
+		{"path", item.Path, 500},
+		{"file name", path.Base(item.Path), 191},
+		{"title", item.Title, 200},
+		{"category", item.Category, 100},
+		{"style", item.Style, 100},
+		{"pack", item.Pack, 100},
+		{"tags", item.Tags, 500},
+	} {
+		if n := utf8.RuneCountInString(f.value); n > f.max {
+			return fmt.Sprintf("%s is %d characters, the limit is %d", f.name, n, f.max)
+		}
+	}
+	return ""
+}
+
+func GetImport(id uint) (*models.ImportJob, error) {
+	var job models.ImportJob
+	err := config.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).First(&job, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrImportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+const JobTypeImport = "import.run"
+
+type importJobPayload struct {
+	ImportID uint `json:"import_id"`
+}
+
+// EnqueueImport schedules RunImport on the job queue and links the job to the
+// import. If the job can't be queued the import is marked failed and its
+// archive removed.
+func EnqueueImport(ctx context.Context, imp *models.ImportJob) error {
+	job, err := Jobs.Enqueue(ctx, JobTypeImport, importJobPayload{ImportID: imp.ID}, JobOptions{
+		UniqueKey: fmt.Sprintf("import:%d", imp.ID),
+	})
+	if err != nil {
+		// nothing will ever run it
+		failImport(imp, err)
+		removeObjectQuietly(imp.ArchiveKey)
+		return err
+	}
+	imp.JobID = &job.ID
+	return config.DB.Model(imp).Update("job_id", job.ID).Error
+}
+
+func handleImportJob(ctx context.Context, job *models.Job) error {
+	var p importJobPayload
+	if err := DecodeJobPayload(job, &p); err != nil {
+		return err
+	}
+	return RunImport(ctx, p.ImportID, job.Attempts >= job.MaxAttempts)
+}
+
+// ErrImportRetry is returned by RunImport when storage errors left files
+// pending; running the import again picks them up.
+var ErrImportRetry = errors.New("import incomplete, retrying")
+
+// isTransientImportError reports whether importing a file failed because of
+// storage rather than the file itself, so a later attempt may succeed.
+func isTransientImportError(err error) bool {
+	return errors.Is(err, ErrStorageCheck) || errors.Is(err, ErrStorageUpload) || errors.Is(err, ErrRecordNotStored)
+}
+
+// RunImport processes every pending item of an import job. It is safe to call
+// again after a crash: items that were already imported are skipped. Files hit
+// by a storage error stay pending and RunImport returns ErrImportRetry, unless
+// this is the last attempt, in which case they are marked failed. When ctx is
+// cancelled (e.g. on shutdown) RunImport stops between files, leaves the rest
+// pending and returns ctx.Err().
+func RunImport(ctx context.Context, jobID uint, lastAttempt bool) error {
+	var job models.ImportJob
+	if err := config.DB.First(&job, jobID).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	config.DB.Model(&job).Updates(map[string]interface{}{"status": models.ImportStatusRunning, "started_at": &now})
+
+	zr, cleanup, err := openStoredArchive(job.ArchiveKey)
+	if err != nil {
+		if errors.Is(err, ErrImportNotZip) {
+			failImport(&job, err)
+			return fmt.Errorf("%w: %w", ErrJobPermanent, err)
+		}
+		if lastAttempt {
+			failImport(&job, err)
+		} else {
+			config.DB.Model(&job).Updates(map[string]interface{}{"status": models.ImportStatusQueued, "error": truncate(err.Error(), 500)})
+		}
+		return err
+	}
+	defer cleanup()
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var items []models.ImportItem
+	if err := config.DB.Where("import_job_id = ? AND status = ?", job.ID, models.ImportItemPending).Order("id").Find(&items).Error; err != nil {
+		failImport(&job, err)
+		return err
+	}
+	var pending int
+	for i := range items {
+		if ctx.Err() != nil {
+			// keep the archive for the next attempt
+			config.DB.Model(&job).Update("status", models.ImportStatusQueued)
+			return ctx.Err()
+		}
+		item := &items[i]
+		ill, err := importItem(item, files[item.Path])
+		if err != nil && isTransientImportError(err) && !lastAttempt {
+			slog.Warn("import file hit a storage error, will retry", "import_id", job.ID, "path", item.Path, "error", err)
+			pending++
+			continue
+		}
+		if err != nil {
+			item.Status = models.ImportItemFailed
+			item.Error = truncate(err.Error(), 500)
+		} else {
+			item.Status = models.ImportItemImported
+			item.IllustrationID = &ill.ID
+		}
+		if err := config.DB.Model(item).Updates(map[string]interface{}{
+			"status":          item.Status,
+			"error":           item.Error,
+			"illustration_id": item.IllustrationID,
+		}).Error; err != nil {
+			slog.Error("import item update failed", "error", err)
+		}
+	}
+
+	var succeeded, failed int64
+	config.DB.Model(&models.ImportItem{}).Where("import_job_id = ? AND status = ?", job.ID, models.ImportItemImported).Count(&succeeded)
+	config.DB.Model(&models.ImportItem{}).Where("import_job_id = ? AND status = ?", job.ID, models.ImportItemFailed).Count(&failed)
+	if pending > 0 {
+		// keep the archive for the next attempt
+		config.DB.Model(&job).Updates(map[string]interface{}{
+			"status":    models.ImportStatusQueued,
+			"succeeded": succeeded,
+			"failed":    failed,
+		})
+		return fmt.Errorf("%w: %d files hit a storage error", ErrImportRetry, pending)
+	}
+	done := time.Now()
+	config.DB.Model(&job).Updates(map[string]interface{}{
+		"status":      models.ImportStatusCompleted,
+		"succeeded":   succeeded,
+		"failed":      failed,
+		"finished_at": &done,
+	})
+	removeObjectQuietly(job.ArchiveKey)
+	return nil
+}
+
+func importItem(item *models.ImportItem, f *zip.File) (*models.Illustration, error) {
+	if f == nil {
+		return nil, errors.New("file missing from archive")
+	}
+	in := IngestInput{
+		Title:     item.Title,
+		FileName:  path.Base(item.Path),
+		IsPremium: item.IsPremium,
+	}
+	if item.Tags != "" {
+		in.Tags = strings.Split(item.Tags, ",")
+	}
+	if item.Category != "" {
+		c, err := FindOrCreateCategory(item.Category)
+		if err != nil {
+			return nil, fmt.Errorf("category %q: %w", item.Category, err)
+		}
+		in.CategoryID = &c.ID
+	}
+	if item.Style != "" {
+		s, err := FindOrCreateStyle(item.Style)
+		if err != nil {
+			return nil, fmt.Errorf("style %q: %w", item.Style, err)
+		}
+		in.StyleID = &s.ID
+	}
+	if item.Pack != "" {
+		p, err := FindOrCreatePack(item.Pack)
+		if err != nil {
+			return nil, fmt.Errorf("pack %q: %w", item.Pack, err)
+		}
+		in.PackID = &p.ID
+	}
+
+	size := int64(f.UncompressedSize64)
+	if size > settings.Uploads.ImportMaxFileBytes {
+		return nil, fmt.Errorf("%w: %d bytes", ErrImportFileTooBig, size)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	// never read past the size the header declared, whatever the data says
+	return IngestIllustration(in, io.LimitReader(rc, size), size)
+}
+
+func failImport(job *models.ImportJob, err error) {
+	done := time.Now()
+	config.DB.Model(job).Updates(map[string]interface{}{
+		"status":      models.ImportStatusFailed,
+		"error":       truncate(err.Error(), 500),
+		"finished_at": &done,
+	})
+}
+
+// openStoredArchive copies the archive to a temp file, since zip needs random access.
+func openStoredArchive(key string) (*zip.Reader, func(), error) {
+	obj, err := config.MinioClient.GetObject(context.Background(), config.BucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer obj.Close()
+	tmp, err := os.CreateTemp("", "import-*.zip")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	n, err := io.Copy(tmp, obj)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	zr, err := zip.NewReader(tmp, n)
+	if err != nil {
+		cleanup()
+		return nil, nil, ErrImportNotZip
+	}
+	return zr, cleanup, nil
+}
+
+func manifestFromZip(zr *zip.Reader) ([]ImportManifestEntry, error) {
+	for _, f := range zr.File {
+		name := strings.ToLower(f.Name)
+		if name != "manifest.json" && name != "manifest.csv" {
+			continue
+		}
+		if f.UncompressedSize64 > maxManifestBytes {
+			return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrImportBadManifest, f.Name, maxManifestBytes)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrImportBadManifest, err)
+		}
+		defer rc.Close()
+		return ParseImportManifest(name, io.LimitReader(rc, maxManifestBytes))
+	}
+	return nil, nil
+}
+
+func isImportableEntry(f *zip.File) bool {
+	if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") {
+		return false
+	}
+	base := path.Base(f.Name)
+	return !strings.HasPrefix(base, ".") && strings.HasSuffix(strings.ToLower(base), ".svg")
+}
+
+// titleFromFileName turns "happy-new_year.svg" into "Happy New Year".
+func titleFromFileName(name string) string {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	words := strings.FieldsFunc(base, func(r rune) bool { return r == '-' || r == '_' || r == ' ' })
+	for i, w := range words {
+		r, n := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToTitle(r)) + w[n:]
+	}
+	return strings.Join(words, " ")
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// truncate keeps values within their column size. It cuts s to at most n bytes,
+// backing off to a rune boundary so the result stays valid UTF-8.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
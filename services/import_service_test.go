@@ -0,0 +1,374 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="1" height="1"></svg>`
+
+// zipOf builds an archive from name → content pairs.
+func zipOf(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	raw := zipBytes(t, files)
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func zipBytes(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTitleFromFileName(t *testing.T) {
+	tests := map[string]string{
+		"happy-new_year.svg":   "Happy New Year",
+		"icons/rocket.svg":     "Rocket",
+		"élan-vital.svg":       "Élan Vital",
+		"ñandú_azul.svg":       "Ñandú Azul",
+		"日本-art.svg":           "日本 Art",
+		"ǆungla.svg":           "ǅungla",
+		"--double--dash--.svg": "Double Dash",
+	}
+	for in, want := range tests {
+		if got := titleFromFileName(in); got != want {
+			t.Errorf("titleFromFileName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestManifestIsPremium(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		manifest string
+		want     []*bool
+	}{
+		{"json", `[{"file":"a.svg","is_premium":true},{"file":"b.svg","is_premium":false},{"file":"c.svg"}]`, []*bool{&yes, &no, nil}},
+		{"csv", "file,is_premium\na.svg,true\nb.svg,false\nc.svg,\n", []*bool{&yes, &no, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := "manifest." + tt.name
+			entries, err := ParseImportManifest(name, strings.NewReader(tt.manifest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			items, err := importItems(zipOf(t, map[string]string{"a.svg": testSVG, "b.svg": testSVG, "c.svg": testSVG}), entries, ImportDefaults{IsPremium: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			premium := map[string]bool{}
+			for _, it := range items {
+				premium[it.Path] = it.IsPremium
+			}
+			// a blank is_premium takes the default (true); an explicit false wins over it
+			if !premium["a.svg"] || premium["b.svg"] || !premium["c.svg"] {
+				t.Errorf("is_premium = %v, want a and c premium", premium)
+			}
+			for i, e := range entries {
+				if (e.IsPremium == nil) != (tt.want[i] == nil) || (e.IsPremium != nil && *e.IsPremium != *tt.want[i]) {
+					t.Errorf("entry %d: IsPremium = %v, want %v", i, e.IsPremium, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := ParseImportManifest("manifest.csv", strings.NewReader("file,is_premium\na.svg,maybe\n")); !errors.Is(err, ErrImportBadManifest) {
+		t.Errorf("invalid is_premium: got %v, want ErrImportBadManifest", err)
+	}
+}
+
+func TestImportSizeLimits(t *testing.T) {
+	useTestSettings(t)
+	settings.Uploads.ImportMaxFileBytes = 100
+	settings.Uploads.ImportMaxTotalBytes = 150
+	small := strings.Repeat("x", 80)
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  error
+	}{
+		{"within the limits", map[string]string{"a.svg": small}, nil},
+		{"one file too large", map[string]string{"a.svg": strings.Repeat("x", 101)}, ErrImportFileTooBig},
+		{"too large in total", map[string]string{"a.svg": small, "b.svg": small}, ErrImportTooBig},
+		{"other files do not count", map[string]string{"a.svg": small, "notes.txt": strings.Repeat("x", 1000)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := importItems(zipOf(t, tt.files), nil, ImportDefaults{})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	bomb := zipOf(t, map[string]string{"manifest.json": "[" + strings.Repeat(" ", maxManifestBytes) + "]", "a.svg": small})
+	if _, err := manifestFromZip(bomb); !errors.Is(err, ErrImportBadManifest) {
+		t.Errorf("oversized manifest: got %v, want ErrImportBadManifest", err)
+	}
+}
+
+// fakeBucket is an S3 endpoint serving objects from memory. Uploads and
+// deletions are counted, and uploads are refused while rejectUploads is set.
+type fakeBucket struct {
+	objects       map[string][]byte
+	rejectUploads atomic.Bool
+	uploads       atomic.Int32
+	deletes       atomic.Int32
+}
+
+// useFakeStorage points config.MinioClient at b for the rest of the test.
+func useFakeStorage(t *testing.T, b *fakeBucket) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, found := b.objects[strings.TrimPrefix(r.URL.Path, "/illustrations/")]
+		switch {
+		case r.Method == http.MethodGet && found:
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Header().Set("ETag", `"object"`)
+			w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+			w.Write(data)
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut && b.rejectUploads.Load():
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>storage unavailable</Message></Error>`))
+		case r.Method == http.MethodPut:
+			b.uploads.Add(1)
+			w.Header().Set("ETag", `"object"`)
+		case r.Method == http.MethodDelete:
+			b.deletes.Add(1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	cli, err := minio.New(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("access", "secret-key", ""),
+		Region: "us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevClient, prevBucket := config.MinioClient, config.BucketName
+	config.MinioClient, config.BucketName = cli, "illustrations"
+	t.Cleanup(func() { config.MinioClient, config.BucketName = prevClient, prevBucket })
+}
+
+func TestRunImportRetriesStorageErrors(t *testing.T) {
+	files := map[string]string{"a.svg": testSVG, "b.svg": testSVG}
+	newImport := func(t *testing.T) *models.ImportJob {
+		t.Helper()
+		useTestDB(t)
+		useTestSettings(t)
+		items, err := importItems(zipOf(t, files), nil, ImportDefaults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		job := models.ImportJob{Status: models.ImportStatusQueued, ArchiveKey: "imports/test.zip", Total: len(items), Items: items}
+		if err := config.DB.Create(&job).Error; err != nil {
+			t.Fatal(err)
+		}
+		return &job
+	}
+	statuses := func(t *testing.T, id uint) (string, map[string]int) {
+		t.Helper()
+		imp, err := GetImport(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := map[string]int{}
+		for _, it := range imp.Items {
+			count[it.Status]++
+		}
+		return imp.Status, count
+	}
+
+	t.Run("items stay pending until storage recovers", func(t *testing.T) {
+		bucket := &fakeBucket{objects: map[string][]byte{"imports/test.zip": zipBytes(t, files)}}
+		bucket.rejectUploads.Store(true)
+		useFakeStorage(t, bucket)
+		imp := newImport(t)
+
+		if err := RunImport(context.Background(), imp.ID, false); !errors.Is(err, ErrImportRetry) {
+			t.Fatalf("RunImport = %v, want ErrImportRetry", err)
+		}
+		if status, items := statuses(t, imp.ID); status != models.ImportStatusQueued || items[models.ImportItemPending] != 2 {
+			t.Fatalf("after a storage error: import %s, items %v; want queued with 2 pending", status, items)
+		}
+
+		bucket.rejectUploads.Store(false)
+		if err := RunImport(context.Background(), imp.ID, false); err != nil {
+			t.Fatalf("RunImport after recovery = %v", err)
+		}
+		if status, items := statuses(t, imp.ID); status != models.ImportStatusCompleted || items[models.ImportItemImported] != 2 {
+			t.Errorf("after recovery: import %s, items %v; want completed with 2 imported", status, items)
+		}
+	})
+
+	t.Run("the last attempt fails what is left", func(t *testing.T) {
+		bucket := &fakeBucket{objects: map[string][]byte{"imports/test.zip": zipBytes(t, files)}}
+		bucket.rejectUploads.Store(true)
+		useFakeStorage(t, bucket)
+		imp := newImport(t)
+
+		if err := RunImport(context.Background(), imp.ID, true); err != nil {
+			t.Fatalf("RunImport = %v", err)
+		}
+		if status, items := statuses(t, imp.ID); status != models.ImportStatusCompleted || items[models.ImportItemFailed] != 2 {
+			t.Errorf("import %s, items %v; want completed with 2 failed", status, items)
+		}
+	})
+}
+
+func TestRunImportStopsWhenCancelled(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	files := map[string]string{"a.svg": testSVG, "b.svg": testSVG}
+	useFakeStorage(t, &fakeBucket{objects: map[string][]byte{"imports/test.zip": zipBytes(t, files)}})
+	items, err := importItems(zipOf(t, files), nil, ImportDefaults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := models.ImportJob{Status: models.ImportStatusQueued, ArchiveKey: "imports/test.zip", Total: len(items), Items: items}
+	if err := config.DB.Create(&job).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := RunImport(ctx, job.ID, true); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunImport = %v, want context.Canceled", err)
+	}
+	imp, err := GetImport(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Status != models.ImportStatusQueued {
+		t.Errorf("status = %s, want queued", imp.Status)
+	}
+	for _, it := range imp.Items {
+		if it.Status != models.ImportItemPending {
+			t.Errorf("%s is %s, want pending", it.Path, it.Status)
+		}
+	}
+}
+
+func TestImportItemsRejectsDuplicateEntries(t *testing.T) {
+	useTestSettings(t)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, content := range []string{testSVG, "<svg/>"} {
+		w, err := zw.Create("a.svg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importItems(zr, nil, ImportDefaults{}); !errors.Is(err, ErrImportDuplicate) {
+		t.Errorf("got %v, want ErrImportDuplicate", err)
+	}
+}
+
+func TestImportItemsFailsOverlongValues(t *testing.T) {
+	useTestSettings(t)
+	long := strings.Repeat("é", 201)
+	entries := []ImportManifestEntry{{File: "a.svg", Title: long}, {File: "b.svg", Category: strings.Repeat("c", 101)}}
+	items, err := importItems(zipOf(t, map[string]string{"a.svg": testSVG, "b.svg": testSVG, "c.svg": testSVG}), entries, ImportDefaults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range items {
+		wantFailed := it.Path != "c.svg"
+		if (it.Status == models.ImportItemFailed) != wantFailed {
+			t.Errorf("%s is %s (%s)", it.Path, it.Status, it.Error)
+		}
+		if utf8.RuneCountInString(it.Title) > 200 || len(it.Category) > 100 || !utf8.ValidString(it.Title) {
+			t.Errorf("%s was stored with title %d and category %d characters", it.Path, utf8.RuneCountInString(it.Title), len(it.Category))
+		}
+	}
+
+	// the failed items are stored instead of failing the whole import
+	useTestDB(t)
+	job := models.ImportJob{Status: models.ImportStatusQueued, ArchiveKey: "imports/test.zip", Total: len(items), Items: items}
+	if err := config.DB.Create(&job).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failingEnqueue is a job store that refuses new jobs.
+type failingEnqueue struct{ JobStore }
+
+func (failingEnqueue) Enqueue(context.Context, *models.Job) error {
+	return errors.New("queue unavailable")
+}
+
+func TestEnqueueImportFailureCleansUp(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	bucket := &fakeBucket{}
+	useFakeStorage(t, bucket)
+	prev := Jobs
+	Jobs = NewJobQueue(failingEnqueue{NewMemoryJobStore()}, 1)
+	t.Cleanup(func() { Jobs = prev })
+
+	imp := models.ImportJob{Status: models.ImportStatusQueued, ArchiveKey: "imports/test.zip"}
+	if err := config.DB.Create(&imp).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := EnqueueImport(context.Background(), &imp); err == nil {
+		t.Fatal("EnqueueImport succeeded")
+	}
+	got, err := GetImport(imp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.ImportStatusFailed || got.Error == "" {
+		t.Errorf("import = %s (%q), want failed with the error", got.Status, got.Error)
+	}
+	if bucket.deletes.Load() != 1 {
+		t.Errorf("archive deleted %d times, want 1", bucket.deletes.Load())
+	}
+}
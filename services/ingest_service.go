@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTitleRequired   = errors.New("title is required")
+	ErrNotSVG          = errors.New("only .svg files are allowed")
+	ErrObjectExists    = errors.New("file already exists in bucket")
+	ErrStorageCheck    = errors.New("minio check failed")
+	ErrStorageUpload   = errors.New("failed to upload to storage")
+	ErrRecordNotStored = errors.New("failed to save record")
+)
+
+// IngestInput describes an illustration whose file still has to be written to storage.
+type IngestInput struct {
+	Title       string
+	FileName    string
+	StyleID     *uint
+	CategoryID  *uint
+	PackID      *uint
+	IsPremium   bool
+	Tags        []string
+	ContentType string
+	// SourceName is the name of the uploaded file when FileName overrides it;
+	// the .svg extension is checked on the uploaded file, not on the override.
+	SourceName string
+	// Created, if set, runs in the transaction that writes the illustration,
+	// so the caller can record the result atomically with it.
+	Created func(tx *gorm.DB, ill *models.Illustration) error
+}
+
+// ValidateIngestName checks the fields that can be verified before any bytes arrive.
+func ValidateIngestName(title, fileName string) error {
+	if strings.TrimSpace(title) == "" {
+		return ErrTitleRequired
+	}
+	if !strings.HasSuffix(strings.ToLower(fileName), ".svg") {
+		return ErrNotSVG
+	}
+	return nil
+}
+
+// ValidateSVG validates SVG only (by extension + light content check on the first bytes).
+func ValidateSVG(fileName string, head []byte) error {
+	if !strings.HasSuffix(strings.ToLower(fileName), ".svg") {
+		return ErrNotSVG
+	}
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	if !strings.Contains(strings.ToLower(string(head)), "<svg") {
+		return ErrNotSVG
+	}
+	return nil
+}
+
+// IngestIllustration validates the file, stores it under a fresh storage key and
+// creates the illustration record. It is shared by every upload path (multipart,
+// tus, bulk import) so they all apply the same rules.
+func IngestIllustration(in IngestInput, r io.Reader, size int64) (*models.Illustration, error) {
+	source := firstNonEmpty(in.SourceName, in.FileName)
+	if err := ValidateIngestName(in.Title, source); err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read upload: %w", err)
+	}
+	head = head[:n]
+	if err := ValidateSVG(source, head); err != nil {
+		return nil, err
+	}
+
+	// Generate unique storage key (random hex) while preserving original filename separately
+	storageKey := GenerateStorageKey(in.FileName)
+
+	exists, err := MinioObjectExists(storageKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageCheck, err)
+	}
+	if exists {
+		return nil, ErrObjectExists
+	}
+
+	body := io.MultiReader(bytes.NewReader(head), r)
+	if err := UploadObject(storageKey, body, size, in.ContentType); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageUpload, err)
+	}
+
+	rec := models.Illustration{
+		Title:      in.Title,
+		StyleID:    in.StyleID,
+		CategoryID: in.CategoryID,
+		PackID:     in.PackID,
+		FileName:   in.FileName,
+		StorageKey: storageKey,
+		IsPremium:  in.IsPremium,
+		Tags:       JoinTags(in.Tags),
+	}
+	if in.Created == nil {
+		err = CreateIllustrationRecord(&rec)
+	} else {
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&rec).Error; err != nil {
+				return err
+			}
+			return in.Created(tx, &rec)
+		})
+	}
+	if err != nil {
+		// don't leave an orphaned object behind when the record can't be written
+		removeObjectQuietly(storageKey)
+		return nil, fmt.Errorf("%w: %w", ErrRecordNotStored, err)
+	}
+	PackMembershipChanged(rec.PackID)
+	return &rec, nil
+}
+
+// GenerateStorageKey returns a unique object name: date + 16 random hex chars + original ext.
+func GenerateStorageKey(original string) string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return original // fallback
+	}
+	ext := filepath.Ext(original)
+	if ext == "" {
+		ext = ".svg" // default
+	}
+	return fmt.Sprintf("%s-%s%s", time.Now().Format("20060102"), hex.EncodeToString(b), ext)
+}
+
+// ParseOptionalID parses a numeric id from a form value; empty or invalid input yields nil.
+func ParseOptionalID(s string) *uint {
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	u := uint(v)
+	return &u
+}
+
+// JoinTags normalizes tags (trimmed, lowercased, de-duplicated) into the stored comma-separated form.
+func JoinTags(tags []string) string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return strings.Join(out, ",")
+}
+
+// SplitTags is the inverse of JoinTags.
+func SplitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
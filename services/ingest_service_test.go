@@ -0,0 +1,35 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIngestChecksTheUploadedFileName(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	useFakeStorage(t, &fakeBucket{})
+
+	tests := []struct {
+		name     string
+		in       IngestInput
+		wantErr  error
+		wantName string
+	}{
+		{"display name override", IngestInput{Title: "Rocket", FileName: "Rocket launch", SourceName: "rocket.svg"}, nil, "Rocket launch"},
+		{"no override", IngestInput{Title: "Moon", FileName: "moon.svg"}, nil, "moon.svg"},
+		{"uploaded file is not an SVG", IngestInput{Title: "Sun", FileName: "sun.svg", SourceName: "sun.png"}, ErrNotSVG, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := IngestIllustration(tt.in, strings.NewReader(testSVG), int64(len(testSVG)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && rec.FileName != tt.wantName {
+				t.Errorf("file_name = %q, want %q", rec.FileName, tt.wantName)
+			}
+		})
+	}
+}
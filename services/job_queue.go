@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"open-illustrations-go/logging"
+	"open-illustrations-go/models"
+)
+
+// JobHandler runs one job. Handlers must be idempotent: a job is retried after
+// an error and re-run if the worker dies while processing it. ctx is cancelled
+// when the worker loses its lease on the job. An error wrapping ErrJobPermanent
+// fails the job without further attempts.
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+var (
+	// ErrJobNotRetryable is returned by Retry for jobs that have not failed.
+	ErrJobNotRetryable = errors.New("only failed jobs can be retried")
+	// ErrJobPermanent marks a handler error that another attempt cannot fix,
+	// such as a payload that does not decode.
+	ErrJobPermanent = errors.New("permanent job error")
+)
+
+// JobOptions tweak a single Enqueue call.
+type JobOptions struct {
+	// RunAt delays the first attempt (zero = now).
+	RunAt time.Time
+	// MaxAttempts overrides JOB_MAX_ATTEMPTS for this job.
+	MaxAttempts int
+	// UniqueKey collapses duplicates: while a queued job with the same key
+	// exists, Enqueue returns it instead of creating another one.
+	UniqueKey string
+}
+
+// JobQueue dispatches persisted jobs to registered handlers with a pool of workers.
+type JobQueue struct {
+	store        JobStore
+	concurrency  int
+	maxAttempts  int
+	pollInterval time.Duration
+	lease        time.Duration
+	workerID     string
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+	wake     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Jobs is the process-wide queue, set up by InitJobs.
+var Jobs *JobQueue
+
+// NewJobQueue creates a queue over store with the given number of workers.
+func NewJobQueue(store JobStore, concurrency int) *JobQueue {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	host, _ := os.Hostname()
+	return &JobQueue{
+		store:        store,
+		concurrency:  concurrency,
+		maxAttempts:  5,
+		pollInterval: 2 * time.Second,
+		lease:        time.Minute,
+		workerID:     fmt.Sprintf("%s-%d", host, os.Getpid()),
+		handlers:     map[string]JobHandler{},
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// InitJobs builds the global queue from the jobs settings and registers the
+// built-in job handlers. Workers are started separately with Jobs.Start.
+//
+//	JOB_BACKEND       db (default) or memory
+//	JOB_WORKERS       worker pool size (default 2)
+//	JOB_MAX_ATTEMPTS  attempts before a job is marked failed (default 5)
+//	JOB_LEASE_SECONDS how long a job survives its worker going silent (default 60)
+func InitJobs() {
+	q := NewJobQueue(defaultJobStore(), settings.Jobs.Workers)
+	q.maxAttempts = settings.Jobs.MaxAttempts
+	q.lease = time.Duration(settings.Jobs.LeaseSeconds) * time.Second
+	registerBuiltinJobs(q)
+	Jobs = q
+}
+
+func registerBuiltinJobs(q *JobQueue) {
+	q.Register(JobTypeImport, handleImportJob)
+	q.Register(JobTypePackArchive, handlePackArchiveJob)
+}
+
+// Register binds a handler to a job type. Call before Start.
+func (q *JobQueue) Register(jobType string, h JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = h
+}
+
+func (q *JobQueue) handler(jobType string) (JobHandler, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	h, ok := q.handlers[jobType]
+	return h, ok
+}
+
+func (q *JobQueue) types() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	out := make([]string, 0, len(q.handlers))
+	for t := range q.handlers {
+		out = append(out, t)
+	}
+	return out
+}
+
+// Enqueue persists a job; payload is stored as JSON.
+func (q *JobQueue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts JobOptions) (*models.Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := models.Job{
+		Type:        jobType,
+		UniqueKey:   opts.UniqueKey,
+		Payload:     string(raw),
+		Status:      models.JobStatusQueued,
+		MaxAttempts: q.maxAttempts,
+		RunAt:       time.Now(),
+	}
+	if opts.MaxAttempts > 0 {
+		job.MaxAttempts = opts.MaxAttempts
+	}
+	if !opts.RunAt.IsZero() {
+		job.RunAt = opts.RunAt
+	}
+	if err := q.store.Enqueue(ctx, &job); err != nil {
+		return nil, err
+	}
+	q.notify()
+	return &job, nil
+}
+
+func (q *JobQueue) Get(ctx context.Context, id uint) (*models.Job, error) {
+	return q.store.Get(ctx, id)
+}
+
+func (q *JobQueue) List(ctx context.Context, f JobFilter) ([]models.Job, error) {
+	return q.store.List(ctx, f)
+}
+
+// Retry puts a failed job back in the queue with a fresh attempt budget.
+func (q *JobQueue) Retry(ctx context.Context, id uint) (*models.Job, error) {
+	job, err := q.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobStatusFailed {
+		return nil, fmt.Errorf("%w (status %s)", ErrJobNotRetryable, job.Status)
+	}
+	retry := models.Job{
+		Type:        job.Type,
+		UniqueKey:   job.UniqueKey,
+		Payload:     job.Payload,
+		Status:      models.JobStatusQueued,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       time.Now(),
+	}
+	if err := q.store.Enqueue(ctx, &retry); err != nil {
+		return nil, err
+	}
+	q.notify()
+	return &retry, nil
+}
+
+func (q *JobQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start launches the worker pool; workers stop when ctx is cancelled.
+// Workers renew the lease on their job while it runs; jobs whose lease has
+// expired (their worker crashed or hung) are put back in the queue.
+func (q *JobQueue) Start(ctx context.Context) {
+	q.wg.Add(1)
+	go q.reap(ctx)
+	for i := 0; i < q.concurrency; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx, fmt.Sprintf("%s/%d", q.workerID, i))
+	}
+}
+
+// reap requeues jobs with an expired lease, at startup and then twice per lease.
+func (q *JobQueue) reap(ctx context.Context) {
+	defer q.wg.Done()
+	t := time.NewTicker(q.lease / 2)
+	defer t.Stop()
+	for {
+		if n, err := q.store.RequeueStale(ctx, time.Now().Add(-q.lease)); err != nil {
+			if ctx.Err() == nil {
+				slog.Error("requeue stale jobs failed", "error", err)
+			}
+		} else if n > 0 {
+			slog.Warn("released jobs with an expired lease", "count", n)
+			q.notify()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Wait blocks until all workers have returned after ctx cancellation.
+func (q *JobQueue) Wait() {
+	q.wg.Wait()
+}
+
+func (q *JobQueue) worker(ctx context.Context, id string) {
+	defer q.wg.Done()
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		job, err := q.store.Claim(ctx, id, q.types(), time.Now())
+		if err != nil {
+			slog.Error("job claim failed", "worker", id, "error", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+			case <-time.After(q.pollInterval):
+			}
+			continue
+		}
+		q.run(ctx, job)
+	}
+}
+
+// keepLease renews the lease on job until the returned stop function is
+// called. If the lease is lost it cancels the job.
+func (q *JobQueue) keepLease(ctx context.Context, cancel context.CancelFunc, job *models.Job, log *slog.Logger) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		t := time.NewTicker(q.lease / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			err := q.store.Heartbeat(ctx, job, time.Now())
+			switch {
+			case errors.Is(err, ErrJobLeaseLost):
+				log.Warn("job lease lost, cancelling")
+				cancel()
+				return
+			case err != nil && ctx.Err() == nil:
+				log.Error("job heartbeat failed", "error", err)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+func (q *JobQueue) run(ctx context.Context, job *models.Job) {
+	log := slog.With("job_id", job.ID, "job_type", job.Type)
+	jobCtx, cancel := context.WithCancel(ctx)
+	stop := q.keepLease(jobCtx, cancel, job, log)
+	err := q.invoke(logging.WithLogger(jobCtx, log), job)
+	stop()
+	cancel()
+	now := time.Now()
+	switch {
+	case err == nil:
+		job.Status = models.JobStatusSucceeded
+		job.LastError = ""
+		job.FinishedAt = &now
+	case job.Attempts >= job.MaxAttempts || errors.Is(err, ErrJobPermanent):
+		job.Status = models.JobStatusFailed
+		job.LastError = truncate(err.Error(), 1000)
+		job.FinishedAt = &now
+		log.Error("job failed", "attempts", job.Attempts, "error", err)
+	default:
+		job.Status = models.JobStatusQueued
+		job.LastError = truncate(err.Error(), 1000)
+		job.RunAt = now.Add(jobBackoff(job.Attempts))
+		log.Warn("job attempt failed, retrying", "attempt", job.Attempts, "retry_at", job.RunAt, "error", err)
+	}
+	// use a fresh context so shutdown doesn't leave the job stuck in "running"
+	if err := q.store.Finish(context.Background(), job); errors.Is(err, ErrJobLeaseLost) {
+		log.Warn("job lease lost, result discarded", "status", job.Status)
+	} else if err != nil {
+		log.Error("job finish failed", "error", err)
+	}
+}
+
+func (q *JobQueue) invoke(ctx context.Context, job *models.Job) (err error) {
+	h, ok := q.handler(job.Type)
+	if !ok {
+		return fmt.Errorf("no handler registered for job type %q", job.Type)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return h(ctx, job)
+}
+
+// jobBackoff is exponential (5s, 10s, 20s, ...) capped at 10 minutes, with ±20% jitter.
+func jobBackoff(attempt int) time.Duration {
+	d := 5 * time.Second
+	for i := 1; i < attempt && d < 10*time.Minute; i++ {
+		d *= 2
+	}
+	if d > 10*time.Minute {
+		d = 10 * time.Minute
+	}
+	jitter := time.Duration(rand.Int64N(int64(d)/5*2+1)) - d/5
+	return d + jitter
+}
+
+// DecodeJobPayload unmarshals a job's JSON payload into v. Its errors wrap
+// ErrJobPermanent, so a handler returning one fails the job straight away.
+func DecodeJobPayload(job *models.Job, v interface{}) error {
+	if err := json.Unmarshal([]byte(job.Payload), v); err != nil {
+		return fmt.Errorf("%w: decode payload: %w", ErrJobPermanent, err)
+	}
+	return nil
+}
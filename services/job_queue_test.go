@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+)
+
+// testQueue is a memory-backed queue with a short lease, running until the
+// test ends.
+func testQueue(t *testing.T, lease time.Duration) (*JobQueue, JobStore) {
+	t.Helper()
+	store := NewMemoryJobStore()
+	q := NewJobQueue(store, 1)
+	q.lease = lease
+	q.pollInterval = 10 * time.Millisecond
+	t.Cleanup(q.Wait)
+	return q, store
+}
+
+func start(t *testing.T, q *JobQueue) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	q.Start(ctx)
+}
+
+// waitForStatus polls until the job reaches status or the deadline passes.
+func waitForStatus(t *testing.T, q *JobQueue, id uint, status string) *models.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := q.Get(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d is %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRetryOnlyFailedJobs(t *testing.T) {
+	q, _ := testQueue(t, time.Minute)
+	ctx := context.Background()
+	job, err := q.Enqueue(ctx, "noop", nil, JobOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Retry(ctx, job.ID); !errors.Is(err, ErrJobNotRetryable) {
+		t.Fatalf("Retry(queued) = %v, want ErrJobNotRetryable", err)
+	}
+	if _, err := q.Retry(ctx, job.ID+1); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("Retry(missing) = %v, want ErrJobNotFound", err)
+	}
+
+	q.Register("noop", func(context.Context, *models.Job) error { return errors.New("boom") })
+	failing, err := q.Enqueue(ctx, "noop", nil, JobOptions{MaxAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start(t, q)
+	waitForStatus(t, q, failing.ID, models.JobStatusFailed)
+	retry, err := q.Retry(ctx, failing.ID)
+	if err != nil || retry.ID == failing.ID || retry.Status != models.JobStatusQueued {
+		t.Fatalf("Retry(failed) = %+v, %v; want a new queued job", retry, err)
+	}
+}
+
+func TestUndecodablePayloadFailsAtOnce(t *testing.T) {
+	q, _ := testQueue(t, time.Minute)
+	q.Register("typed", func(_ context.Context, job *models.Job) error {
+		var p struct{ ID uint }
+		return DecodeJobPayload(job, &p)
+	})
+	job, err := q.Enqueue(context.Background(), "typed", "not an object", JobOptions{MaxAttempts: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start(t, q)
+	if done := waitForStatus(t, q, job.ID, models.JobStatusFailed); done.Attempts != 1 {
+		t.Errorf("failed after %d attempts, want 1", done.Attempts)
+	}
+}
+
+func TestHeartbeatKeepsLongJobsClaimed(t *testing.T) {
+	q, _ := testQueue(t, 60*time.Millisecond)
+	q.Register("slow", func(ctx context.Context, _ *models.Job) error {
+		select {
+		case <-time.After(300 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	job, err := q.Enqueue(context.Background(), "slow", nil, JobOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start(t, q)
+	done := waitForStatus(t, q, job.ID, models.JobStatusSucceeded)
+	if done.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1: the job was requeued while it ran", done.Attempts)
+	}
+}
+
+func TestExpiredLeaseIsRequeued(t *testing.T) {
+	q, store := testQueue(t, 60*time.Millisecond)
+	ctx := context.Background()
+	job, err := q.Enqueue(ctx, "work", nil, JobOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a worker that claims the job and then goes silent
+	dead, err := store.Claim(ctx, "dead-worker", nil, time.Now())
+	if err != nil || dead == nil {
+		t.Fatalf("Claim = %v, %v", dead, err)
+	}
+
+	q.Register("work", func(context.Context, *models.Job) error { return nil })
+	start(t, q)
+	done := waitForStatus(t, q, job.ID, models.JobStatusSucceeded)
+	if done.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", done.Attempts)
+	}
+	if err := store.Heartbeat(ctx, dead, time.Now()); !errors.Is(err, ErrJobLeaseLost) {
+		t.Errorf("Heartbeat from the old worker = %v, want ErrJobLeaseLost", err)
+	}
+	dead.Status = models.JobStatusFailed
+	if err := store.Finish(ctx, dead); !errors.Is(err, ErrJobLeaseLost) {
+		t.Errorf("Finish from the old worker = %v, want ErrJobLeaseLost", err)
+	}
+	if got := waitForStatus(t, q, job.ID, models.JobStatusSucceeded); got.Attempts != 2 {
+		t.Errorf("the old worker changed the job: %+v", got)
+	}
+}
+
+func TestLostLeaseCancelsTheHandler(t *testing.T) {
+	q, store := testQueue(t, 60*time.Millisecond)
+	cancelled := make(chan struct{}, 1)
+	q.Register("stuck", func(ctx context.Context, _ *models.Job) error {
+		<-ctx.Done()
+		select {
+		case cancelled <- struct{}{}:
+		default:
+		}
+		return ctx.Err()
+	})
+	job, err := q.Enqueue(context.Background(), "stuck", nil, JobOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start(t, q)
+	waitForStatus(t, q, job.ID, models.JobStatusRunning)
+	// another replica decides the lease has expired
+	if _, err := store.RequeueStale(context.Background(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler still running after its lease was lost")
+	}
+}
+
+// jobStores builds each JobStore backend for a test.
+var jobStores = map[string]func(t *testing.T) JobStore{
+	"memory": func(*testing.T) JobStore { return NewMemoryJobStore() },
+	"db": func(t *testing.T) JobStore {
+		useTestDB(t)
+		return NewDBJobStore(config.DB)
+	},
+}
+
+func TestStoreLease(t *testing.T) {
+	for name, newStore := range jobStores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			ctx := context.Background()
+			now := time.Now()
+			if err := store.Enqueue(ctx, &models.Job{Type: "work", Status: models.JobStatusQueued, MaxAttempts: 3, RunAt: now}); err != nil {
+				t.Fatal(err)
+			}
+			job, err := store.Claim(ctx, "w1", nil, now)
+			if err != nil || job == nil {
+				t.Fatalf("Claim = %v, %v", job, err)
+			}
+			if err := store.Heartbeat(ctx, job, now.Add(time.Minute)); err != nil {
+				t.Fatalf("Heartbeat = %v", err)
+			}
+			if n, err := store.RequeueStale(ctx, now.Add(30*time.Second)); err != nil || n != 0 {
+				t.Fatalf("RequeueStale before the renewed lease expired = %d, %v; want 0", n, err)
+			}
+			if n, err := store.RequeueStale(ctx, now.Add(2*time.Minute)); err != nil || n != 1 {
+				t.Fatalf("RequeueStale after it expired = %d, %v; want 1", n, err)
+			}
+			if err := store.Heartbeat(ctx, job, now.Add(2*time.Minute)); !errors.Is(err, ErrJobLeaseLost) {
+				t.Errorf("Heartbeat after requeue = %v, want ErrJobLeaseLost", err)
+			}
+			job.Status = models.JobStatusSucceeded
+			if err := store.Finish(ctx, job); !errors.Is(err, ErrJobLeaseLost) {
+				t.Errorf("Finish after requeue = %v, want ErrJobLeaseLost", err)
+			}
+			if got, _ := store.Get(ctx, job.ID); got.Status != models.JobStatusQueued {
+				t.Errorf("status = %s, want queued", got.Status)
+			}
+		})
+	}
+}
+
+func TestStaleLastAttemptFails(t *testing.T) {
+	for name, newStore := range jobStores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			ctx := context.Background()
+			now := time.Now()
+			for _, max := range []int{1, 2} {
+				if err := store.Enqueue(ctx, &models.Job{Type: "work", Status: models.JobStatusQueued, MaxAttempts: max, RunAt: now}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for range 2 {
+				if job, err := store.Claim(ctx, "w1", nil, now); err != nil || job == nil {
+					t.Fatalf("Claim = %v, %v", job, err)
+				}
+			}
+			if n, err := store.RequeueStale(ctx, now.Add(time.Minute)); err != nil || n != 2 {
+				t.Fatalf("RequeueStale = %d, %v; want 2", n, err)
+			}
+			last, _ := store.Get(ctx, 1)
+			if last.Status != models.JobStatusFailed || last.LastError == "" || last.FinishedAt == nil || last.LockedBy != "" {
+				t.Errorf("job on its last attempt = %+v, want failed with an error", last)
+			}
+			if again, _ := store.Get(ctx, 2); again.Status != models.JobStatusQueued {
+				t.Errorf("job with attempts left is %s, want queued", again.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobLeaseLost means the worker no longer holds the job: its lease
+	// expired and the job was requeued, possibly to another worker.
+	ErrJobLeaseLost = errors.New("job lease lost")
+)
+
+// errLeaseExpiredLastAttempt is the last_error of a job failed by RequeueStale.
+const errLeaseExpiredLastAttempt = "lease expired on the last attempt: the worker stopped or hung"
+
+// JobFilter narrows ListJobs results; zero values mean "any". Results are
+// newest first and Offset skips that many of them.
+type JobFilter struct {
+	Type   string
+	Status string
+	Limit  int
+	Offset int
+}
+
+// JobStore persists jobs for the queue. Claim must be safe to call from many
+// workers (and many replicas) at once: a job may only be handed out once per attempt.
+//
+// A claimed job is leased to the worker named in LockedBy; LockedAt is when the
+// lease was last renewed. Heartbeat and Finish return ErrJobLeaseLost once the
+// job has been requeued by RequeueStale or claimed by someone else.
+//
+// RequeueStale releases jobs whose lease is older than lockedBefore: they are
+// queued again, or failed if that was their last attempt, so a job that kills
+// or hangs its worker every time is not retried forever. It returns how many
+// jobs it released.
+type JobStore interface {
+	Enqueue(ctx context.Context, job *models.Job) error
+	Claim(ctx context.Context, workerID string, types []string, now time.Time) (*models.Job, error)
+	Heartbeat(ctx context.Context, job *models.Job, now time.Time) error
+	Finish(ctx context.Context, job *models.Job) error
+	Get(ctx context.Context, id uint) (*models.Job, error)
+	List(ctx context.Context, f JobFilter) ([]models.Job, error)
+	RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error)
+}
+
+// ---- database store ----
+
+type dbJobStore struct {
+	db *gorm.DB
+}
+
+// NewDBJobStore stores jobs in the application database (jobs table).
+func NewDBJobStore(db *gorm.DB) JobStore {
+	return &dbJobStore{db: db}
+}
+
+func (s *dbJobStore) Enqueue(ctx context.Context, job *models.Job) error {
+	db := s.db.WithContext(ctx)
+	if job.UniqueKey != "" {
+		var existing models.Job
+		err := db.Where("unique_key = ? AND status = ?", job.UniqueKey, models.JobStatusQueued).First(&existing).Error
+		if err == nil {
+			*job = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	return db.Create(job).Error
+}
+
+func (s *dbJobStore) Claim(ctx context.Context, workerID string, types []string, now time.Time) (*models.Job, error) {
+	db := s.db.WithContext(ctx)
+	// a few candidates so that losing the race for one doesn't idle the worker
+	for range 3 {
+		var candidate models.Job
+		q := db.Where("status = ? AND run_at <= ?", models.JobStatusQueued, now)
+		if len(types) > 0 {
+			q = q.Where("type IN ?", types)
+		}
+		err := q.Order("run_at, id").First(&candidate).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		res := db.Model(&models.Job{}).
+			Where("id = ? AND status = ?", candidate.ID, models.JobStatusQueued).
+			Updates(map[string]interface{}{
+				"status":     models.JobStatusRunning,
+				"locked_by":  workerID,
+				"locked_at":  now,
+				"started_at": now,
+				"attempts":   gorm.Expr("attempts + 1"),
+			})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			var job models.Job
+			if err := db.First(&job, candidate.ID).Error; err != nil {
+				return nil, err
+			}
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+// leased matches job while it is still running under the worker that claimed it.
+func (s *dbJobStore) leased(ctx context.Context, job *models.Job) *gorm.DB {
+	return s.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, models.JobStatusRunning, job.LockedBy)
+}
+
+func (s *dbJobStore) Heartbeat(ctx context.Context, job *models.Job, now time.Time) error {
+	res := s.leased(ctx, job).Update("locked_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+func (s *dbJobStore) Finish(ctx context.Context, job *models.Job) error {
+	res := s.leased(ctx, job).Updates(map[string]interface{}{
+		"status":      job.Status,
+		"run_at":      job.RunAt,
+		"locked_by":   "",
+		"locked_at":   nil,
+		"last_error":  job.LastError,
+		"result":      job.Result,
+		"finished_at": job.FinishedAt,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+func (s *dbJobStore) Get(ctx context.Context, id uint) (*models.Job, error) {
+	var job models.Job
+	err := s.db.WithContext(ctx).First(&job, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *dbJobStore) List(ctx context.Context, f JobFilter) ([]models.Job, error) {
+	q := s.db.WithContext(ctx).Order("id DESC")
+	if f.Type != "" {
+		q = q.Where("type = ?", f.Type)
+	}
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit).Offset(f.Offset)
+	}
+	var list []models.Job
+	return list, q.Find(&list).Error
+}
+
+func (s *dbJobStore) RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	var n int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stale := func() *gorm.DB {
+			return tx.Model(&models.Job{}).Where("status = ? AND locked_at < ?", models.JobStatusRunning, lockedBefore)
+		}
+		res := stale().Where("attempts >= max_attempts").Updates(map[string]interface{}{
+			"status": models.JobStatusFailed, "last_error": errLeaseExpiredLastAttempt,
+			"finished_at": time.Now(), "locked_by": "", "locked_at": nil,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		n = res.RowsAffected
+		res = stale().Updates(map[string]interface{}{"status": models.JobStatusQueued, "locked_by": "", "locked_at": nil})
+		n += res.RowsAffected
+		return res.Error
+	})
+	return n, err
+}
+
+// ---- in-process store ----
+
+type memoryJobStore struct {
+	mu     sync.Mutex
+	nextID uint
+	jobs   map[uint]*models.Job
+}
+
+// NewMemoryJobStore keeps jobs in memory. Jobs are lost on restart, so it is
+// meant for tests and single-process development setups.
+func NewMemoryJobStore() JobStore {
+	return &memoryJobStore{jobs: map[uint]*models.Job{}}
+}
+
+func (s *memoryJobStore) Enqueue(_ context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.UniqueKey != "" {
+		for _, j := range s.jobs {
+			if j.UniqueKey == job.UniqueKey && j.Status == models.JobStatusQueued {
+				*job = *j
+				return nil
+			}
+		}
+	}
+	s.nextID++
+	now := time.Now()
+	job.ID = s.nextID
+	job.CreatedAt, job.UpdatedAt = now, now
+	cp := *job
+	s.jobs[job.ID] = &cp
+	return nil
+}
+
+func (s *memoryJobStore) Claim(_ context.Context, workerID string, types []string, now time.Time) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var best *models.Job
+	for _, j := range s.jobs {
+		if j.Status != models.JobStatusQueued || j.RunAt.After(now) || !matchesJobType(types, j.Type) {
+			continue
+		}
+		if best == nil || j.RunAt.Before(best.RunAt) || (j.RunAt.Equal(best.RunAt) && j.ID < best.ID) {
+			best = j
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	best.Status = models.JobStatusRunning
+	best.LockedBy = workerID
+	best.LockedAt = &now
+	best.StartedAt = &now
+	best.Attempts++
+	best.UpdatedAt = now
+	cp := *best
+	return &cp, nil
+}
+
+// leased returns the stored job while it is still running under the worker
+// that claimed it. The caller holds s.mu.
+func (s *memoryJobStore) leased(job *models.Job) (*models.Job, error) {
+	j, ok := s.jobs[job.ID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	if j.Status != models.JobStatusRunning || j.LockedBy != job.LockedBy {
+		return nil, ErrJobLeaseLost
+	}
+	return j, nil
+}
+
+func (s *memoryJobStore) Heartbeat(_ context.Context, job *models.Job, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.leased(job)
+	if err != nil {
+		return err
+	}
+	j.LockedAt = &now
+	return nil
+}
+
+func (s *memoryJobStore) Finish(_ context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.leased(job)
+	if err != nil {
+		return err
+	}
+	j.Status, j.RunAt, j.LastError, j.Result, j.FinishedAt = job.Status, job.RunAt, job.LastError, job.Result, job.FinishedAt
+	j.LockedBy, j.LockedAt = "", nil
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryJobStore) Get(_ context.Context, id uint) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	cp := *j
+	return &cp, nil
+}
+
+func (s *memoryJobStore) List(_ context.Context, f JobFilter) ([]models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]models.Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		if (f.Type == "" || j.Type == f.Type) && (f.Status == "" || j.Status == f.Status) {
+			list = append(list, *j)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].ID > list[b].ID })
+	if f.Limit > 0 {
+		list = list[min(f.Offset, len(list)):]
+		if len(list) > f.Limit {
+			list = list[:f.Limit]
+		}
+	}
+	return list, nil
+}
+
+func (s *memoryJobStore) RequeueStale(_ context.Context, lockedBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, j := range s.jobs {
+		if j.Status != models.JobStatusRunning || j.LockedAt == nil || !j.LockedAt.Before(lockedBefore) {
+			continue
+		}
+		j.Status, j.LockedBy, j.LockedAt = models.JobStatusQueued, "", nil
+		if j.Attempts >= j.MaxAttempts {
+			now := time.Now()
+			j.Status, j.LastError, j.FinishedAt = models.JobStatusFailed, errLeaseExpiredLastAttempt, &now
+		}
+		n++
+	}
+	return n, nil
+}
+
+// matchesJobType reports whether t is one of types; an empty list matches every type.
+func matchesJobType(types []string, t string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultJobStore picks the backend from JOB_BACKEND ("db" by default, or "memory").
+func defaultJobStore() JobStore {
+	if settings.Jobs.Backend == "memory" {
+		return NewMemoryJobStore()
+	}
+	return NewDBJobStore(config.DB)
+}
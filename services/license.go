@@ -0,0 +1,37 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LicenseSummary is the short statement returned by GET /api/v1/info/license.
+const LicenseSummary = "All illustrations are free to use under the MIT license."
+
+// LicenseTerms is the full license text (kept in sync with the README).
+const LicenseTerms = `All images, assets and vectors published on illustration.aku.farm can be used for free. You can use them for noncommercial and commercial purposes. You do not need to ask permission from or provide credit to the creator or illustration.aku.farm.
+
+More precisely, illustration.aku.farm grants you an nonexclusive, worldwide copyright license to download, copy, modify, distribute, perform, and use the assets provided from illustration.aku.farm for free, including for commercial purposes, without permission from or attributing the creator or illustration.aku.farm. This license does not include the right to compile assets, vectors or images from illustration.aku.farm to replicate a similar or competing service, in any form or distribute the assets in packs or otherwise. This extends to automated and non-automated ways to link, embed, scrape, search or download the assets included on the website without our consent.
+
+Additionally, this license explicitly prohibits the use of illustration.aku.farm assets, vectors, and images for training, fine-tuning, or developing artificial intelligence, machine learning models, or similar technologies. This includes but is not limited to:
+
+Using the assets as training data for generative AI models
+Incorporating the assets into machine learning datasets
+Fine-tuning a machine learning model
+Using the assets to train, validate, or test AI systems
+Any automated processing of the assets for AI/ML model development
+Any such use requires separate explicit written permission from illustration.aku.farm.
+
+Regarding brand logos that are included:
+Are registered trademarks of their respected owners. Are included on a promotional basis and do not represent an association with illustration.aku.farm or its users. Do not indicate any kind of endorsement of the trademark holder towards illustration.aku.farm, nor vice versa. Are provided with the sole purpose to represent the actual brand/service/company that has registered the trademark and must not be used otherwise.`
+
+// PackLicenseText renders the LICENSE.txt bundled into every pack archive.
+func PackLicenseText(packName string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s — license\n", packName)
+	b.WriteString(strings.Repeat("=", 60) + "\n\n")
+	b.WriteString("Copyright 2025 Katerina Limpitsouni\n\n")
+	b.WriteString(LicenseTerms)
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,670 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"gorm.io/gorm"
+)
+
+const (
+	JobTypePackArchive = "pack.build_archive"
+
+	// defaultArchiveVariant is the plain ZIP of every SVG in the pack.
+	defaultArchiveVariant = "default"
+
+	ArchiveFormatSVG  = "svg"
+	ArchiveFormatPNG  = "png"
+	ArchiveFormatBoth = "both"
+
+	ArchiveLayoutFlat     = "flat"
+	ArchiveLayoutCategory = "category"
+	ArchiveLayoutStyle    = "style"
+
+	defaultPNGSize = 512
+)
+
+var (
+	ErrPackEmpty             = errors.New("pack has no illustrations")
+	ErrPackArchivePending    = errors.New("pack archive is being built")
+	ErrPackArchiveIncomplete = errors.New("pack archive is incomplete")
+	// ErrPackArchiveStale comes with the last complete archive when the
+	// current content can't be built completely; it may still be served.
+	ErrPackArchiveStale      = errors.New("pack archive is out of date")
+	ErrInvalidArchiveOptions = errors.New("invalid archive options")
+)
+
+// cachedPNGSizes are the PNG sizes whose archives are stored and reused.
+var cachedPNGSizes = []int{256, defaultPNGSize, 1024}
+
+const (
+	// maxPNGSize bounds the PNG size of any archive.
+	maxPNGSize = 1024
+	// maxUncachedFiles bounds the illustrations in an archive that is built
+	// inside the download request because it is not cached.
+	maxUncachedFiles = 50
+)
+
+// PackArchiveOptions select what goes into a pack archive. The zero value
+// (all SVGs, flat) is the default archive that is rebuilt on every membership change.
+// Other cacheable combinations (see Cacheable) are built on first request and
+// cached the same way; the rest are built for the request that asks for them.
+type PackArchiveOptions struct {
+	Format string `json:"format"`
+	// Size is the PNG width/height bound in pixels (PNG formats only).
+	Size   int    `json:"size,omitempty"`
+	IDs    []uint `json:"ids,omitempty"`
+	Layout string `json:"layout"`
+}
+
+// ParsePackArchiveOptions validates download query values: format=svg|png|both,
+// size=16..1024, ids=1,2,3 (at most maxUncachedFiles) and layout=flat|category|style.
+func ParsePackArchiveOptions(format, size, ids, layout string) (PackArchiveOptions, error) {
+	o := PackArchiveOptions{Format: strings.ToLower(format), Layout: strings.ToLower(layout)}
+	if o.Format == "" {
+		o.Format = ArchiveFormatSVG
+	}
+	if o.Layout == "" {
+		o.Layout = ArchiveLayoutFlat
+	}
+	switch o.Format {
+	case ArchiveFormatSVG, ArchiveFormatPNG, ArchiveFormatBoth:
+	default:
+		return o, fmt.Errorf("%w: format must be svg, png or both", ErrInvalidArchiveOptions)
+	}
+	switch o.Layout {
+	case ArchiveLayoutFlat, ArchiveLayoutCategory, ArchiveLayoutStyle:
+	default:
+		return o, fmt.Errorf("%w: layout must be flat, category or style", ErrInvalidArchiveOptions)
+	}
+	if o.Format != ArchiveFormatSVG {
+		o.Size = defaultPNGSize
+		if size != "" {
+			n, err := strconv.Atoi(size)
+			if err != nil || n < 16 || n > maxPNGSize {
+				return o, fmt.Errorf("%w: size must be between 16 and %d", ErrInvalidArchiveOptions, maxPNGSize)
+			}
+			o.Size = n
+		}
+	}
+	if ids != "" {
+		seen := map[uint]bool{}
+		for _, part := range strings.Split(ids, ",") {
+			id := ParseOptionalID(strings.TrimSpace(part))
+			if id == nil {
+				return o, fmt.Errorf("%w: ids must be a comma-separated list of illustration IDs", ErrInvalidArchiveOptions)
+			}
+			if !seen[*id] {
+				seen[*id] = true
+				o.IDs = append(o.IDs, *id)
+			}
+		}
+		if len(o.IDs) > maxUncachedFiles {
+			return o, fmt.Errorf("%w: at most %d ids", ErrInvalidArchiveOptions, maxUncachedFiles)
+		}
+		sort.Slice(o.IDs, func(a, b int) bool { return o.IDs[a] < o.IDs[b] })
+	}
+	return o, nil
+}
+
+// Cacheable reports whether archives with these options are stored in the
+// bucket and reused. Subsets (IDs) and PNG sizes outside cachedPNGSizes are
+// not, so the number of stored archives per pack stays bounded.
+func (o PackArchiveOptions) Cacheable() bool {
+	if len(o.IDs) > 0 {
+		return false
+	}
+	return o.Format == ArchiveFormatSVG || slices.Contains(cachedPNGSizes, o.Size)
+}
+
+// variant is the stable cache key of an option set.
+func (o PackArchiveOptions) variant() string {
+	if o.Format == ArchiveFormatSVG && o.Layout == ArchiveLayoutFlat && len(o.IDs) == 0 {
+		return defaultArchiveVariant
+	}
+	raw, _ := json.Marshal(o)
+	sum := sha256.Sum256(raw)
+	return "opt-" + hex.EncodeToString(sum[:12])
+}
+
+func defaultArchiveOptions() PackArchiveOptions {
+	return PackArchiveOptions{Format: ArchiveFormatSVG, Layout: ArchiveLayoutFlat}
+}
+
+type packArchiveJobPayload struct {
+	PackID  uint                `json:"pack_id"`
+	Options *PackArchiveOptions `json:"options,omitempty"`
+}
+
+// PackArchiveDebounce delays rebuilds so a burst of membership changes (e.g. an
+// import) results in one build (PACK_ARCHIVE_DEBOUNCE_SECONDS, default 10).
+func PackArchiveDebounce() time.Duration {
+	return time.Duration(settings.Uploads.PackArchiveDebounceSeconds) * time.Second
+}
+
+// PackMembers returns the live illustrations of a pack in a stable order.
+func PackMembers(packID uint) ([]models.Illustration, error) {
+	var ills []models.Illustration
+	err := config.DB.Preload("CategoryRef").Preload("StyleRef").
+		Where("pack_id = ?", packID).Order("id").Find(&ills).Error
+	return ills, err
+}
+
+// selectMembers narrows the pack to the requested IDs; IDs outside the pack are an error.
+func selectMembers(ills []models.Illustration, o PackArchiveOptions) ([]models.Illustration, error) {
+	if len(o.IDs) == 0 {
+		return ills, nil
+	}
+	byID := make(map[uint]models.Illustration, len(ills))
+	for _, ill := range ills {
+		byID[ill.ID] = ill
+	}
+	out := make([]models.Illustration, 0, len(o.IDs))
+	var unknown []string
+	for _, id := range o.IDs {
+		ill, ok := byID[id]
+		if !ok {
+			unknown = append(unknown, strconv.FormatUint(uint64(id), 10))
+			continue
+		}
+		out = append(out, ill)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: illustrations not in this pack: %s", ErrInvalidArchiveOptions, strings.Join(unknown, ","))
+	}
+	return out, nil
+}
+
+// packSourceHash fingerprints everything an archive is built from; any added,
+// removed or updated illustration (or renamed category/style) changes it.
+func packSourceHash(pack *models.Pack, o PackArchiveOptions, ills []models.Illustration) string {
+	h := sha256.New()
+	raw, _ := json.Marshal(o)
+	fmt.Fprintf(h, "%s\n%s\n", raw, pack.Name)
+	for _, ill := range ills {
+		fmt.Fprintf(h, "%d|%s|%s|%s|%s|%d|%s|%s\n", ill.ID, ill.StorageKey, ill.FileName, ill.Title, ill.Tags,
+			ill.UpdatedAt.UnixNano(), categoryFolder(ill), styleFolder(ill))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// PackMembershipChanged schedules a rebuild of the pack's default archive. Safe to
+// call with a nil id or before the job queue is initialised (e.g. from the CLI).
+func PackMembershipChanged(packID *uint) {
+	if packID == nil || Jobs == nil {
+		return
+	}
+	if _, err := EnqueuePackArchive(context.Background(), *packID, defaultArchiveOptions(), time.Now().Add(PackArchiveDebounce())); err != nil {
+		slog.Error("enqueue pack archive failed", "error", err)
+	}
+}
+
+// EnqueuePackArchive schedules a build; concurrent requests for the same pack and
+// options share one job. Only cacheable options can be queued.
+func EnqueuePackArchive(ctx context.Context, packID uint, o PackArchiveOptions, runAt time.Time) (*models.Job, error) {
+	if !o.Cacheable() {
+		return nil, fmt.Errorf("%w: these options are not cached", ErrInvalidArchiveOptions)
+	}
+	return Jobs.Enqueue(ctx, JobTypePackArchive, packArchiveJobPayload{PackID: packID, Options: &o}, JobOptions{
+		RunAt:     runAt,
+		UniqueKey: fmt.Sprintf("pack-archive:%d:%s", packID, o.variant()),
+	})
+}
+
+func handlePackArchiveJob(ctx context.Context, job *models.Job) error {
+	var p packArchiveJobPayload
+	if err := DecodeJobPayload(job, &p); err != nil {
+		return err
+	}
+	o := defaultArchiveOptions()
+	if p.Options != nil {
+		o = *p.Options
+	}
+	archive, err := BuildPackArchive(ctx, p.PackID, o)
+	if err != nil {
+		return err
+	}
+	job.Result = fmt.Sprintf(`{"archive_id":%d,"status":%q}`, archive.ID, archive.Status)
+	return nil
+}
+
+// archiveMembers returns the illustrations an archive with options o contains.
+func archiveMembers(packID uint, o PackArchiveOptions) ([]models.Illustration, error) {
+	all, err := PackMembers(packID)
+	if err != nil {
+		return nil, err
+	}
+	ills, err := selectMembers(all, o)
+	if err != nil {
+		return nil, err
+	}
+	if len(ills) == 0 {
+		return nil, ErrPackEmpty
+	}
+	return ills, nil
+}
+
+// CurrentPackArchive returns the archive matching the pack's current content and options.
+// ErrPackArchivePending means none has been built yet (the caller should enqueue
+// a build). If the current content has missing files, the last complete archive
+// of these options comes with ErrPackArchiveStale; without one,
+// ErrPackArchiveIncomplete comes with the archive describing what is missing.
+func CurrentPackArchive(pack *models.Pack, o PackArchiveOptions) (*models.PackArchive, error) {
+	ills, err := archiveMembers(pack.ID, o)
+	if err != nil {
+		return nil, err
+	}
+	var a models.PackArchive
+	err = config.DB.Where("pack_id = ? AND variant = ? AND source_hash = ?", pack.ID, o.variant(), packSourceHash(pack, o, ills)).
+		Order("id DESC").First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPackArchivePending
+	}
+	if err != nil {
+		return nil, err
+	}
+	if a.Status == models.PackArchiveIncomplete {
+		var last models.PackArchive
+		err := config.DB.Where("pack_id = ? AND variant = ? AND status = ?", pack.ID, o.variant(), models.PackArchiveReady).
+			Order("id DESC").First(&last).Error
+		if err == nil {
+			return &last, ErrPackArchiveStale
+		}
+		return &a, ErrPackArchiveIncomplete
+	}
+	return &a, nil
+}
+
+// archiveManifest is written to manifest.json in every archive.
+type archiveManifest struct {
+	Pack struct {
+		ID   uint   `json:"id"`
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	} `json:"pack"`
+	Format     string                 `json:"format"`
+	Layout     string                 `json:"layout"`
+	Size       int                    `json:"size,omitempty"`
+	SourceHash string                 `json:"source_hash"`
+	License    string                 `json:"license"`
+	Files      []archiveManifestEntry `json:"files"`
+}
+
+type archiveManifestEntry struct {
+	Path           string   `json:"path"`
+	Format         string   `json:"format"`
+	IllustrationID uint     `json:"illustration_id"`
+	Title          string   `json:"title"`
+	Category       string   `json:"category,omitempty"`
+	Style          string   `json:"style,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	IsPremium      bool     `json:"is_premium"`
+	Size           int64    `json:"size"`
+	SHA256         string   `json:"sha256"`
+}
+
+// BuildPackArchive writes the pack ZIP to a temp file, stores it under its content
+// hash and replaces older archives of the same options. Every archive carries a
+// manifest.json and LICENSE.txt. Objects missing from the bucket (or SVGs that can't
+// be rendered to PNG) produce an "incomplete" archive record instead of a silently
+// truncated ZIP; other storage errors are returned so the job is retried.
+func BuildPackArchive(ctx context.Context, packID uint, o PackArchiveOptions) (*models.PackArchive, error) {
+	var pack models.Pack
+	if err := config.DB.First(&pack, packID).Error; err != nil {
+		return nil, err
+	}
+	ills, err := archiveMembers(packID, o)
+	if err != nil {
+		return nil, err
+	}
+	variant := o.variant()
+	sourceHash := packSourceHash(&pack, o, ills)
+
+	var existing models.PackArchive
+	if err := config.DB.Where("pack_id = ? AND variant = ? AND source_hash = ? AND status = ?", packID, variant, sourceHash, models.PackArchiveReady).
+		First(&existing).Error; err == nil {
+		return &existing, nil
+	}
+
+	f, err := writeTempPackArchive(ctx, &pack, o, ills)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	archive := f.Archive
+	archive.Variant = variant
+	if archive.Status == models.PackArchiveIncomplete {
+		if err := config.DB.Create(&archive).Error; err != nil {
+			return nil, err
+		}
+		// ready archives stay until a complete one replaces them
+		config.DB.Where("pack_id = ? AND variant = ? AND status = ? AND id <> ?", packID, variant, models.PackArchiveIncomplete, archive.ID).
+			Delete(&models.PackArchive{})
+		return &archive, nil
+	}
+
+	archive.StorageKey = fmt.Sprintf("packs/%d/%s.zip", packID, archive.ContentHash)
+	if err := UploadObject(archive.StorageKey, f, archive.Size, "application/zip"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageUpload, err)
+	}
+	if err := config.DB.Create(&archive).Error; err != nil {
+		return nil, err
+	}
+	prunePackArchives(&pack, variant, archive.ID)
+	return &archive, nil
+}
+
+// PackArchiveFile is an archive in a temporary file, positioned at its start.
+// Close removes the file.
+type PackArchiveFile struct {
+	*os.File
+	// Archive describes the file; it is not saved.
+	Archive models.PackArchive
+}
+
+func (f *PackArchiveFile) Close() error {
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// BuildUncachedPackArchive builds an archive with options that are not cached
+// (see Cacheable) for a single response. As with CurrentPackArchive,
+// ErrPackArchiveIncomplete comes with a file whose Archive.Error describes what
+// is missing; the caller closes the file in every case where it is non-nil.
+// The build runs inside the download request, so archives of more than
+// maxUncachedFiles illustrations are refused.
+func BuildUncachedPackArchive(ctx context.Context, pack *models.Pack, o PackArchiveOptions) (*PackArchiveFile, error) {
+	ills, err := archiveMembers(pack.ID, o)
+	if err != nil {
+		return nil, err
+	}
+	if len(ills) > maxUncachedFiles {
+		return nil, fmt.Errorf("%w: packs of more than %d illustrations are only available at PNG sizes %v",
+			ErrInvalidArchiveOptions, maxUncachedFiles, cachedPNGSizes)
+	}
+	f, err := writeTempPackArchive(ctx, pack, o, ills)
+	if err != nil {
+		return nil, err
+	}
+	if f.Archive.Status == models.PackArchiveIncomplete {
+		return f, ErrPackArchiveIncomplete
+	}
+	return f, nil
+}
+
+// writeTempPackArchive writes the ZIP of ills to a temporary file. When files
+// are missing or fail to render the ZIP is abandoned and the archive is marked
+// incomplete with the problems in Error.
+func writeTempPackArchive(ctx context.Context, pack *models.Pack, o PackArchiveOptions, ills []models.Illustration) (*PackArchiveFile, error) {
+	tmp, err := os.CreateTemp("", "pack-archive-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	f := &PackArchiveFile{File: tmp, Archive: models.PackArchive{
+		PackID:     pack.ID,
+		SourceHash: packSourceHash(pack, o, ills),
+	}}
+	hasher := sha256.New()
+	files, problems, err := writePackZip(ctx, io.MultiWriter(tmp, hasher), pack, o, ills, f.Archive.SourceHash)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	f.Archive.FileCount = files
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		f.Archive.Status = models.PackArchiveIncomplete
+		f.Archive.Error = strings.Join(problems, "; ")
+		return f, nil
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	now := time.Now()
+	f.Archive.Status = models.PackArchiveReady
+	f.Archive.ContentHash = hex.EncodeToString(hasher.Sum(nil))
+	f.Archive.Size = size
+	f.Archive.BuiltAt = &now
+	return f, nil
+}
+
+// writePackZip writes the archive entries, manifest.json and LICENSE.txt to w and
+// returns the number of illustration files. If any illustration is missing or
+// can't be rendered, the problems are returned and the ZIP is left unfinished.
+func writePackZip(ctx context.Context, w io.Writer, pack *models.Pack, o PackArchiveOptions, ills []models.Illustration, sourceHash string) (int, []string, error) {
+	// fixed timestamps keep the archive bytes (and so its hash) reproducible
+	stamp := pack.UpdatedAt.UTC()
+	zw := zip.NewWriter(w)
+
+	manifest := archiveManifest{Format: o.Format, Layout: o.Layout, Size: o.Size, SourceHash: sourceHash, License: "LICENSE.txt"}
+	manifest.Pack.ID, manifest.Pack.Name, manifest.Pack.Slug = pack.ID, pack.Name, pack.Slug
+
+	names := archiveNamer{}
+	var problems []string
+	for _, ill := range ills {
+		if ill.UpdatedAt.After(stamp) {
+			stamp = ill.UpdatedAt.UTC()
+		}
+		src, err := readObject(ctx, ill.StorageKey)
+		if apierror.IsMissingObject(err) {
+			problems = append(problems, fmt.Sprintf("%s (%s): missing from storage", ill.FileName, ill.StorageKey))
+			continue
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("read %s: %w", ill.StorageKey, err)
+		}
+
+		type rendition struct {
+			format string
+			data   []byte
+		}
+		var out []rendition
+		if o.Format == ArchiveFormatSVG || o.Format == ArchiveFormatBoth {
+			out = append(out, rendition{ArchiveFormatSVG, src})
+		}
+		if o.Format == ArchiveFormatPNG || o.Format == ArchiveFormatBoth {
+			pngData, err := RenderSVGToPNG(src, o.Size)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s (%s): png render failed: %v", ill.FileName, ill.StorageKey, err))
+				continue
+			}
+			out = append(out, rendition{ArchiveFormatPNG, pngData})
+		}
+
+		for _, r := range out {
+			name := names.unique(archivePath(ill, o.Layout, r.format))
+			if err := writeZipEntry(zw, name, ill.UpdatedAt, r.data); err != nil {
+				return 0, nil, err
+			}
+			sum := sha256.Sum256(r.data)
+			entry := archiveManifestEntry{
+				Path:           name,
+				Format:         r.format,
+				IllustrationID: ill.ID,
+				Title:          ill.Title,
+				IsPremium:      ill.IsPremium,
+				Size:           int64(len(r.data)),
+				SHA256:         hex.EncodeToString(sum[:]),
+			}
+			if ill.CategoryRef != nil {
+				entry.Category = ill.CategoryRef.Name
+			}
+			if ill.StyleRef != nil {
+				entry.Style = ill.StyleRef.Name
+			}
+			if ill.Tags != "" {
+				entry.Tags = strings.Split(ill.Tags, ",")
+			}
+			manifest.Files = append(manifest.Files, entry)
+		}
+	}
+	if len(problems) > 0 {
+		return len(manifest.Files), problems, nil
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := writeZipEntry(zw, "manifest.json", stamp, manifestJSON); err != nil {
+		return 0, nil, err
+	}
+	if err := writeZipEntry(zw, "LICENSE.txt", stamp, []byte(PackLicenseText(pack.Name))); err != nil {
+		return 0, nil, err
+	}
+	return len(manifest.Files), nil, zw.Close()
+}
+
+// prunePackArchives removes every other archive of the same variant. Rebuilding the
+// default archive also drops option archives, which were built from the same (now
+// outdated) membership and are rebuilt on their next request. Objects are
+// content-addressed, so one shared with the kept archive is left in place.
+func prunePackArchives(pack *models.Pack, variant string, keepID uint) {
+	var keep models.PackArchive
+	config.DB.First(&keep, keepID)
+	q := config.DB.Where("pack_id = ? AND id <> ?", pack.ID, keepID)
+	if variant != defaultArchiveVariant {
+		q = q.Where("variant = ?", variant)
+	}
+	var old []models.PackArchive
+	if err := q.Find(&old).Error; err != nil {
+		slog.Error("list old pack archives failed", "error", err)
+		return
+	}
+	for _, a := range old {
+		if a.StorageKey != "" && a.StorageKey != keep.StorageKey {
+			removeObjectQuietly(a.StorageKey)
+		}
+		config.DB.Delete(&models.PackArchive{}, a.ID)
+	}
+}
+
+// archivePath places a file according to the layout and swaps the extension for the format.
+func archivePath(ill models.Illustration, layout, format string) string {
+	name := path.Base(strings.ReplaceAll(ill.FileName, "\\", "/"))
+	name = strings.TrimSuffix(name, path.Ext(name)) + "." + format
+	switch layout {
+	case ArchiveLayoutCategory:
+		return categoryFolder(ill) + "/" + name
+	case ArchiveLayoutStyle:
+		return styleFolder(ill) + "/" + name
+	}
+	return name
+}
+
+func categoryFolder(ill models.Illustration) string {
+	if ill.CategoryRef != nil && ill.CategoryRef.Slug != "" {
+		return ill.CategoryRef.Slug
+	}
+	return "uncategorized"
+}
+
+func styleFolder(ill models.Illustration) string {
+	if ill.StyleRef != nil && ill.StyleRef.Slug != "" {
+		return ill.StyleRef.Slug
+	}
+	return "unstyled"
+}
+
+// archiveNamer de-duplicates entry names case-insensitively: a.svg, a-2.svg, a-3.svg.
+type archiveNamer map[string]bool
+
+func (n archiveNamer) unique(name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 2; n[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	n[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func writeZipEntry(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified.UTC()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func readObject(ctx context.Context, storageKey string) ([]byte, error) {
+	obj, err := config.MinioClient.GetObject(ctx, config.BucketName, storageKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return io.ReadAll(obj)
+}
+
+// RenderSVGToPNG rasterizes an SVG so that its longer side is size pixels.
+func RenderSVGToPNG(svg []byte, size int) ([]byte, error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(svg), oksvg.WarnErrorMode)
+	if err != nil {
+		return nil, err
+	}
+	vw, vh := icon.ViewBox.W, icon.ViewBox.H
+	if vw <= 0 || vh <= 0 {
+		vw, vh = 1, 1
+	}
+	w, h := size, size
+	if vw > vh {
+		h = max(1, int(float64(size)*vh/vw))
+	} else {
+		w = max(1, int(float64(size)*vw/vh))
+	}
+	icon.SetTarget(0, 0, float64(w), float64(h))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	scanner := rasterx.NewScannerGV(w, h, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(w, h, scanner), 1)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OpenPackArchive returns a seekable stream of a ready archive (for Range requests).
+func OpenPackArchive(ctx context.Context, a *models.PackArchive) (*minio.Object, error) {
+	obj, err := config.MinioClient.GetObject(ctx, config.BucketName, a.StorageKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return obj, nil
+}
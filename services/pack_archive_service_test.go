@@ -0,0 +1,200 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+)
+
+func TestPackArchiveOptionsCacheable(t *testing.T) {
+	tests := []struct {
+		format, size, ids string
+		want              bool
+	}{
+		{"svg", "", "", true},
+		{"png", "", "", true},
+		{"both", "256", "", true},
+		{"png", "1024", "", true},
+		{"png", "300", "", false},
+		{"both", "1000", "", false},
+		{"svg", "", "1,2", false},
+		{"png", "512", "3", false},
+	}
+	for _, tt := range tests {
+		o, err := ParsePackArchiveOptions(tt.format, tt.size, tt.ids, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := o.Cacheable(); got != tt.want {
+			t.Errorf("format=%s size=%s ids=%s: Cacheable() = %v, want %v", tt.format, tt.size, tt.ids, got, tt.want)
+		}
+	}
+}
+
+func TestPackArchiveOptionLimits(t *testing.T) {
+	many := make([]string, maxUncachedFiles+1)
+	for i := range many {
+		many[i] = strconv.Itoa(i + 1)
+	}
+	for _, tt := range []struct{ size, ids string }{
+		{"2048", ""},
+		{"", strings.Join(many, ",")},
+	} {
+		if _, err := ParsePackArchiveOptions("png", tt.size, tt.ids, ""); !errors.Is(err, ErrInvalidArchiveOptions) {
+			t.Errorf("size=%s ids=%d: got %v, want ErrInvalidArchiveOptions", tt.size, len(strings.Split(tt.ids, ",")), err)
+		}
+	}
+}
+
+func TestEnqueuePackArchiveRejectsUncachedOptions(t *testing.T) {
+	prev := Jobs
+	Jobs = NewJobQueue(NewMemoryJobStore(), 1)
+	t.Cleanup(func() { Jobs = prev })
+	ctx := context.Background()
+
+	for _, o := range []PackArchiveOptions{
+		{Format: ArchiveFormatPNG, Size: 300, Layout: ArchiveLayoutFlat},
+		{Format: ArchiveFormatSVG, IDs: []uint{1}, Layout: ArchiveLayoutFlat},
+	} {
+		if _, err := EnqueuePackArchive(ctx, 1, o, time.Now()); !errors.Is(err, ErrInvalidArchiveOptions) {
+			t.Errorf("EnqueuePackArchive(%+v) = %v, want ErrInvalidArchiveOptions", o, err)
+		}
+	}
+	if _, err := EnqueuePackArchive(ctx, 1, PackArchiveOptions{Format: ArchiveFormatPNG, Size: 1024, Layout: ArchiveLayoutFlat}, time.Now()); err != nil {
+		t.Errorf("EnqueuePackArchive(png 1024) = %v", err)
+	}
+}
+
+func TestBuildUncachedPackArchive(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	pack := models.Pack{Name: "Space"}
+	if err := config.DB.Create(&pack).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for _, name := range []string{"rocket", "moon"} {
+		ill := models.Illustration{Title: name, FileName: name + ".svg", StorageKey: "illustrations/" + name + ".svg", PackID: &pack.ID}
+		if err := config.DB.Create(&ill).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, ill.ID)
+	}
+	bucket := &fakeBucket{objects: map[string][]byte{"illustrations/rocket.svg": []byte(testSVG)}}
+	useFakeStorage(t, bucket)
+	ctx := context.Background()
+
+	t.Run("a subset is built without being stored", func(t *testing.T) {
+		f, err := BuildUncachedPackArchive(ctx, &pack, PackArchiveOptions{Format: ArchiveFormatSVG, IDs: ids[:1], Layout: ArchiveLayoutFlat})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		zr, err := zip.NewReader(f, f.Archive.Size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, zf := range zr.File {
+			names = append(names, zf.Name)
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != "LICENSE.txt,manifest.json,rocket.svg" {
+			t.Errorf("archive holds %v", names)
+		}
+		if f.Archive.Status != models.PackArchiveReady || f.Archive.FileCount != 1 || f.Archive.ContentHash == "" {
+			t.Errorf("archive = %+v", f.Archive)
+		}
+		var stored int64
+		config.DB.Model(&models.PackArchive{}).Count(&stored)
+		if stored != 0 || bucket.uploads.Load() != 0 {
+			t.Errorf("%d archive rows and %d uploads, want none", stored, bucket.uploads.Load())
+		}
+	})
+
+	t.Run("missing files make it incomplete", func(t *testing.T) {
+		f, err := BuildUncachedPackArchive(ctx, &pack, PackArchiveOptions{Format: ArchiveFormatSVG, IDs: ids, Layout: ArchiveLayoutFlat})
+		if !errors.Is(err, ErrPackArchiveIncomplete) {
+			t.Fatalf("err = %v, want ErrPackArchiveIncomplete", err)
+		}
+		defer f.Close()
+		if !strings.Contains(f.Archive.Error, "moon") {
+			t.Errorf("error = %q, want it to name the missing file", f.Archive.Error)
+		}
+	})
+
+	t.Run("large packs are only built at cached sizes", func(t *testing.T) {
+		big := models.Pack{Name: "Big", Slug: "big"}
+		if err := config.DB.Create(&big).Error; err != nil {
+			t.Fatal(err)
+		}
+		for i := range maxUncachedFiles + 1 {
+			name := fmt.Sprintf("big-%d", i)
+			ill := models.Illustration{Title: name, FileName: name + ".svg", StorageKey: "illustrations/" + name + ".svg", PackID: &big.ID}
+			if err := config.DB.Create(&ill).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err := BuildUncachedPackArchive(ctx, &big, PackArchiveOptions{Format: ArchiveFormatPNG, Size: 300, Layout: ArchiveLayoutFlat})
+		if !errors.Is(err, ErrInvalidArchiveOptions) {
+			t.Errorf("err = %v, want ErrInvalidArchiveOptions", err)
+		}
+	})
+
+	t.Run("the temporary file is removed on close", func(t *testing.T) {
+		f, err := BuildUncachedPackArchive(ctx, &pack, PackArchiveOptions{Format: ArchiveFormatSVG, IDs: ids[:1], Layout: ArchiveLayoutFlat})
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := f.Name()
+		f.Close()
+		if _, err := os.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s still exists", name)
+		}
+	})
+}
+
+func TestIncompleteBuildKeepsTheLastReadyArchive(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	pack := models.Pack{Name: "Space", Slug: "space"}
+	if err := config.DB.Create(&pack).Error; err != nil {
+		t.Fatal(err)
+	}
+	add := func(name string) {
+		t.Helper()
+		ill := models.Illustration{Title: name, FileName: name + ".svg", StorageKey: "illustrations/" + name + ".svg", PackID: &pack.ID}
+		if err := config.DB.Create(&ill).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	useFakeStorage(t, &fakeBucket{objects: map[string][]byte{"illustrations/rocket.svg": []byte(testSVG)}})
+	ctx, o := context.Background(), defaultArchiveOptions()
+
+	add("rocket")
+	ready, err := BuildPackArchive(ctx, pack.ID, o)
+	if err != nil || ready.Status != models.PackArchiveReady {
+		t.Fatalf("first build = %+v, %v; want ready", ready, err)
+	}
+
+	add("moon") // not in storage
+	incomplete, err := BuildPackArchive(ctx, pack.ID, o)
+	if err != nil || incomplete.Status != models.PackArchiveIncomplete {
+		t.Fatalf("second build = %+v, %v; want incomplete", incomplete, err)
+	}
+	got, err := CurrentPackArchive(&pack, o)
+	if !errors.Is(err, ErrPackArchiveStale) || got == nil || got.ID != ready.ID {
+		t.Errorf("CurrentPackArchive = %+v, %v; want the first archive with ErrPackArchiveStale", got, err)
+	}
+}
@@ -1,11 +1,15 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"open-illustrations-go/config"
 	"open-illustrations-go/models"
+
+	"gorm.io/gorm"
 )
 
 func CreatePack(name string) (*models.Pack, error) {
@@ -16,15 +20,20 @@ func CreatePack(name string) (*models.Pack, error) {
 	return &p, nil
 }
 
-func GetPacks() ([]models.Pack, error) {
+func GetPacks(shape QueryShape) ([]models.Pack, error) {
 	var list []models.Pack
-	res := config.DB.Where("deleted_at IS NULL").Find(&list)
+	res := shape.apply(config.DB).Find(&list)
 	return list, res.Error
 }
 
 func GetPack(id string) (*models.Pack, error) {
+	return FindPack(id, QueryShape{})
+}
+
+// FindPack loads one pack with only the columns and relations in shape.
+func FindPack(id string, shape QueryShape) (*models.Pack, error) {
 	var p models.Pack
-	res := config.DB.First(&p, id)
+	res := shape.apply(config.DB).First(&p, id)
 	if res.Error != nil {
 		return nil, res.Error
 	}
@@ -67,3 +76,32 @@ func SoftDeletePack(id string) (*models.Pack, error) {
 func PackArchiveFileName(p *models.Pack) string {
 	return fmt.Sprintf("pack-%s-%d-%s.zip", p.Slug, p.ID, time.Now().Format("20060102"))
 }
+
+// FindOrCreatePack returns the pack with the given name (ignoring case), creating it if needed.
+// A soft-deleted pack with the same name is restored instead, since names are unique.
+func FindOrCreatePack(name string) (*models.Pack, error) {
+	name = strings.TrimSpace(name)
+	var p models.Pack
+	err := config.DB.Unscoped().Where("LOWER(name) = LOWER(?)", name).First(&p).Error
+	if err == nil {
+		if p.DeletedAt.Valid {
+			if err := config.DB.Unscoped().Model(&p).Update("deleted_at", nil).Error; err != nil {
+				return nil, err
+			}
+			p.DeletedAt = gorm.DeletedAt{}
+		}
+		return &p, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	created, err := CreatePack(name)
+	if err != nil {
+		// lost a race with a concurrent create; the row exists now
+		if err2 := config.DB.Where("LOWER(name) = LOWER(?)", name).First(&p).Error; err2 == nil {
+			return &p, nil
+		}
+		return nil, err
+	}
+	return created, nil
+}
@@ -0,0 +1,30 @@
+package services
+
+import "gorm.io/gorm"
+
+// QueryShape narrows a read query to what the response will actually use:
+// Columns limits the SELECT (nil = all columns) and Preloads names the
+// relations to load (nil = none). Limit > 0 returns one page of a list,
+// ordered by id, starting after Offset rows.
+type QueryShape struct {
+	Columns  []string
+	Preloads []string
+	Limit    int
+	Offset   int
+}
+
+// illustrationRefs are the relations loaded for callers that need the full row.
+var illustrationRefs = QueryShape{Preloads: []string{"CategoryRef", "PackRef", "StyleRef"}}
+
+func (q QueryShape) apply(db *gorm.DB) *gorm.DB {
+	if len(q.Columns) > 0 {
+		db = db.Select(q.Columns)
+	}
+	for _, p := range q.Preloads {
+		db = db.Preload(p)
+	}
+	if q.Limit > 0 {
+		db = db.Order("id").Limit(q.Limit).Offset(q.Offset)
+	}
+	return db
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"open-illustrations-go/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitPolicy is a token bucket: Burst requests at once, refilled at
+// PerMinute requests per minute.
+type RateLimitPolicy struct {
+	Name      string
+	PerMinute int
+	Burst     int
+}
+
+func (p RateLimitPolicy) perSecond() float64 {
+	return float64(p.PerMinute) / 60
+}
+
+// RateLimitResult is the outcome of one Allow call. Remaining is the number
+// of requests that could be made right now; Reset is how long until the
+// bucket is full again and RetryAfter how long until the next request is
+// allowed (zero when Allowed).
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets. key identifies the caller within
+// the policy.
+type RateLimitStore interface {
+	Allow(ctx context.Context, p RateLimitPolicy, key string, now time.Time) (RateLimitResult, error)
+}
+
+// Rate-limit policy names, used in bucket keys, metrics and the
+// RateLimit-Policy header.
+const (
+	RateLimitStream   = "stream"
+	RateLimitDownload = "download"
+)
+
+// RateLimitPolicyFor returns the configured policy for name (stream or
+// download) and whether rate limiting is on at all.
+func RateLimitPolicyFor(name string) (RateLimitPolicy, bool) {
+	rl := settings.RateLimit
+	p := RateLimitPolicy{Name: name}
+	switch name {
+	case RateLimitStream:
+		p.PerMinute, p.Burst = rl.StreamPerMinute, rl.StreamBurst
+	case RateLimitDownload:
+		p.PerMinute, p.Burst = rl.DownloadPerMinute, rl.DownloadBurst
+	default:
+		return p, false
+	}
+	return p, rl.Enabled
+}
+
+// RateLimits is the process-wide bucket store. RATE_LIMIT_STORE selects memory
+// (default, per instance) or redis (shared by all replicas).
+var RateLimits RateLimitStore = lazyRateLimitStore{}
+
+// lazyRateLimitStore defers the backend choice until first use, after
+// config.Redis is set.
+type lazyRateLimitStore struct{}
+
+var (
+	rateLimitOnce  sync.Once
+	rateLimitStore RateLimitStore
+)
+
+func (lazyRateLimitStore) Allow(ctx context.Context, p RateLimitPolicy, key string, now time.Time) (RateLimitResult, error) {
+	rateLimitOnce.Do(func() {
+		if settings.RateLimit.Store == "redis" && config.Redis != nil {
+			rateLimitStore = NewRedisRateLimitStore(config.Redis)
+			return
+		}
+		rateLimitStore = NewMemoryRateLimitStore()
+	})
+	return rateLimitStore.Allow(ctx, p, key, now)
+}
+
+// bucketResult fills in a RateLimitResult from the tokens left after the
+// request was (or was not) taken.
+func bucketResult(p RateLimitPolicy, allowed bool, tokens float64) RateLimitResult {
+	rate := p.perSecond()
+	res := RateLimitResult{
+		Allowed:   allowed,
+		Limit:     p.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(p.Burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return res
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket will have refilled; it can be dropped after
+}
+
+// NewMemoryRateLimitStore keeps buckets in process memory. Each replica
+// limits on its own, so the effective limit grows with the replica count.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: map[string]*rateBucket{}}
+}
+
+func (s *memoryRateLimitStore) Allow(_ context.Context, p RateLimitPolicy, key string, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	k := p.Name + ":" + key
+	b, ok := s.buckets[k]
+	if !ok {
+		b = &rateBucket{tokens: float64(p.Burst), last: now}
+		s.buckets[k] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(p.Burst), b.tokens+elapsed*p.perSecond())
+		b.last = now
+	}
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	res := bucketResult(p, allowed, b.tokens)
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
+
+// sweep drops refilled buckets once a minute; a full bucket is the same as
+// no bucket.
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if !b.full.After(now) {
+			delete(s.buckets, k)
+		}
+	}
+}
+
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+// NewRedisRateLimitStore keeps buckets in Redis so all replicas share them.
+// Each bucket is a hash that expires once it would have refilled.
+func NewRedisRateLimitStore(client *redis.Client) RateLimitStore {
+	return &redisRateLimitStore{client: client}
+}
+
+// rateBucketScript refills and takes from one bucket atomically.
+// ARGV: tokens per millisecond, burst, now in milliseconds.
+// Returns {allowed (0 or 1), tokens left as a string}.
+var rateBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1]) or burst
+local ts = tonumber(b[2]) or now
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) * rate)
+  ts = now
+end
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+func (s *redisRateLimitStore) Allow(ctx context.Context, p RateLimitPolicy, key string, now time.Time) (RateLimitResult, error) {
+	out, err := rateBucketScript.Run(ctx, s.client, []string{"ratelimit:" + p.Name + ":" + key},
+		p.perSecond()/1000, p.Burst, now.UnixMilli()).Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(out) != 2 {
+		return RateLimitResult{}, fmt.Errorf("rate limit script: unexpected reply %v", out)
+	}
+	allowed, _ := out[0].(int64)
+	raw, _ := out[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("rate limit script: %w", err)
+	}
+	return bucketResult(p, allowed == 1, tokens), nil
+}
+
+var packDownloads struct {
+	once  sync.Once
+	slots chan struct{}
+}
+
+// AcquirePackDownload takes one of the PACK_DOWNLOAD_CONCURRENCY slots for
+// streaming a pack archive. It returns false when all are in use; otherwise
+// the caller must call release when the download ends.
+func AcquirePackDownload() (release func(), ok bool) {
+	packDownloads.once.Do(func() {
+		packDownloads.slots = make(chan struct{}, settings.RateLimit.PackDownloadConcurrency)
+	})
+	select {
+	case packDownloads.slots <- struct{}{}:
+		return func() { <-packDownloads.slots }, true
+	default:
+		return nil, false
+	}
+}
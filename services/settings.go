@@ -0,0 +1,13 @@
+package services
+
+import "open-illustrations-go/config"
+
+// settings is the configuration the services run with. Configure replaces it
+// at startup; until then the built-in defaults apply.
+var settings = config.Default()
+
+// Configure hands the validated configuration to the services. Call it before
+// InitJobs and any other service.
+func Configure(cfg *config.Config) {
+	settings = *cfg
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"gorm.io/gorm"
+)
+
+// envKeyID identifies ASSET_SIGNING_SECRET in the keyring. It signs tokens only
+// while no key has been created in the database, and always verifies legacy
+// (pre-keyring) tokens.
+const envKeyID = "env"
+
+var ErrNoSigningKey = errors.New("no asset signing key configured (set ASSET_SIGNING_SECRET or rotate a key)")
+
+// keyring caches signing keys from the database; it is refreshed periodically so
+// every replica picks up a rotation, and on demand when a token names an unknown kid.
+type keyring struct {
+	mu       sync.RWMutex
+	keys     map[string][]byte
+	active   string
+	loadedAt time.Time
+}
+
+var assetKeys = &keyring{}
+
+const keyringRefresh = 30 * time.Second
+
+// SigningKeyRetention is how long a rotated-out key keeps verifying tokens
+// (ASSET_KEY_RETENTION_HOURS, default 24). Validation rejects a retention
+// shorter than config.Config.MaxTokenTTL.
+func SigningKeyRetention() time.Duration {
+	return time.Duration(settings.Assets.KeyRetentionHours) * time.Hour
+}
+
+// SigningKeyRotationInterval is the automatic rotation period; 0 disables it.
+// Set by ASSET_KEY_ROTATION_DAYS.
+func SigningKeyRotationInterval() time.Duration {
+	return time.Duration(settings.Assets.KeyRotationDays) * 24 * time.Hour
+}
+
+func (k *keyring) load() error {
+	keys := map[string][]byte{}
+	active := ""
+	if sec := settings.Assets.SigningSecret; sec != "" {
+		keys[envKeyID] = []byte(sec)
+		active = envKeyID
+	}
+	if config.DB != nil {
+		var rows []models.SigningKey
+		err := config.DB.Where("status = ? OR (status = ? AND verify_until > ?)", models.SigningKeyActive, models.SigningKeyVerify, time.Now()).
+			Order("id").Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			secret, err := base64.RawStdEncoding.DecodeString(r.Secret)
+			if err != nil {
+				slog.Warn("signing key has an invalid secret, skipping", "kid", r.KID)
+				continue
+			}
+			keys[r.KID] = secret
+			if r.Status == models.SigningKeyActive {
+				active = r.KID
+			}
+		}
+	}
+	k.mu.Lock()
+	k.keys, k.active, k.loadedAt = keys, active, time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *keyring) fresh() {
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) > keyringRefresh
+	k.mu.RUnlock()
+	if stale {
+		if err := k.load(); err != nil {
+			slog.Error("signing keyring reload failed", "error", err)
+		}
+	}
+}
+
+// signingKey returns the active key used to mint tokens.
+func (k *keyring) signingKey() (string, []byte, error) {
+	k.fresh()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.active == "" {
+		return "", nil, ErrNoSigningKey
+	}
+	return k.active, k.keys[k.active], nil
+}
+
+// verificationKey looks up a key by id, reloading once for kids minted by another
+// replica after our last refresh.
+func (k *keyring) verificationKey(kid string) ([]byte, bool) {
+	k.fresh()
+	k.mu.RLock()
+	secret, ok := k.keys[kid]
+	recent := time.Since(k.loadedAt) < time.Second
+	k.mu.RUnlock()
+	if ok || recent {
+		return secret, ok
+	}
+	if err := k.load(); err != nil {
+		return nil, false
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	secret, ok = k.keys[kid]
+	return secret, ok
+}
+
+// ListSigningKeys returns the stored keys (secrets are never serialized).
+func ListSigningKeys() ([]models.SigningKey, error) {
+	var list []models.SigningKey
+	return list, config.DB.Order("id DESC").Find(&list).Error
+}
+
+// RotateSigningKey creates a new active key and demotes the current one to
+// verification-only for SigningKeyRetention, so outstanding tokens keep working.
+// Keys whose retention has passed are deleted. Concurrent rotations (e.g. the
+// schedule firing on several replicas) produce a single new key: demoting the
+// current key is conditional on it still being active, and the unique
+// active_slot index rejects a second active key when there was none to demote.
+func RotateSigningKey() (*models.SigningKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	kid := make([]byte, 6)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+	slot := 1
+	key := models.SigningKey{
+		KID:        hex.EncodeToString(kid),
+		Secret:     base64.RawStdEncoding.EncodeToString(secret),
+		Status:     models.SigningKeyActive,
+		ActiveSlot: &slot,
+	}
+
+	now := time.Now()
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var current []models.SigningKey
+		if err := tx.Where("status = ?", models.SigningKeyActive).Find(&current).Error; err != nil {
+			return err
+		}
+		until := now.Add(SigningKeyRetention())
+		for _, cur := range current {
+			res := tx.Model(&models.SigningKey{}).
+				Where("id = ? AND status = ?", cur.ID, models.SigningKeyActive).
+				Updates(map[string]interface{}{"status": models.SigningKeyVerify, "verify_until": until, "active_slot": nil})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errConcurrentRotation
+			}
+		}
+		if err := tx.Where("status = ? AND verify_until < ?", models.SigningKeyVerify, now).Delete(&models.SigningKey{}).Error; err != nil {
+			return err
+		}
+		err := tx.Create(&key).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return errConcurrentRotation
+		}
+		return err
+	})
+	if errors.Is(err, errConcurrentRotation) {
+		var winner models.SigningKey
+		if err := config.DB.Where("status = ?", models.SigningKeyActive).Order("id DESC").First(&winner).Error; err != nil {
+			return nil, err
+		}
+		return &winner, assetKeys.load()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, assetKeys.load()
+}
+
+var errConcurrentRotation = errors.New("signing key rotated concurrently")
+
+// StartKeyRotation rotates the active key whenever it is older than
+// SigningKeyRotationInterval. It does nothing if the interval is 0.
+func StartKeyRotation(ctx context.Context) {
+	every := SigningKeyRotationInterval()
+	if every <= 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(time.Hour)
+		defer t.Stop()
+		for {
+			rotateIfDue(every)
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+func rotateIfDue(every time.Duration) {
+	var active models.SigningKey
+	err := config.DB.Where("status = ?", models.SigningKeyActive).Order("id DESC").First(&active).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.Error("key rotation check failed", "error", err)
+		return
+	}
+	if err == nil && time.Since(active.CreatedAt) < every {
+		return
+	}
+	key, err := RotateSigningKey()
+	if err != nil {
+		slog.Error("scheduled key rotation failed", "error", err)
+		return
+	}
+	slog.Info("rotated asset signing key", "kid", key.KID)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// StorageReport compares illustration records with the objects in the bucket.
+// Only top-level objects are considered: imports/, packs/ and uploads/ hold
+// archives and chunks that have their own cleanup.
+type StorageReport struct {
+	Objects  int             `json:"objects"`
+	Records  int             `json:"records"`
+	Missing  []MissingObject `json:"missing"`
+	Orphans  []OrphanObject  `json:"orphans"`
+	Deleted  int             `json:"deleted"`
+	Duration time.Duration   `json:"duration"`
+}
+
+// MissingObject is a live illustration whose file is not in the bucket.
+type MissingObject struct {
+	IllustrationID uint   `json:"illustration_id"`
+	Title          string `json:"title"`
+	StorageKey     string `json:"storage_key"`
+}
+
+// OrphanObject is an object that no illustration (deleted or not) points to.
+type OrphanObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// ReconcileOptions controls what ReconcileStorage changes.
+type ReconcileOptions struct {
+	// DeleteOrphans removes orphaned objects older than Grace. Uploads write
+	// the object before the record, so young orphans may still be claimed.
+	DeleteOrphans bool
+	Grace         time.Duration
+}
+
+// ReconcileStorage lists the bucket and the illustrations table and reports the
+// differences. Missing objects are only reported; there is nothing to restore from.
+func ReconcileStorage(ctx context.Context, opts ReconcileOptions) (*StorageReport, error) {
+	start := time.Now()
+	var ills []models.Illustration
+	if err := config.DB.Unscoped().Select("id", "title", "storage_key", "deleted_at").Find(&ills).Error; err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(ills))
+	for _, ill := range ills {
+		known[ill.StorageKey] = true
+	}
+
+	report := &StorageReport{Records: len(ills), Missing: []MissingObject{}, Orphans: []OrphanObject{}}
+	present := map[string]bool{}
+	for obj := range config.MinioClient.ListObjects(ctx, config.BucketName, minio.ListObjectsOptions{}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		if strings.HasSuffix(obj.Key, "/") {
+			continue // imports/, packs/, uploads/
+		}
+		report.Objects++
+		present[obj.Key] = true
+		if !known[obj.Key] {
+			report.Orphans = append(report.Orphans, OrphanObject{Key: obj.Key, Size: obj.Size, LastModified: obj.LastModified})
+		}
+	}
+	for _, ill := range ills {
+		if !ill.DeletedAt.Valid && !present[ill.StorageKey] {
+			report.Missing = append(report.Missing, MissingObject{IllustrationID: ill.ID, Title: ill.Title, StorageKey: ill.StorageKey})
+		}
+	}
+
+	if opts.DeleteOrphans {
+		cutoff := time.Now().Add(-opts.Grace)
+		for _, o := range report.Orphans {
+			if o.LastModified.After(cutoff) {
+				continue
+			}
+			if err := config.MinioClient.RemoveObject(ctx, config.BucketName, o.Key, minio.RemoveObjectOptions{}); err != nil {
+				return report, err
+			}
+			report.Deleted++
+		}
+	}
+	report.Duration = time.Since(start)
+	return report, nil
+}
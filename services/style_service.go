@@ -1,11 +1,14 @@
 package services
 
 import (
+	"errors"
 	"strings"
 	"time"
 
 	"open-illustrations-go/config"
 	"open-illustrations-go/models"
+
+	"gorm.io/gorm"
 )
 
 func slugifyStyle(s string) string {
@@ -23,15 +26,20 @@ func CreateStyle(name string) (*models.Style, error) {
 	return &s, nil
 }
 
-func GetStyles() ([]models.Style, error) {
+func GetStyles(shape QueryShape) ([]models.Style, error) {
 	var list []models.Style
-	res := config.DB.Where("deleted_at IS NULL").Find(&list)
+	res := shape.apply(config.DB).Find(&list)
 	return list, res.Error
 }
 
 func GetStyle(id string) (*models.Style, error) {
+	return FindStyle(id, QueryShape{})
+}
+
+// FindStyle loads one style with only the columns and relations in shape.
+func FindStyle(id string, shape QueryShape) (*models.Style, error) {
 	var s models.Style
-	res := config.DB.First(&s, id)
+	res := shape.apply(config.DB).First(&s, id)
 	if res.Error != nil {
 		return nil, res.Error
 	}
@@ -64,3 +72,32 @@ func SoftDeleteStyle(id string) (*models.Style, error) {
 	}
 	return &s, nil
 }
+
+// FindOrCreateStyle returns the style with the given name (ignoring case), creating it if needed.
+// A soft-deleted style with the same name is restored instead, since names are unique.
+func FindOrCreateStyle(name string) (*models.Style, error) {
+	name = strings.TrimSpace(name)
+	var s models.Style
+	err := config.DB.Unscoped().Where("LOWER(name) = LOWER(?)", name).First(&s).Error
+	if err == nil {
+		if s.DeletedAt.Valid {
+			if err := config.DB.Unscoped().Model(&s).Update("deleted_at", nil).Error; err != nil {
+				return nil, err
+			}
+			s.DeletedAt = gorm.DeletedAt{}
+		}
+		return &s, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	created, err := CreateStyle(name)
+	if err != nil {
+		// lost a race with a concurrent create; the row exists now
+		if err2 := config.DB.Where("LOWER(name) = LOWER(?)", name).First(&s).Error; err2 == nil {
+			return &s, nil
+		}
+		return nil, err
+	}
+	return created, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"gorm.io/gorm"
+)
+
+func TestFindOrCreateCategoryIgnoresCase(t *testing.T) {
+	useTestDB(t)
+
+	first, err := FindOrCreateCategory("Nature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := FindOrCreateCategory("  nature ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID {
+		t.Fatalf("got category %d, want existing %d", again.ID, first.ID)
+	}
+}
+
+func TestFindOrCreateRestoresSoftDeleted(t *testing.T) {
+	useTestDB(t)
+
+	s, err := CreateStyle("Flat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.Itoa(int(s.ID))
+	if _, err := SoftDeleteStyle(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetStyle(id); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetStyle after delete: err = %v, want not found", err)
+	}
+
+	restored, err := FindOrCreateStyle("flat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ID != s.ID || restored.DeletedAt.Valid {
+		t.Fatalf("got %+v, want style %d restored", restored, s.ID)
+	}
+}
+
+func TestCreateCategoryDuplicateName(t *testing.T) {
+	useTestDB(t)
+
+	if _, err := CreateCategory("Food"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateCategory("Food"); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Fatalf("err = %v, want gorm.ErrDuplicatedKey", err)
+	}
+}
+
+func TestIllustrationsByTaxonomy(t *testing.T) {
+	useTestDB(t)
+	ctx := context.Background()
+
+	cat, err := CreateCategory("Animals")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack, err := CreatePack("Starter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []models.Illustration{
+		{Title: "Cat", FileName: "cat.svg", StorageKey: "illustrations/cat.svg", CategoryID: &cat.ID, PackID: &pack.ID},
+		{Title: "Dog", FileName: "dog.svg", StorageKey: "illustrations/dog.svg", CategoryID: &cat.ID},
+		{Title: "Tree", FileName: "tree.svg", StorageKey: "illustrations/tree.svg"},
+	}
+	if err := config.DB.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := GetIllustrations(ctx, QueryShape{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("GetIllustrations = %d rows, %v; want 3", len(all), err)
+	}
+	page, err := GetIllustrations(ctx, QueryShape{Columns: []string{"title"}, Limit: 2, Offset: 1})
+	if err != nil || len(page) != 2 || page[0].Title != "Dog" || page[1].Title != "Tree" {
+		t.Fatalf("GetIllustrations page = %+v, %v; want [Dog Tree]", page, err)
+	}
+	byCat, err := GetIllustrationsByCategory(ctx, strconv.Itoa(int(cat.ID)), QueryShape{})
+	if err != nil || len(byCat) != 2 {
+		t.Fatalf("GetIllustrationsByCategory = %d rows, %v; want 2", len(byCat), err)
+	}
+	byPack, err := GetIllustrationsByPack(ctx, strconv.Itoa(int(pack.ID)), QueryShape{})
+	if err != nil || len(byPack) != 1 || byPack[0].Title != "Cat" {
+		t.Fatalf("GetIllustrationsByPack = %+v, %v; want [Cat]", byPack, err)
+	}
+
+	ill, err := GetIllustration(ctx, strconv.Itoa(int(rows[0].ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ill.CategoryRef == nil || ill.CategoryRef.Name != "Animals" || ill.PackRef == nil || ill.StyleRef != nil {
+		t.Fatalf("relations not preloaded as expected: %+v", ill)
+	}
+
+	narrow, err := FindIllustration(ctx, strconv.Itoa(int(rows[1].ID)), QueryShape{Columns: []string{"id", "title"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if narrow.Title != "Dog" || narrow.StorageKey != "" {
+		t.Fatalf("column selection ignored: %+v", narrow)
+	}
+}
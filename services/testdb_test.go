@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/logging"
+	"open-illustrations-go/migrations"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// useTestDB points config.DB at a fresh in-memory SQLite database with every
+// migration applied, and puts the previous handle back when the test ends.
+func useTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared&_pragma=foreign_keys(1)"),
+		&gorm.Config{TranslateError: true, Logger: logging.GORM(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// same as production SQLite: one writer at a time
+	sqlDB.SetMaxOpenConns(1)
+	if _, err := migrations.Up(context.Background(), db, 0); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	prev := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = prev
+		sqlDB.Close()
+	})
+	return db
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"open-illustrations-go/metrics"
+)
+
+// URLContext says where a signed URL is going to be used; each context has its
+// own token lifetime.
+type URLContext string
+
+const (
+	URLContextList     URLContext = "list"
+	URLContextDetail   URLContext = "detail"
+	URLContextDownload URLContext = "download"
+	URLContextInternal URLContext = "internal"
+)
+
+// TokenPolicy picks the lifetime of a signed asset URL.
+//
+//	ASSET_TTL_LIST_SECONDS       list endpoints (default 900)
+//	ASSET_TTL_DETAIL_SECONDS     single-illustration endpoints (default 900)
+//	ASSET_TTL_DOWNLOAD_SECONDS   file/download URLs (default 300)
+//	PRESIGN_TTL_SECONDS          internal callers (see PresignTTL)
+//	ASSET_TTL_TIER_<TIER>_SECONDS  overrides the above for an entitlement tier
+//	ASSET_TTL_BUCKET_SECONDS     expiry rounding, see Window (default 300, 0 = off)
+type TokenPolicy struct {
+	Context URLContext
+	// Tier is the caller's entitlement tier, empty for anonymous callers.
+	Tier string
+}
+
+var defaultContextTTL = map[URLContext]int{
+	URLContextList:     900,
+	URLContextDetail:   900,
+	URLContextDownload: 300,
+}
+
+// TTL returns the minimum lifetime of a token issued under p, clamped to
+// [60s, 24h]. Config validation keeps ASSET_KEY_RETENTION_HOURS at least as
+// long as the longest TTL plus one bucket, so a token never outlives the key
+// that signed it.
+func (p TokenPolicy) TTL() time.Duration {
+	if p.Tier != "" {
+		if n, ok := ttlSeconds(settings.Assets.TierTTLSeconds, strings.ToLower(p.Tier)); ok {
+			return n
+		}
+	}
+	if p.Context == URLContextInternal {
+		return PresignTTL()
+	}
+	if n, ok := ttlSeconds(settings.Assets.ContextTTLSeconds, string(p.Context)); ok {
+		return n
+	}
+	if n, ok := defaultContextTTL[p.Context]; ok {
+		return time.Duration(n) * time.Second
+	}
+	return 15 * time.Minute
+}
+
+func ttlSeconds(overrides map[string]int, key string) (time.Duration, bool) {
+	n, ok := overrides[key]
+	if !ok {
+		return 0, false
+	}
+	if n < 60 {
+		n = 60
+	}
+	if n > 86400 {
+		n = 86400
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// tokenBucket is the granularity of issue and expiry times.
+func tokenBucket() time.Duration {
+	return time.Duration(settings.Assets.TTLBucketSeconds) * time.Second
+}
+
+// Window returns the issue and expiry times for a token minted at now. Both are
+// aligned to the bucket, so every request inside one bucket gets the same token
+// (and the same URL, which keeps browser and CDN caches warm). The token stays
+// valid for at least TTL and at most TTL plus one bucket.
+func (p TokenPolicy) Window(now time.Time) (issuedAt, expiresAt time.Time) {
+	ttl := p.TTL()
+	bucket := tokenBucket()
+	if bucket <= 0 {
+		return now, now.Add(ttl)
+	}
+	start := now.Truncate(bucket)
+	return start, start.Add(bucket + ttl)
+}
+
+// IssuePolicyToken signs claims with the lifetime chosen by p. Single-use tokens
+// are never bucketed since each one is unique anyway. When the storage key (or
+// every token) was revoked after the bucket started, the issue time moves past
+// the revocation so the new token is not born revoked; it is still shared by the
+// rest of the bucket.
+func IssuePolicyToken(claims AssetClaims, p TokenPolicy) (string, time.Time, error) {
+	now := time.Now()
+	iat, exp := p.Window(now)
+	if claims.Nonce != "" {
+		iat, exp = now, now.Add(p.TTL())
+	}
+	if nb := revocations.notBefore(claims.StorageKey); iat.Before(nb) {
+		iat = nb
+	}
+	claims.IssuedAt, claims.ExpiresAt = iat.Unix(), exp.Unix()
+	tok, err := IssueAssetToken(claims, 0)
+	if err == nil {
+		metrics.TokensIssued.WithLabelValues(string(p.Context)).Inc()
+	}
+	return tok, exp, err
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"gorm.io/gorm"
+)
+
+// ReplayStore remembers nonces of single-use tokens. Consume reports whether
+// this is the first time the nonce has been seen.
+type ReplayStore interface {
+	Consume(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+// TokenReplays is the process-wide replay store. TOKEN_REPLAY_STORE selects db
+// (default, shared by all replicas) or memory (single instance only).
+var TokenReplays ReplayStore = lazyReplayStore{}
+
+// lazyReplayStore defers the backend choice until first use, after config.DB is set.
+type lazyReplayStore struct{}
+
+var (
+	replayOnce  sync.Once
+	replayStore ReplayStore
+)
+
+func (lazyReplayStore) get() ReplayStore {
+	replayOnce.Do(func() {
+		if settings.Assets.ReplayStore == "memory" || config.DB == nil {
+			replayStore = NewMemoryReplayStore()
+			return
+		}
+		replayStore = NewDBReplayStore(config.DB)
+	})
+	return replayStore
+}
+
+func (l lazyReplayStore) Consume(ctx context.Context, nonce string, exp time.Time) (bool, error) {
+	return l.get().Consume(ctx, nonce, exp)
+}
+
+func (l lazyReplayStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	return l.get().Purge(ctx, now)
+}
+
+type dbReplayStore struct {
+	db *gorm.DB
+}
+
+// NewDBReplayStore keeps used nonces in the used_nonces table; the unique index
+// makes Consume atomic across replicas.
+func NewDBReplayStore(db *gorm.DB) ReplayStore {
+	return &dbReplayStore{db: db}
+}
+
+func (s *dbReplayStore) Consume(ctx context.Context, nonce string, exp time.Time) (bool, error) {
+	err := s.db.WithContext(ctx).Create(&models.UsedNonce{Nonce: nonce, ExpiresAt: exp}).Error
+	if err == nil {
+		return true, nil
+	}
+	if apierror.IsDuplicateKey(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *dbReplayStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.UsedNonce{})
+	return res.RowsAffected, res.Error
+}
+
+type memoryReplayStore struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewMemoryReplayStore keeps used nonces in process memory.
+func NewMemoryReplayStore() ReplayStore {
+	return &memoryReplayStore{seen: map[string]time.Time{}}
+}
+
+func (s *memoryReplayStore) Consume(_ context.Context, nonce string, exp time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.seen[nonce]; ok {
+		return false, nil
+	}
+	s.seen[nonce] = exp
+	return true, nil
+}
+
+func (s *memoryReplayStore) Purge(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for k, exp := range s.seen {
+		if exp.Before(now) {
+			delete(s.seen, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// StartReplayJanitor drops expired nonces every interval until ctx is done.
+func StartReplayJanitor(ctx context.Context, every time.Duration) {
+	go func() {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if n, err := TokenReplays.Purge(ctx, time.Now()); err != nil {
+					slog.Error("replay store purge failed", "error", err)
+				} else if n > 0 {
+					slog.Info("purged expired token nonces", "count", n)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"open-illustrations-go/apierror"
+	"open-illustrations-go/models"
+)
+
+func TestDBReplayStoreConsumesOnce(t *testing.T) {
+	db := useTestDB(t)
+	store := NewDBReplayStore(db)
+	ctx := context.Background()
+	exp := time.Now().Add(time.Minute)
+
+	for i, want := range []bool{true, false, false} {
+		first, err := store.Consume(ctx, "nonce-1", exp)
+		if err != nil {
+			t.Fatalf("consume %d: %v", i+1, err)
+		}
+		if first != want {
+			t.Errorf("consume %d = %v, want %v", i+1, first, want)
+		}
+	}
+	if first, err := store.Consume(ctx, "nonce-2", exp); err != nil || !first {
+		t.Errorf("other nonce = %v, %v; want true", first, err)
+	}
+}
+
+func TestIsDuplicateKeyUsesTranslatedErrors(t *testing.T) {
+	db := useTestDB(t)
+	if err := db.Create(&models.UsedNonce{Nonce: "n", ExpiresAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := db.Create(&models.UsedNonce{Nonce: "n", ExpiresAt: time.Now()}).Error
+	if !apierror.IsDuplicateKey(err) {
+		t.Errorf("IsDuplicateKey(%v) = false on a unique violation", err)
+	}
+	if err := errors.New(`style "duplicate" not found`); apierror.IsDuplicateKey(err) {
+		t.Errorf("IsDuplicateKey(%v) = true for an error that merely mentions duplicate", err)
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrTokenRevoked      = errors.New("token revoked")
+	ErrInvalidRevocation = errors.New("invalid revocation")
+)
+
+// RevocationInput is one revoke request. Token is a convenience: it is parsed and
+// revoked by nonce when it has one, otherwise by storage key.
+type RevocationInput struct {
+	Token        string     `json:"token"`
+	Nonce        string     `json:"nonce"`
+	StorageKey   string     `json:"storage_key"`
+	IssuedBefore *time.Time `json:"issued_before"`
+	Reason       string     `json:"reason"`
+}
+
+// revocationList caches the token_revocations table in memory. Like the keyring it
+// is refreshed periodically, so a revocation made on one replica reaches the
+// others within revocationRefresh.
+type revocationList struct {
+	mu       sync.RWMutex
+	nonces   map[string]bool
+	keys     map[string]time.Time
+	cutoff   time.Time
+	loadedAt time.Time
+	reload   singleflight.Group
+}
+
+var revocations = &revocationList{}
+
+const revocationRefresh = 30 * time.Second
+
+func (l *revocationList) load() error {
+	var rows []models.TokenRevocation
+	if config.DB != nil {
+		if err := config.DB.Find(&rows).Error; err != nil {
+			return err
+		}
+	}
+	nonces := map[string]bool{}
+	keys := map[string]time.Time{}
+	var cutoff time.Time
+	for _, r := range rows {
+		switch r.Kind {
+		case models.RevokeNonce:
+			nonces[r.Nonce] = true
+		case models.RevokeStorageKey:
+			if r.RevokedAt.After(keys[r.StorageKey]) {
+				keys[r.StorageKey] = r.RevokedAt
+			}
+		case models.RevokeIssuedBefore:
+			if r.RevokedAt.After(cutoff) {
+				cutoff = r.RevokedAt
+			}
+		}
+	}
+	l.mu.Lock()
+	l.nonces, l.keys, l.cutoff, l.loadedAt = nonces, keys, cutoff, time.Now()
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *revocationList) stale() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return time.Since(l.loadedAt) > revocationRefresh
+}
+
+// fresh reloads the list once it is older than revocationRefresh. Requests that
+// find it stale while a reload is running wait for that reload instead of
+// starting their own.
+func (l *revocationList) fresh() {
+	if !l.stale() {
+		return
+	}
+	_, err, _ := l.reload.Do("", func() (any, error) {
+		if !l.stale() {
+			return nil, nil
+		}
+		return nil, l.load()
+	})
+	if err != nil {
+		slog.Error("revocation list reload failed", "error", err)
+	}
+}
+
+// revoked reports whether claims are covered by a revocation. Tokens without an
+// issue time (formats before v3) count as issued at the epoch.
+func (l *revocationList) revoked(claims *AssetClaims) bool {
+	l.fresh()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if claims.Nonce != "" && l.nonces[claims.Nonce] {
+		return true
+	}
+	iat := time.Unix(claims.IssuedAt, 0)
+	if !l.cutoff.IsZero() && !iat.After(l.cutoff) {
+		return true
+	}
+	if at, ok := l.keys[claims.StorageKey]; ok && !iat.After(at) {
+		return true
+	}
+	return false
+}
+
+// notBefore returns the earliest issue time a new token for storageKey can carry
+// without being covered by an existing revocation, or the zero time when none
+// applies. Issue times are whole seconds, so it is the second after the latest
+// revocation.
+func (l *revocationList) notBefore(storageKey string) time.Time {
+	l.fresh()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	at := l.cutoff
+	if k, ok := l.keys[storageKey]; ok && k.After(at) {
+		at = k
+	}
+	if at.IsZero() {
+		return at
+	}
+	return at.Truncate(time.Second).Add(time.Second)
+}
+
+// CheckRevoked returns ErrTokenRevoked if claims have been revoked.
+func CheckRevoked(claims *AssetClaims) error {
+	if revocations.revoked(claims) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// RevokeTokens stores a revocation and refreshes the local cache immediately.
+func RevokeTokens(in RevocationInput) (*models.TokenRevocation, error) {
+	rev := models.TokenRevocation{Reason: truncate(in.Reason, 255), RevokedAt: time.Now()}
+	switch {
+	case in.Token != "":
+		claims, err := ParseAssetToken(in.Token)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRevocation, err)
+		}
+		if claims.Nonce != "" {
+			rev.Kind, rev.Nonce = models.RevokeNonce, claims.Nonce
+		} else {
+			rev.Kind, rev.StorageKey = models.RevokeStorageKey, claims.StorageKey
+		}
+	case in.Nonce != "":
+		rev.Kind, rev.Nonce = models.RevokeNonce, in.Nonce
+	case in.StorageKey != "":
+		rev.Kind, rev.StorageKey = models.RevokeStorageKey, in.StorageKey
+	case in.IssuedBefore != nil:
+		if in.IssuedBefore.After(rev.RevokedAt) {
+			return nil, fmt.Errorf("%w: issued_before is in the future", ErrInvalidRevocation)
+		}
+		rev.Kind, rev.RevokedAt = models.RevokeIssuedBefore, *in.IssuedBefore
+	default:
+		return nil, fmt.Errorf("%w: one of token, nonce, storage_key or issued_before is required", ErrInvalidRevocation)
+	}
+	if err := config.DB.Create(&rev).Error; err != nil {
+		return nil, err
+	}
+	if err := revocations.load(); err != nil {
+		slog.Error("revocation list reload failed", "error", err)
+	}
+	return &rev, nil
+}
+
+// ListRevocations returns revocations, newest first.
+func ListRevocations() ([]models.TokenRevocation, error) {
+	var list []models.TokenRevocation
+	return list, config.DB.Order("id DESC").Find(&list).Error
+}
+
+// DeleteRevocation lifts a revocation.
+func DeleteRevocation(id uint) error {
+	res := config.DB.Delete(&models.TokenRevocation{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRevocationNotFound
+	}
+	return revocations.load()
+}
+
+var ErrRevocationNotFound = errors.New("revocation not found")
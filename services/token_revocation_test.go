@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// useTestSettings lets a test change the service settings and puts them back
+// when it ends. The keyring and revocation list are reloaded on next use so
+// they see the test database and secret.
+func useTestSettings(t *testing.T) {
+	t.Helper()
+	prev := settings
+	settings.Assets.SigningSecret = "test-signing-secret"
+	resetTokenCaches()
+	t.Cleanup(func() {
+		settings = prev
+		resetTokenCaches()
+	})
+}
+
+func resetTokenCaches() {
+	assetKeys.mu.Lock()
+	assetKeys.loadedAt = time.Time{}
+	assetKeys.mu.Unlock()
+	revocations.mu.Lock()
+	revocations.loadedAt = time.Time{}
+	revocations.mu.Unlock()
+}
+
+func TestRevokedKeyStillIssuesValidTokens(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	settings.Assets.TTLBucketSeconds = 300
+	p := TokenPolicy{Context: URLContextDetail}
+	claims := AssetClaims{StorageKey: "illustrations/a.svg"}
+
+	old, _, err := IssuePolicyToken(claims, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RevokeTokens(RevocationInput{StorageKey: claims.StorageKey}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkToken(old); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("token issued before the revocation: got %v, want ErrTokenRevoked", err)
+	}
+
+	fresh, _, err := IssuePolicyToken(claims, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkToken(fresh); err != nil {
+		t.Fatalf("token issued after the revocation: %v", err)
+	}
+	again, _, err := IssuePolicyToken(claims, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != fresh {
+		t.Error("tokens issued in the same bucket after a revocation differ")
+	}
+}
+
+func TestIssuedBeforeRevocationSparesNewTokens(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	settings.Assets.TTLBucketSeconds = 300
+	p := TokenPolicy{Context: URLContextList}
+	claims := AssetClaims{StorageKey: "illustrations/b.svg"}
+
+	old, _, err := IssuePolicyToken(claims, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if _, err := RevokeTokens(RevocationInput{IssuedBefore: &now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkToken(old); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("token issued before the cutoff: got %v, want ErrTokenRevoked", err)
+	}
+	fresh, _, err := IssuePolicyToken(claims, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkToken(fresh); err != nil {
+		t.Fatalf("token issued after the cutoff: %v", err)
+	}
+}
+
+func TestRevocationLeavesOtherKeysBucketed(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	settings.Assets.TTLBucketSeconds = 300
+	if _, err := RevokeTokens(RevocationInput{StorageKey: "illustrations/a.svg"}); err != nil {
+		t.Fatal(err)
+	}
+	tok, _, err := IssuePolicyToken(AssetClaims{StorageKey: "illustrations/c.svg"}, TokenPolicy{Context: URLContextList})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseAssetToken(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.IssuedAt%300 != 0 {
+		t.Errorf("iat = %d, want the start of a 300s bucket", claims.IssuedAt)
+	}
+}
+
+func TestDeleteRevocation(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	claims := AssetClaims{StorageKey: "illustrations/d.svg"}
+	tok, _, err := IssuePolicyToken(claims, TokenPolicy{Context: URLContextDetail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rev, err := RevokeTokens(RevocationInput{StorageKey: claims.StorageKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteRevocation(rev.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkToken(tok); err != nil {
+		t.Errorf("token after the revocation was lifted: %v", err)
+	}
+	if err := DeleteRevocation(rev.ID); !errors.Is(err, ErrRevocationNotFound) {
+		t.Errorf("second delete = %v, want ErrRevocationNotFound", err)
+	}
+}
+
+func TestStaleRevocationListReloadsOnce(t *testing.T) {
+	db := useTestDB(t)
+	useTestSettings(t)
+	var loads atomic.Int32
+	err := db.Callback().Query().After("gorm:query").Register("test:count_revocation_loads", func(tx *gorm.DB) {
+		if tx.Statement.Table == "token_revocations" {
+			loads.Add(1)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			revocations.fresh()
+		}()
+	}
+	wg.Wait()
+	if n := loads.Load(); n != 1 {
+		t.Errorf("%d reloads, want 1", n)
+	}
+}
+
+func checkToken(token string) error {
+	_, err := VerifyAssetToken(context.Background(), token, AssetRequest{})
+	return err
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/metrics"
+	"open-illustrations-go/models"
+
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+)
+
+const TusVersion = "1.0.0"
+
+var (
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadExpired        = errors.New("upload expired")
+	ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+	ErrUploadFinished       = errors.New("upload already finished")
+	ErrUploadTooLarge       = errors.New("upload exceeds maximum size")
+	ErrInvalidMetadata      = errors.New("invalid Upload-Metadata")
+)
+
+// TusMaxSize returns the largest accepted upload in bytes
+// (TUS_MAX_SIZE_BYTES, default 50 MiB).
+func TusMaxSize() int64 {
+	return settings.Uploads.TusMaxSizeBytes
+}
+
+// TusUploadExpiry returns how long an unfinished upload is kept
+// (TUS_UPLOAD_EXPIRY_HOURS, 1 to 168, default 24).
+func TusUploadExpiry() time.Duration {
+	return time.Duration(settings.Uploads.TusExpiryHours) * time.Hour
+}
+
+// ParseUploadMetadata decodes a tus Upload-Metadata header ("key b64value,key2 b64value2").
+func ParseUploadMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		switch len(fields) {
+		case 1:
+			meta[fields[0]] = ""
+		case 2:
+			v, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad value for %q", ErrInvalidMetadata, fields[0])
+			}
+			meta[fields[0]] = string(v)
+		default:
+			return nil, fmt.Errorf("%w: bad pair %q", ErrInvalidMetadata, pair)
+		}
+	}
+	return meta, nil
+}
+
+// UploadIngestInput maps tus metadata onto the shared ingest input.
+func UploadIngestInput(meta map[string]string) IngestInput {
+	name := meta["file_name"]
+	if name == "" {
+		name = meta["filename"]
+	}
+	premium, _ := strconv.ParseBool(meta["is_premium"])
+	return IngestInput{
+		Title:       meta["title"],
+		FileName:    name,
+		StyleID:     ParseOptionalID(meta["style_id"]),
+		CategoryID:  ParseOptionalID(meta["category_id"]),
+		PackID:      ParseOptionalID(meta["pack_id"]),
+		IsPremium:   premium,
+		ContentType: meta["filetype"],
+	}
+}
+
+// CreateUpload registers a new resumable upload after checking the metadata up front,
+// so clients don't send megabytes only to be rejected for a missing title.
+func CreateUpload(length int64, rawMetadata string) (*models.Upload, error) {
+	if length > TusMaxSize() {
+		return nil, ErrUploadTooLarge
+	}
+	meta, err := ParseUploadMetadata(rawMetadata)
+	if err != nil {
+		return nil, err
+	}
+	in := UploadIngestInput(meta)
+	if err := ValidateIngestName(in.Title, in.FileName); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	u := models.Upload{
+		ID:        hex.EncodeToString(b),
+		Length:    length,
+		Metadata:  rawMetadata,
+		Status:    models.UploadStatusPending,
+		ExpiresAt: time.Now().Add(TusUploadExpiry()),
+	}
+	if err := config.DB.Create(&u).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func GetUpload(id string) (*models.Upload, error) {
+	var u models.Upload
+	if err := config.DB.First(&u, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	if u.Status == models.UploadStatusPending && time.Now().After(u.ExpiresAt) {
+		return &u, ErrUploadExpired
+	}
+	return &u, nil
+}
+
+// AppendUploadChunk stores the bytes of one PATCH request starting at offset.
+// Whatever was received is kept even if the body is cut off mid-way, which is
+// what makes the upload resumable. When the last byte arrives the upload is
+// assembled and ingested; ingest errors are returned alongside the upload.
+func AppendUploadChunk(id string, offset int64, body io.Reader) (*models.Upload, error) {
+	u, err := GetUpload(id)
+	if err != nil {
+		return u, err
+	}
+	if u.Status != models.UploadStatusPending {
+		return u, ErrUploadFinished
+	}
+	if offset != u.Offset {
+		return u, ErrUploadOffsetMismatch
+	}
+
+	n, readErr := spoolChunk(u, offset, body)
+	if n < 0 {
+		return u, readErr
+	}
+	u.Offset += n
+
+	if u.Offset == u.Length {
+		if err := finishUpload(u); err != nil {
+			return u, err
+		}
+	}
+	return u, readErr
+}
+
+// spoolChunk buffers the request body to a temp file (its size is unknown until the
+// client stops sending), then writes it to MinIO and advances the offset atomically.
+// It returns -1 if nothing could be stored.
+func spoolChunk(u *models.Upload, offset int64, body io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp("", "tus-chunk-*")
+	if err != nil {
+		return -1, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	n, readErr := io.Copy(tmp, io.LimitReader(body, u.Length-offset))
+	if n == 0 {
+		return 0, readErr
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return -1, err
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	key := fmt.Sprintf("uploads/%s/%020d-%s", u.ID, offset, hex.EncodeToString(suffix))
+	if err := UploadObject(key, tmp, n, "application/octet-stream"); err != nil {
+		return -1, fmt.Errorf("%w: %v", ErrStorageUpload, err)
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Upload{}).
+			Where("id = ? AND upload_offset = ? AND status = ?", u.ID, offset, models.UploadStatusPending).
+			Update("upload_offset", offset+n)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUploadOffsetMismatch
+		}
+		return tx.Create(&models.UploadChunk{UploadID: u.ID, Offset: offset, Size: n, StorageKey: key}).Error
+	})
+	if err != nil {
+		// a concurrent PATCH won the race; drop our copy of the bytes
+		removeObjectQuietly(key)
+		return -1, err
+	}
+	return n, readErr
+}
+
+// FinishUpload retries ingestion of an upload whose bytes have all arrived,
+// e.g. after a storage error during the final PATCH.
+func FinishUpload(id string) (*models.Upload, error) {
+	u, err := GetUpload(id)
+	if err != nil {
+		return u, err
+	}
+	if u.Status != models.UploadStatusPending {
+		return u, ErrUploadFinished
+	}
+	if u.Offset != u.Length {
+		return u, ErrUploadOffsetMismatch
+	}
+	return u, finishUpload(u)
+}
+
+func finishUpload(u *models.Upload) error {
+	var chunks []models.UploadChunk
+	if err := config.DB.Where("upload_id = ?", u.ID).Order("chunk_offset").Find(&chunks).Error; err != nil {
+		return err
+	}
+	meta, err := ParseUploadMetadata(u.Metadata)
+	if err != nil {
+		return err
+	}
+
+	r := &chunkReader{chunks: chunks}
+	defer r.Close()
+	in := UploadIngestInput(meta)
+	// the upload is completed in the same transaction as the illustration, so a
+	// retried PATCH can't ingest it twice
+	in.Created = func(tx *gorm.DB, ill *models.Illustration) error {
+		res := tx.Model(&models.Upload{}).Where("id = ? AND status = ?", u.ID, models.UploadStatusPending).
+			Updates(map[string]interface{}{"status": models.UploadStatusCompleted, "illustration_id": ill.ID})
+		if res.Error == nil && res.RowsAffected == 0 {
+			return ErrUploadFinished
+		}
+		return res.Error
+	}
+	ill, err := IngestIllustration(in, r, u.Length)
+	switch {
+	case errors.Is(err, ErrUploadFinished):
+		// another request finished it first
+		return err
+	case errors.Is(err, ErrStorageCheck) || errors.Is(err, ErrStorageUpload) || errors.Is(err, ErrRecordNotStored):
+		// transient: keep the chunks so the client can retry the final PATCH
+		return err
+	case err != nil:
+		u.Status = models.UploadStatusFailed
+		u.Error = truncate(err.Error(), 500)
+		if dbErr := config.DB.Model(u).Updates(map[string]interface{}{"status": u.Status, "error": u.Error}).Error; dbErr != nil {
+			slog.Error("upload status update failed", "error", dbErr)
+		}
+	default:
+		u.Status = models.UploadStatusCompleted
+		u.IllustrationID = &ill.ID
+		metrics.UploadSize.WithLabelValues("tus").Observe(float64(u.Length))
+	}
+	deleteUploadChunks(u.ID)
+	return err
+}
+
+// TerminateUpload implements the tus termination extension.
+func TerminateUpload(id string) error {
+	if _, err := GetUpload(id); err != nil && !errors.Is(err, ErrUploadExpired) {
+		return err
+	}
+	deleteUploadChunks(id)
+	return config.DB.Delete(&models.Upload{}, "id = ?", id).Error
+}
+
+// PurgeExpiredUploads removes unfinished uploads past their expiry together with their chunks.
+func PurgeExpiredUploads() (int, error) {
+	var ids []string
+	if err := config.DB.Model(&models.Upload{}).
+		Where("status = ? AND expires_at < ?", models.UploadStatusPending, time.Now()).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		deleteUploadChunks(id)
+		if err := config.DB.Delete(&models.Upload{}, "id = ?", id).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// StartUploadJanitor purges expired uploads periodically until ctx is done.
+func StartUploadJanitor(ctx context.Context, every time.Duration) {
+	go func() {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if n, err := PurgeExpiredUploads(); err != nil {
+					slog.Error("purge expired uploads failed", "error", err)
+				} else if n > 0 {
+					slog.Info("purged expired uploads", "count", n)
+				}
+			}
+		}
+	}()
+}
+
+func deleteUploadChunks(uploadID string) {
+	var chunks []models.UploadChunk
+	if err := config.DB.Where("upload_id = ?", uploadID).Find(&chunks).Error; err != nil {
+		slog.Error("list upload chunks failed", "error", err)
+		return
+	}
+	for _, ch := range chunks {
+		removeObjectQuietly(ch.StorageKey)
+	}
+	config.DB.Where("upload_id = ?", uploadID).Delete(&models.UploadChunk{})
+}
+
+func removeObjectQuietly(key string) {
+	if err := config.MinioClient.RemoveObject(context.Background(), config.BucketName, key, minio.RemoveObjectOptions{}); err != nil {
+		slog.Error("remove object failed", "key", key, "error", err)
+	}
+}
+
+// chunkReader reads the stored chunks back in order, opening one object at a time.
+type chunkReader struct {
+	chunks []models.UploadChunk
+	cur    *minio.Object
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+			obj, err := config.MinioClient.GetObject(context.Background(), config.BucketName, r.chunks[0].StorageKey, minio.GetObjectOptions{})
+			if err != nil {
+				return 0, err
+			}
+			r.cur = obj
+			r.chunks = r.chunks[1:]
+		}
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.cur.Close()
+			r.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.cur != nil {
+		return r.cur.Close()
+	}
+	return nil
+}
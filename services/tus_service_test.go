@@ -0,0 +1,90 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"unicode/utf8"
+
+	"open-illustrations-go/config"
+	"open-illustrations-go/models"
+)
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"ééé", 3, "é"},
+		{"ééé", 4, "éé"},
+		{"日本", 2, ""},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.in, tt.n)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFinishUploadIngestsOnce(t *testing.T) {
+	useTestDB(t)
+	useTestSettings(t)
+	bucket := &fakeBucket{objects: map[string][]byte{}}
+	useFakeStorage(t, bucket)
+
+	// an upload whose bytes have all arrived in one chunk
+	newUpload := func(t *testing.T) *models.Upload {
+		t.Helper()
+		meta := "title " + base64.StdEncoding.EncodeToString([]byte("Rocket")) + ",filename " + base64.StdEncoding.EncodeToString([]byte("rocket.svg"))
+		u, err := CreateUpload(int64(len(testSVG)), meta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := "uploads/" + u.ID + "/0"
+		bucket.objects[key] = []byte(testSVG)
+		if err := config.DB.Create(&models.UploadChunk{UploadID: u.ID, Size: u.Length, StorageKey: key}).Error; err != nil {
+			t.Fatal(err)
+		}
+		u.Offset = u.Length
+		if err := config.DB.Model(u).Update("upload_offset", u.Length).Error; err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	illustrations := func() int64 {
+		var n int64
+		config.DB.Model(&models.Illustration{}).Count(&n)
+		return n
+	}
+
+	u := newUpload(t)
+	done, err := FinishUpload(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := GetUpload(u.ID)
+	if stored.Status != models.UploadStatusCompleted || stored.IllustrationID == nil {
+		t.Fatalf("upload = %+v, want completed with its illustration", stored)
+	}
+	if _, err := FinishUpload(done.ID); !errors.Is(err, ErrUploadFinished) {
+		t.Errorf("second FinishUpload = %v, want ErrUploadFinished", err)
+	}
+
+	// a request that read the upload before another one completed it
+	stale := newUpload(t)
+	config.DB.Model(&models.Upload{}).Where("id = ?", stale.ID).Update("status", models.UploadStatusCompleted)
+	before, deletes := illustrations(), bucket.deletes.Load()
+	if err := finishUpload(stale); !errors.Is(err, ErrUploadFinished) {
+		t.Errorf("finishUpload(stale) = %v, want ErrUploadFinished", err)
+	}
+	if got := illustrations(); got != before {
+		t.Errorf("%d illustrations after the stale finish, want %d", got, before)
+	}
+	if bucket.deletes.Load() == deletes {
+		t.Error("the stored file of the rolled back illustration was not removed")
+	}
+}
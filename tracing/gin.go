@@ -0,0 +1,22 @@
+package tracing
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// untraced are the probe and scrape endpoints; a span per poll is only noise.
+var untraced = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Middleware starts a server span per request, continuing the caller's trace
+// when a traceparent header is present. Register it first so the spans from
+// GORM and MinIO nest under it.
+func Middleware(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return !untraced[c.FullPath()]
+	}))
+}
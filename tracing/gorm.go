@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// GORM returns a plugin that wraps every statement in a client span. Spans are
+// children of the span in the statement's context, so services must pass the
+// request context with db.WithContext(ctx). Preloads run inside their parent
+// query and show up as its children.
+func GORM() gorm.Plugin {
+	return gormPlugin{}
+}
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string { return "otel" }
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("otel:before_create", startSpan("INSERT")),
+		cb.Create().After("*").Register("otel:after_create", endSpan),
+		cb.Query().Before("*").Register("otel:before_query", startSpan("SELECT")),
+		cb.Query().After("*").Register("otel:after_query", endSpan),
+		cb.Update().Before("*").Register("otel:before_update", startSpan("UPDATE")),
+		cb.Update().After("*").Register("otel:after_update", endSpan),
+		cb.Delete().Before("*").Register("otel:before_delete", startSpan("DELETE")),
+		cb.Delete().After("*").Register("otel:after_delete", endSpan),
+		cb.Row().Before("*").Register("otel:before_row", startSpan("SELECT")),
+		cb.Row().After("*").Register("otel:after_row", endSpan),
+		cb.Raw().Before("*").Register("otel:before_raw", startSpan("RAW")),
+		cb.Raw().After("*").Register("otel:after_raw", endSpan),
+	)
+}
+
+// parentKey keeps the caller's context so endSpan can put it back; a chained
+// *gorm.DB may run more than one statement.
+const parentKey = "otel:parent"
+
+func startSpan(verb string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		name := verb
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		ctx, _ := Tracer().Start(parent, name, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system.name", db.Dialector.Name())))
+		db.InstanceSet(parentKey, parent)
+		db.Statement.Context = ctx
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	span := trace.SpanFromContext(db.Statement.Context)
+	if parent, ok := db.InstanceGet(parentKey); ok {
+		db.Statement.Context = parent.(context.Context)
+	}
+	if !span.IsRecording() {
+		span.End()
+		return
+	}
+	span.SetAttributes(
+		attribute.String("db.collection.name", db.Statement.Table),
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.response.returned_rows", db.Statement.RowsAffected),
+	)
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
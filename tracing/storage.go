@@ -0,0 +1,22 @@
+package tracing
+
+import (
+	"net/http"
+
+	"open-illustrations-go/metrics"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// StorageTransport adds a client span to every MinIO request, named after
+// the S3 operation ("storage get", "storage stat", ...). Trace context is not
+// sent to MinIO.
+func StorageTransport(next http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(next,
+		otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator()),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "storage " + metrics.StorageOperation(r)
+		}),
+	)
+}
@@ -0,0 +1,194 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useInMemory installs a tracer provider that records every span
+// synchronously, and puts the previous provider back when the test ends.
+func useInMemory(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+// spanNamed returns the only recorded span called name.
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	var found []tracetest.SpanStub
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name)
+		if s.Name == name {
+			found = append(found, s)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("want one span %q, got %d among %q", name, len(found), names)
+	}
+	return found[0]
+}
+
+func TestMiddlewareSpans(t *testing.T) {
+	spans := useInMemory(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware("test"))
+	r.GET("/items/:id", func(c *gin.Context) {
+		_, span := Tracer().Start(c.Request.Context(), "work")
+		span.End()
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/items/12", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	got := spans.GetSpans()
+	if len(got) != 2 {
+		t.Fatalf("got %d spans, want the request and its child (health checks are not traced)", len(got))
+	}
+	var server tracetest.SpanStub
+	for _, s := range got {
+		if s.SpanKind == trace.SpanKindServer {
+			server = s
+		}
+	}
+	if !strings.Contains(server.Name, "/items/:id") {
+		t.Errorf("server span %q is not named after the route", server.Name)
+	}
+	if server.SpanContext.TraceID().String() != traceID {
+		t.Errorf("server span trace %s, want the incoming traceparent %s", server.SpanContext.TraceID(), traceID)
+	}
+	work := spanNamed(t, got, "work")
+	if work.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("handler span is not a child of the server span")
+	}
+}
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGORMSpans(t *testing.T) {
+	spans := useInMemory(t)
+	db, err := gorm.Open(sqlite.Open("file:tracing?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GORM()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, root := Tracer().Start(context.Background(), "request")
+	db = db.WithContext(ctx)
+	if err := db.Create(&widget{Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var w widget
+	if err := db.First(&w).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Table("missing").Find(&[]widget{}).Error; err == nil {
+		t.Fatal("query on a missing table succeeded")
+	}
+	root.End()
+
+	got := spans.GetSpans()
+	for _, name := range []string{"INSERT widgets", "SELECT widgets", "SELECT missing"} {
+		s := spanNamed(t, got, name)
+		if s.Parent.SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the request span", name)
+		}
+		if s.SpanKind != trace.SpanKindClient {
+			t.Errorf("%s kind = %s, want client", name, s.SpanKind)
+		}
+	}
+	if s := spanNamed(t, got, "SELECT widgets"); !hasAttr(s, "db.query.text", "SELECT * FROM `widgets`") {
+		t.Errorf("SELECT span attributes = %v", s.Attributes)
+	}
+	if s := spanNamed(t, got, "SELECT missing"); s.Status.Code != codes.Error {
+		t.Errorf("failed query status = %v, want error", s.Status)
+	}
+}
+
+func hasAttr(s tracetest.SpanStub, key, prefix string) bool {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key && strings.HasPrefix(kv.Value.Emit(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStorageSpans(t *testing.T) {
+	spans := useInMemory(t)
+	var sawTraceparent bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sawTraceparent = sawTraceparent || r.Header.Get("traceparent") != ""
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Content-Length", "3")
+		w.Header().Set("ETag", `"abc"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		if r.Method == http.MethodGet {
+			w.Write([]byte("<s>"))
+		}
+	}))
+	defer srv.Close()
+
+	cli, err := minio.New(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Creds:     credentials.NewStaticV4("access", "secret-key", ""),
+		Region:    "us-east-1",
+		Transport: StorageTransport(http.DefaultTransport),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, root := Tracer().Start(context.Background(), "request")
+	if _, err := cli.StatObject(ctx, "bucket", "a.svg", minio.StatObjectOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	root.End()
+
+	s := spanNamed(t, spans.GetSpans(), "storage stat")
+	if s.Parent.SpanID() != root.SpanContext().SpanID() {
+		t.Error("storage span is not a child of the request span")
+	}
+	if s.SpanKind != trace.SpanKindClient {
+		t.Errorf("storage span kind = %s, want client", s.SpanKind)
+	}
+	if sawTraceparent {
+		t.Error("trace context was sent to storage")
+	}
+}